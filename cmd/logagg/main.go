@@ -1,16 +1,142 @@
 package main
 
+/*
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * flag パッケージはコマンドライン引数の解析を提供します。
+ * log パッケージはログ出力を提供します。
+ * os パッケージはファイル操作を提供します。
+ * os/signal パッケージはシグナルの受信を提供します。
+ * strings パッケージは文字列操作を提供します。
+ * syscall パッケージはシグナルの定義を提供します。
+ * time/tzdata パッケージはタイムゾーンのデータベースを埋め込みます (データベースのない Windows などでもタイムゾーン名を使用するため)。
+ */
 import (
+	"encoding/json"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	_ "time/tzdata"
 
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
+	"github.com/Yamituki/go-review-logagg/internal/parser"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/internal/processor"
 	"github.com/Yamituki/go-review-logagg/internal/server"
 )
 
+// maxWorkers はファイルを並行して処理するワーカー数の上限です。
+const maxWorkers = 4
+
+// stringList は繰り返し指定できるコマンドライン引数です。
+type stringList []string
+
+// String は指定された値をカンマ区切りで返します。
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+// Set は値を追加します。
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
 func main() {
-	srv := server.NewServer(":8080")
-	srv.SetupRoutes()
-	log.Println("サーバーを起動しています: http://localhost:8080")
-	if err := srv.Start(); err != nil {
-		log.Fatalf("サーバーの起動に失敗: %v", err)
+	// コマンドライン引数の定義
+	addr := flag.String("addr", ":8080", "サーバーの待ち受けアドレス")
+	deadLetterPath := flag.String("dead-letter", "", "ファイルの集計で解析できなかった行を JSON Lines 形式で追記するファイル (指定しない場合は解析のエラーとして扱う。サーバーでは dead_letter と /monitors/{id}/deadletters を使用する)")
+	stateDir := flag.String("state-dir", "", "API で作成したモニターの定義と読み込み位置を保存するディレクトリ (指定しない場合は再起動で失われる)")
+	timezone := flag.String("timezone", "", "タイムゾーンを含まないタイムスタンプを解釈するタイムゾーン (例: Asia/Tokyo, +09:00、既定は UTC)")
+	var layouts stringList
+	flag.Var(&layouts, "layout", "既定の書式で解析できない場合に試すタイムスタンプの書式 (Go の時刻の書式、繰り返し指定可)")
+	var filter pipeline.FilterOptions
+	var sources, excludeSources, where stringList
+	flag.StringVar(&filter.Level, "level", "", "このレベル以上のログに絞り込む (INFO, WARN, ERROR)")
+	flag.StringVar(&filter.Since, "since", "", "この時刻以降のログに絞り込む (RFC3339 または \"YYYY-MM-DD HH:MM:SS\")")
+	flag.StringVar(&filter.Until, "until", "", "この時刻より前のログに絞り込む")
+	flag.StringVar(&filter.Last, "last", "", "現在からさかのぼってこの時間以内のログに絞り込む (例: 2h)")
+	flag.Var(&sources, "source", "含める発生源 (繰り返し指定可)")
+	flag.Var(&excludeSources, "exclude-source", "除外する発生源 (繰り返し指定可)")
+	flag.StringVar(&filter.Contains, "contains", "", "メッセージに含まれる文字列")
+	flag.StringVar(&filter.Match, "match", "", "メッセージに一致する正規表現")
+	flag.Var(&where, "where", "フィールドの条件 (例: status>=500、繰り返し指定可)")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		out.Write([]byte("使い方:\n  logagg [-addr :8080]          サーバーを起動\n  logagg [オプション] ファイル...  ファイルを集計して統計情報を JSON で出力\n\nオプション:\n"))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// ファイルが指定されていない場合はサーバーを起動
+	if flag.NArg() == 0 {
+		if *deadLetterPath != "" {
+			log.Fatalf("-dead-letter はファイルを指定した集計でのみ使用できます (サーバーではリクエストの dead_letter と /monitors/{id}/deadletters を使用してください)")
+		}
+		srv := server.NewServer(*addr)
+		srv.SetupRoutes()
+		if *stateDir != "" {
+			if err := srv.SetStateDir(*stateDir); err != nil {
+				log.Fatalf("モニターの状態を読み込めません: %v", err)
+			}
+		}
+
+		// 終了のシグナルを受信したらモニターの読み込み位置を保存してから終了
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			srv.Close()
+			os.Exit(0)
+		}()
+
+		log.Printf("サーバーを起動しています: %s", *addr)
+		if err := srv.Start(); err != nil {
+			log.Fatalf("サーバーの起動に失敗: %v", err)
+		}
+		return
+	}
+
+	// 絞り込みの条件の解析
+	filter.Sources = sources
+	filter.ExcludeSources = excludeSources
+	filter.Where = where
+	config, err := filter.Config()
+	if err != nil {
+		log.Fatalf("絞り込みの条件が不正です: %v", err)
+	}
+
+	// ファイルの集計
+	cp := processor.NewConcurrentProcessor(min(flag.NArg(), maxWorkers))
+	if err := cp.AddFilter(config); err != nil {
+		log.Fatalf("絞り込みの条件が不正です: %v", err)
+	}
+	parserConfig := parser.StandardParserConfig{Layouts: layouts}
+	if *timezone != "" {
+		if parserConfig.Location, err = parser.ParseLocation(*timezone); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	cp.SetParserConfig(parserConfig)
+	if *deadLetterPath != "" {
+		sink, err := deadletter.NewFileSink(*deadLetterPath)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer sink.Close()
+		cp.SetDeadLetter(sink)
+	}
+	stats, err := cp.ProcessFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("ログファイルの解析に失敗しました: %v", err)
+	}
+
+	// 統計情報の出力
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(stats); err != nil {
+		log.Fatalf("統計情報の出力に失敗しました: %v", err)
 	}
 }
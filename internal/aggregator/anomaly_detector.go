@@ -0,0 +1,389 @@
+package aggregator
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * math パッケージは基本的な数学関数を提供します。
+ * sort パッケージはスライスのソートを提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// maxGapBuckets は空の時間バケットとして埋める最大数です。これを超える空白は基準値を引き継ぎます。
+const maxGapBuckets = 1440
+
+// AnomalyConfig は異常検知の設定を表す構造体です。
+type AnomalyConfig struct {
+	// 時間バケットの長さ
+	BucketSize time.Duration
+	// EWMA の平滑化係数 (0.0〜1.0、大きいほど直近を重視)
+	Alpha float64
+	// 異常とみなす z スコアの閾値
+	Threshold float64
+	// 異常とみなす最小件数 (急増は観測値、急減は期待値に適用)
+	MinCount int
+	// 判定を始めるまでに必要な時間バケット数
+	Warmup int
+	// 監視するレベル一覧
+	Levels []string
+	// テンプレートごとの頻度も監視するかどうか
+	TrackTemplates bool
+	// 保持する異常の最大数 (古いものから破棄)
+	MaxAnomalies int
+}
+
+// DefaultAnomalyConfig は異常検知の既定の設定を返します。
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		BucketSize:     time.Minute,
+		Alpha:          0.3,
+		Threshold:      3.0,
+		MinCount:       5,
+		Warmup:         5,
+		Levels:         []string{"ERROR", "WARN"},
+		TrackTemplates: true,
+		MaxAnomalies:   1000,
+	}
+}
+
+// anomalySeries は異常検知の対象となる系列です。
+type anomalySeries struct {
+	// レベル (レベルの系列の場合)
+	level string
+	// テンプレートID (テンプレートの系列の場合)
+	templateID int
+}
+
+// name は系列の表示名を返します。
+func (as anomalySeries) name() string {
+	if as.templateID != 0 {
+		return fmt.Sprintf("template:%d", as.templateID)
+	}
+	return "level:" + as.level
+}
+
+// ewma は1つの系列の指数加重移動平均と分散です。
+type ewma struct {
+	// 平均
+	mean float64
+	// 分散
+	variance float64
+	// 観測した時間バケット数
+	observed int
+}
+
+// AnomalyDetector は時間バケットごとのレベル別件数とテンプレート頻度の急増・急減を EWMA と z スコアで検知する集約器です。
+// エントリはタイムスタンプ順に追加されることを想定しており、確定済みの時間バケットより古いエントリは判定に含めません。
+type AnomalyDetector struct {
+	// 設定
+	config AnomalyConfig
+	// 監視するレベルの集合
+	levels map[string]bool
+	// テンプレートの分類器 (TrackTemplates が無効な場合は nil)
+	miner *TemplateMiner
+	// 全体の統計情報
+	base *LogAggregator
+	// 集計中の時間バケットの開始時刻
+	current time.Time
+	// 集計中の時間バケットの系列別件数
+	counts map[anomalySeries]int
+	// 系列ごとの基準値
+	baselines map[anomalySeries]*ewma
+	// 検知した異常一覧
+	anomalies []models.Anomaly
+	// 確定済みの時間バケットより古いため判定に含めなかったエントリ数
+	late int
+	// 追加したエントリの最新のタイムスタンプ
+	latest time.Time
+	// 最新のタイムスタンプのエントリを追加した実際の時刻
+	observedAt time.Time
+	// 実際の時刻を返す関数
+	now func() time.Time
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// NewAnomalyDetector は指定した設定で AnomalyDetector の新しいインスタンスを作成します。
+func NewAnomalyDetector(config AnomalyConfig) *AnomalyDetector {
+	// 不正な設定値は既定値で補う
+	defaults := DefaultAnomalyConfig()
+	if config.BucketSize <= 0 {
+		config.BucketSize = defaults.BucketSize
+	}
+	if config.Alpha <= 0 || config.Alpha > 1 {
+		config.Alpha = defaults.Alpha
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = defaults.Threshold
+	}
+	if config.Warmup < 1 {
+		config.Warmup = 1
+	}
+	if config.Levels == nil {
+		config.Levels = defaults.Levels
+	}
+	if config.MaxAnomalies <= 0 {
+		config.MaxAnomalies = defaults.MaxAnomalies
+	}
+
+	ad := &AnomalyDetector{
+		config: config,
+		levels: make(map[string]bool),
+		base:   NewLogAggregator(),
+		now:    time.Now,
+	}
+	for _, level := range config.Levels {
+		ad.levels[level] = true
+	}
+	ad.clear()
+
+	return ad
+}
+
+// Add は1つのログエントリを時間バケットに追加します。新しい時間バケットに進んだ場合は前の時間バケットを判定します。
+func (ad *AnomalyDetector) Add(entry models.LogEntry) error {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	// 全体の統計情報の更新
+	ad.base.Add(entry)
+
+	// テンプレートの分類
+	templateID := 0
+	if ad.miner != nil {
+		templateID = ad.miner.Classify(entry)
+	}
+
+	// 最新のタイムスタンプと実際の時刻の記録 (Tick で使用)
+	if entry.Timestamp.After(ad.latest) {
+		ad.latest = entry.Timestamp
+		ad.observedAt = ad.now()
+	}
+
+	// 時間バケットの決定
+	start := entry.Timestamp.Truncate(ad.config.BucketSize)
+	switch {
+	case ad.current.IsZero():
+		ad.current = start
+	case start.Before(ad.current):
+		// 確定済みの時間バケットには加えない
+		ad.late += entry.Count()
+		return nil
+	case start.After(ad.current):
+		ad.advance(start)
+	}
+
+	// 系列別件数の更新
+	if ad.levels[entry.Level] {
+		ad.counts[anomalySeries{level: entry.Level}] += entry.Count()
+	}
+	if templateID != 0 {
+		ad.counts[anomalySeries{templateID: templateID}] += entry.Count()
+	}
+
+	return nil
+}
+
+// GetStats は追加されたすべてのログの統計情報を取得します。
+func (ad *AnomalyDetector) GetStats() models.Stats {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	return ad.base.GetStats()
+}
+
+// Reset は基準値、検知した異常、統計情報をリセットします。
+func (ad *AnomalyDetector) Reset() {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	ad.clear()
+}
+
+// Flush は集計中の時間バケットを確定して判定します。ファイルを一括で解析した後に呼び出します。
+func (ad *AnomalyDetector) Flush() {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	if ad.current.IsZero() {
+		return
+	}
+
+	ad.evaluate()
+	ad.current = ad.current.Add(ad.config.BucketSize)
+	ad.counts = make(map[anomalySeries]int)
+}
+
+// Tick は最新のエントリを追加してから実際の時計で経過した時間だけ集計中の時間バケットを進め、終わった時間バケットを判定します。
+// リアルタイム監視で監視間隔ごとに呼び出すと、エントリが届かなくなった場合も件数の急減を検知できます。
+// 経過時間で進めるため、ログの時計がずれている場合や過去のログを読み込んでいる途中でも、届いたエントリを遅延として扱いません。
+// エントリを1件も追加していない場合は何もしません。
+func (ad *AnomalyDetector) Tick(now time.Time) {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	if ad.latest.IsZero() {
+		return
+	}
+	if start := ad.latest.Add(now.Sub(ad.observedAt)).Truncate(ad.config.BucketSize); start.After(ad.current) {
+		ad.advance(start)
+	}
+}
+
+// Anomalies は検知した異常を古い順に返します。
+func (ad *AnomalyDetector) Anomalies() []models.Anomaly {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	return append([]models.Anomaly(nil), ad.anomalies...)
+}
+
+// Since は指定した時刻以降の時間バケットで検知した異常を返します。
+func (ad *AnomalyDetector) Since(t time.Time) []models.Anomaly {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	var anomalies []models.Anomaly
+	for _, anomaly := range ad.anomalies {
+		if !anomaly.BucketStart.Before(t) {
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	return anomalies
+}
+
+// LateEntries は確定済みの時間バケットより古いため判定に含めなかったエントリ数を返します。
+func (ad *AnomalyDetector) LateEntries() int {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	return ad.late
+}
+
+// advance は集計中の時間バケットを確定し、start の時間バケットまで進めます。間の空の時間バケットは0件として判定します。
+func (ad *AnomalyDetector) advance(start time.Time) {
+	ad.evaluate()
+
+	gaps := 0
+	for next := ad.current.Add(ad.config.BucketSize); next.Before(start); next = next.Add(ad.config.BucketSize) {
+		// 長い空白は基準値の更新を打ち切る
+		if gaps++; gaps > maxGapBuckets {
+			break
+		}
+		ad.current = next
+		ad.counts = make(map[anomalySeries]int)
+		ad.evaluate()
+	}
+
+	ad.current = start
+	ad.counts = make(map[anomalySeries]int)
+}
+
+// evaluate は集計中の時間バケットを系列ごとに基準値と比較し、基準値を更新します。
+func (ad *AnomalyDetector) evaluate() {
+	// 監視対象のすべての系列 (このバケットで0件の系列を含む)
+	targets := make(map[anomalySeries]bool, len(ad.baselines)+len(ad.counts))
+	for series := range ad.baselines {
+		targets[series] = true
+	}
+	for series := range ad.counts {
+		targets[series] = true
+	}
+	for level := range ad.levels {
+		targets[anomalySeries{level: level}] = true
+	}
+
+	// 結果の順序を安定させるため系列名の順に判定する
+	ordered := make([]anomalySeries, 0, len(targets))
+	for series := range targets {
+		ordered = append(ordered, series)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].name() < ordered[j].name()
+	})
+
+	for _, series := range ordered {
+		count := ad.counts[series]
+		baseline, ok := ad.baselines[series]
+		if !ok {
+			baseline = &ewma{}
+			ad.baselines[series] = baseline
+		}
+
+		// ウォームアップ後に z スコアで判定
+		if baseline.observed >= ad.config.Warmup {
+			// 件数の揺らぎとして最低でもポアソン分布相当の標準偏差を見込む
+			stddev := math.Max(math.Sqrt(baseline.variance), math.Sqrt(math.Max(baseline.mean, 1)))
+			z := (float64(count) - baseline.mean) / stddev
+
+			if z >= ad.config.Threshold && count >= ad.config.MinCount {
+				ad.record(series, count, baseline.mean, z, "spike")
+			} else if z <= -ad.config.Threshold && baseline.mean >= float64(ad.config.MinCount) {
+				ad.record(series, count, baseline.mean, z, "drop")
+			}
+		}
+
+		// 基準値の更新
+		if baseline.observed == 0 {
+			baseline.mean = float64(count)
+		} else {
+			diff := float64(count) - baseline.mean
+			baseline.mean += ad.config.Alpha * diff
+			baseline.variance = (1 - ad.config.Alpha) * (baseline.variance + ad.config.Alpha*diff*diff)
+		}
+		baseline.observed++
+	}
+}
+
+// record は検知した異常を記録します。
+func (ad *AnomalyDetector) record(series anomalySeries, count int, expected, z float64, direction string) {
+	anomaly := models.Anomaly{
+		BucketStart: ad.current,
+		BucketSize:  ad.config.BucketSize,
+		Series:      series.name(),
+		Level:       series.level,
+		TemplateID:  series.templateID,
+		Count:       count,
+		Expected:    expected,
+		ZScore:      z,
+		Direction:   direction,
+	}
+
+	// テンプレートの系列の場合はテンプレート文字列を付加
+	if series.templateID != 0 && ad.miner != nil {
+		if template, ok := ad.miner.Template(series.templateID); ok {
+			anomaly.Template = template.Template
+		}
+	}
+
+	ad.anomalies = append(ad.anomalies, anomaly)
+
+	// 上限を超えた場合は古いものから破棄
+	if len(ad.anomalies) > ad.config.MaxAnomalies {
+		ad.anomalies = ad.anomalies[len(ad.anomalies)-ad.config.MaxAnomalies:]
+	}
+}
+
+// clear はすべての状態を初期化します。
+func (ad *AnomalyDetector) clear() {
+	if ad.config.TrackTemplates {
+		ad.miner = NewTemplateMiner()
+	}
+	ad.base.Reset()
+	ad.current = time.Time{}
+	ad.counts = make(map[anomalySeries]int)
+	ad.baselines = make(map[anomalySeries]*ewma)
+	ad.anomalies = nil
+	ad.late = 0
+	ad.latest = time.Time{}
+	ad.observedAt = time.Time{}
+}
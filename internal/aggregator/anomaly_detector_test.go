@@ -0,0 +1,152 @@
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestAnomalyDetector_ErrorSpike は ERROR の急増が異常として検知されることをテストします。
+func TestAnomalyDetector_ErrorSpike(t *testing.T) {
+	// AnomalyDetector のインスタンスを作成
+	detector := NewAnomalyDetector(DefaultAnomalyConfig())
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 10分間は毎分 ERROR 2件と INFO 10件
+	for minute := 0; minute < 10; minute++ {
+		for i := 0; i < 10; i++ {
+			detector.Add(models.LogEntry{Timestamp: base.Add(time.Duration(minute)*time.Minute + time.Duration(i)*time.Second), Level: "INFO", Message: fmt.Sprintf("request %d served", i)})
+		}
+		for i := 0; i < 2; i++ {
+			detector.Add(models.LogEntry{Timestamp: base.Add(time.Duration(minute)*time.Minute + 30*time.Second), Level: "ERROR", Message: fmt.Sprintf("timeout calling service %d", i)})
+		}
+	}
+
+	// 10分目に ERROR が30件に急増
+	spike := base.Add(10 * time.Minute)
+	for i := 0; i < 30; i++ {
+		detector.Add(models.LogEntry{Timestamp: spike.Add(time.Duration(i) * time.Second), Level: "ERROR", Message: fmt.Sprintf("timeout calling service %d", i)})
+	}
+	detector.Flush()
+
+	anomalies := detector.Anomalies()
+
+	t.Logf("検知した異常: %+v", anomalies)
+
+	// ERROR の急増とテンプレートの急増を検知
+	var levelAnomaly, templateAnomaly *models.Anomaly
+	for i := range anomalies {
+		switch {
+		case anomalies[i].Level == "ERROR":
+			levelAnomaly = &anomalies[i]
+		case anomalies[i].TemplateID != 0:
+			templateAnomaly = &anomalies[i]
+		}
+	}
+
+	if levelAnomaly == nil {
+		t.Fatalf("ERROR の急増が検知されませんでした")
+	}
+	if !levelAnomaly.BucketStart.Equal(spike) || levelAnomaly.Count != 30 || levelAnomaly.Direction != "spike" {
+		t.Errorf("ERROR の異常が期待値と異なります: %+v", levelAnomaly)
+	}
+
+	if templateAnomaly == nil {
+		t.Fatalf("テンプレートの急増が検知されませんでした")
+	}
+	if templateAnomaly.Template != "timeout calling service <*>" {
+		t.Errorf("テンプレートの異常が期待値と異なります: %+v", templateAnomaly)
+	}
+
+	// 急増の前の時間バケットでは異常なし
+	if len(detector.Since(spike)) != len(anomalies) {
+		t.Errorf("急増の前に異常が検知されています: %+v", anomalies)
+	}
+
+	// 統計情報の検証
+	if detector.GetStats().ErrorCount != 50 {
+		t.Errorf("期待されるERRORログ数は 50 ですが、実際の値は %d です", detector.GetStats().ErrorCount)
+	}
+}
+
+// TestAnomalyDetector_Drop は件数の急減と空の時間バケットが検知されることをテストします。
+func TestAnomalyDetector_Drop(t *testing.T) {
+	// テンプレートを監視しない設定
+	config := DefaultAnomalyConfig()
+	config.TrackTemplates = false
+	config.Levels = []string{"INFO"}
+	detector := NewAnomalyDetector(config)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 10分間は毎分 INFO 50件
+	for minute := 0; minute < 10; minute++ {
+		for i := 0; i < 50; i++ {
+			detector.Add(models.LogEntry{Timestamp: base.Add(time.Duration(minute)*time.Minute + time.Duration(i)*time.Second), Level: "INFO", Message: "heartbeat"})
+		}
+	}
+
+	// 2分間ログが途絶えた後に再開
+	detector.Add(models.LogEntry{Timestamp: base.Add(12 * time.Minute), Level: "INFO", Message: "heartbeat"})
+
+	// 遅れて届いたエントリは判定に含めない
+	detector.Add(models.LogEntry{Timestamp: base, Level: "INFO", Message: "heartbeat"})
+
+	anomalies := detector.Anomalies()
+
+	t.Logf("検知した異常: %+v", anomalies)
+
+	if len(anomalies) == 0 || anomalies[0].Direction != "drop" || !anomalies[0].BucketStart.Equal(base.Add(10*time.Minute)) {
+		t.Fatalf("10分目の急減が検知されませんでした: %+v", anomalies)
+	}
+	if detector.LateEntries() != 1 {
+		t.Errorf("期待される遅延エントリ数は 1 ですが、実際の値は %d です", detector.LateEntries())
+	}
+
+	// リセット後の検証
+	detector.Reset()
+	if len(detector.Anomalies()) != 0 {
+		t.Errorf("リセット後の異常数は 0 であるべきですが、実際の値は %d です", len(detector.Anomalies()))
+	}
+}
+
+// TestAnomalyDetector_Tick はエントリが届かなくなった場合に実際の時計で時間バケットを進めて急減を検知することをテストします。
+func TestAnomalyDetector_Tick(t *testing.T) {
+	config := DefaultAnomalyConfig()
+	config.Levels = []string{"INFO"}
+	detector := NewAnomalyDetector(config)
+
+	// ログの時計は実際の時計より1時間遅れている
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	wall := base.Add(time.Hour)
+	detector.now = func() time.Time { return wall }
+
+	// 10分間は毎分 INFO 50件
+	for minute := 0; minute < 10; minute++ {
+		for i := 0; i < 50; i++ {
+			detector.Add(models.LogEntry{Timestamp: base.Add(time.Duration(minute)*time.Minute + time.Duration(i)*time.Second), Level: "INFO", Message: "heartbeat"})
+		}
+	}
+
+	// 最後のバケットが終わるまでは判定しない
+	detector.Tick(wall.Add(10 * time.Second))
+	if len(detector.Anomalies()) != 0 {
+		t.Fatalf("異常は検知されないはずです: %+v", detector.Anomalies())
+	}
+
+	// 2分経過すると0件のバケットを急減として検知する
+	detector.Tick(wall.Add(2 * time.Minute))
+	anomalies := detector.Anomalies()
+	if len(anomalies) == 0 || anomalies[0].Direction != "drop" || !anomalies[0].BucketStart.Equal(base.Add(10*time.Minute)) {
+		t.Fatalf("10分目の急減が検知されませんでした: %+v", anomalies)
+	}
+
+	// 時計のずれによって届いたエントリを遅延として扱わない
+	detector.Add(models.LogEntry{Timestamp: base.Add(12*time.Minute + time.Second), Level: "INFO", Message: "heartbeat"})
+	if detector.LateEntries() != 0 {
+		t.Errorf("期待される遅延エントリ数は 0 ですが、実際の値は %d です", detector.LateEntries())
+	}
+}
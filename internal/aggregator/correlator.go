@@ -0,0 +1,250 @@
+package aggregator
+
+/*
+ * container/list パッケージは双方向連結リストを提供します。
+ * sort パッケージはスライスのソートを提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ */
+import (
+	"container/list"
+	"sort"
+	"sync"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// CorrelatorConfig は相関集約器の設定を表す構造体です。
+type CorrelatorConfig struct {
+	// リクエストを識別するフィールド名 (例: "request_id", "trace_id")
+	Field string
+	// 保持するリクエストの最大数 (超えた場合は最も長く更新のないものから破棄)
+	MaxTraces int
+	// リクエストごとに保持するログの最大数
+	MaxEntries int
+}
+
+// DefaultCorrelatorConfig は相関集約器の既定の設定を返します。
+func DefaultCorrelatorConfig() CorrelatorConfig {
+	return CorrelatorConfig{
+		Field:      "request_id",
+		MaxTraces:  10000,
+		MaxEntries: 1000,
+	}
+}
+
+// correlatedTrace は集計中の1つのリクエストです。
+type correlatedTrace struct {
+	// 集計結果 (Entries は保持しているログ)
+	trace models.Trace
+	// 発生源の集合
+	sources map[string]bool
+	// 更新順のリスト上の要素
+	element *list.Element
+}
+
+// Correlator はリクエストIDやトレースIDのフィールドでエントリをまとめ、リクエストごとの経過時間、件数、最も重大なレベルを集計する集約器です。
+// 複数のファイルのエントリをまとめるため、スレッドセーフに実装されています。
+type Correlator struct {
+	// 設定
+	config CorrelatorConfig
+	// リクエストごとの集計 (キーはID)
+	traces map[string]*correlatedTrace
+	// 更新順のIDのリスト (先頭が最も古い)
+	recency *list.List
+	// IDのフィールドを持たないログ数
+	uncorrelated int
+	// 上限を超えて破棄したリクエスト数
+	evicted int
+	// 全体の統計情報
+	base *LogAggregator
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// NewCorrelator は指定した設定で Correlator の新しいインスタンスを作成します。
+func NewCorrelator(config CorrelatorConfig) *Correlator {
+	// 不正な設定値は既定値で補う
+	defaults := DefaultCorrelatorConfig()
+	if config.Field == "" {
+		config.Field = defaults.Field
+	}
+	if config.MaxTraces <= 0 {
+		config.MaxTraces = defaults.MaxTraces
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaults.MaxEntries
+	}
+
+	return &Correlator{
+		config:  config,
+		traces:  make(map[string]*correlatedTrace),
+		recency: list.New(),
+		base:    NewLogAggregator(),
+	}
+}
+
+// Add は1つのログエントリを ID ごとのリクエストに追加します。
+func (c *Correlator) Add(entry models.LogEntry) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// 全体の統計情報の更新
+	c.base.Add(entry)
+
+	id, ok := entry.Field(c.config.Field)
+	if !ok || id == "" {
+		c.uncorrelated += entry.Count()
+		return nil
+	}
+
+	ct, ok := c.traces[id]
+	if !ok {
+		ct = &correlatedTrace{
+			trace: models.Trace{
+				ID:          id,
+				Start:       entry.Timestamp,
+				End:         entry.LastTimestamp(),
+				LevelCounts: make(map[string]int),
+			},
+			sources: make(map[string]bool),
+		}
+		ct.element = c.recency.PushBack(id)
+		c.traces[id] = ct
+		c.evict()
+	} else {
+		c.recency.MoveToBack(ct.element)
+	}
+
+	// 集計の更新
+	trace := &ct.trace
+	trace.Count += entry.Count()
+	trace.LevelCounts[entry.Level] += entry.Count()
+	if models.LevelSeverity(entry.Level) > models.LevelSeverity(trace.WorstLevel) {
+		trace.WorstLevel = entry.Level
+	}
+	if entry.Timestamp.Before(trace.Start) {
+		trace.Start = entry.Timestamp
+	}
+	if entry.LastTimestamp().After(trace.End) {
+		trace.End = entry.LastTimestamp()
+	}
+	trace.Duration = trace.End.Sub(trace.Start)
+	if entry.Source != "" {
+		ct.sources[entry.Source] = true
+	}
+
+	// ログの保持
+	if len(trace.Entries) < c.config.MaxEntries {
+		trace.Entries = append(trace.Entries, entry)
+	} else {
+		trace.Dropped += entry.Count()
+	}
+
+	return nil
+}
+
+// GetStats は追加されたすべてのログの統計情報を取得します。
+func (c *Correlator) GetStats() models.Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.base.GetStats()
+}
+
+// Reset はすべてのリクエストと統計情報をリセットします。
+func (c *Correlator) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.traces = make(map[string]*correlatedTrace)
+	c.recency.Init()
+	c.uncorrelated = 0
+	c.evicted = 0
+	c.base.Reset()
+}
+
+// Traces はログを除いたリクエストごとの集計を開始時刻順に返します。
+func (c *Correlator) Traces() []models.Trace {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	traces := make([]models.Trace, 0, len(c.traces))
+	for _, ct := range c.traces {
+		trace := ct.snapshot()
+		trace.Entries = nil
+		traces = append(traces, trace)
+	}
+
+	// 開始時刻順、同時刻の場合は ID 順に並べる
+	sort.Slice(traces, func(i, j int) bool {
+		if !traces[i].Start.Equal(traces[j].Start) {
+			return traces[i].Start.Before(traces[j].Start)
+		}
+		return traces[i].ID < traces[j].ID
+	})
+
+	return traces
+}
+
+// Trace は指定した ID のリクエストの集計とタイムスタンプ順のログ一覧を返します。
+func (c *Correlator) Trace(id string) (models.Trace, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ct, ok := c.traces[id]
+	if !ok {
+		return models.Trace{}, false
+	}
+
+	return ct.snapshot(), true
+}
+
+// Uncorrelated は ID のフィールドを持たないため集計に含めなかったログ数を返します。
+func (c *Correlator) Uncorrelated() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.uncorrelated
+}
+
+// Evicted は保持するリクエストの上限を超えて破棄したリクエスト数を返します。
+func (c *Correlator) Evicted() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.evicted
+}
+
+// evict は上限を超えたリクエストを最も長く更新のないものから破棄します。
+func (c *Correlator) evict() {
+	for len(c.traces) > c.config.MaxTraces {
+		oldest := c.recency.Front()
+		c.recency.Remove(oldest)
+		delete(c.traces, oldest.Value.(string))
+		c.evicted++
+	}
+}
+
+// snapshot は集計のコピーを、ログをタイムスタンプ順に並べて返します。
+func (ct *correlatedTrace) snapshot() models.Trace {
+	trace := ct.trace
+
+	trace.LevelCounts = make(map[string]int, len(ct.trace.LevelCounts))
+	for level, count := range ct.trace.LevelCounts {
+		trace.LevelCounts[level] = count
+	}
+
+	trace.Sources = make([]string, 0, len(ct.sources))
+	for source := range ct.sources {
+		trace.Sources = append(trace.Sources, source)
+	}
+	sort.Strings(trace.Sources)
+
+	// 同時刻のログは追加された順を保つ
+	trace.Entries = append([]models.LogEntry(nil), ct.trace.Entries...)
+	sort.SliceStable(trace.Entries, func(i, j int) bool {
+		return trace.Entries[i].Timestamp.Before(trace.Entries[j].Timestamp)
+	})
+
+	return trace
+}
@@ -0,0 +1,103 @@
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestCorrelator_Add_GroupsByID は Correlator が ID ごとに経過時間、件数、最も重大なレベルを集計することをテストします。
+func TestCorrelator_Add_GroupsByID(t *testing.T) {
+	// Correlator のインスタンスを作成
+	correlator := NewCorrelator(DefaultCorrelatorConfig())
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 2つのリクエストのログが交互に、順不同で追加される
+	entries := []models.LogEntry{
+		{Timestamp: base.Add(2 * time.Second), Level: "INFO", Source: "api", Message: "request done", Fields: map[string]string{"request_id": "a"}},
+		{Timestamp: base, Level: "INFO", Source: "api", Message: "request start", Fields: map[string]string{"request_id": "a"}},
+		{Timestamp: base.Add(500 * time.Millisecond), Level: "INFO", Source: "api", Message: "request start", Fields: map[string]string{"request_id": "b"}},
+		{Timestamp: base.Add(time.Second), Level: "WARN", Source: "db", Message: "slow query", Fields: map[string]string{"request_id": "a"}},
+		{Timestamp: base.Add(time.Second), Level: "ERROR", Source: "db", Message: "query failed", Fields: map[string]string{"request_id": "b"}},
+		{Timestamp: base.Add(time.Second), Level: "INFO", Message: "heartbeat"},
+	}
+	for _, entry := range entries {
+		if err := correlator.Add(entry); err != nil {
+			t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+		}
+	}
+
+	// 一覧の検証
+	traces := correlator.Traces()
+
+	t.Logf("取得したリクエスト一覧: %+v", traces)
+
+	if len(traces) != 2 {
+		t.Fatalf("期待されるリクエスト数は 2 ですが、実際の値は %d です", len(traces))
+	}
+	if traces[0].ID != "a" || traces[0].Count != 3 || traces[0].Duration != 2*time.Second || traces[0].WorstLevel != "WARN" {
+		t.Errorf("リクエスト a の集計が期待値と異なります: %+v", traces[0])
+	}
+	if traces[1].ID != "b" || traces[1].Count != 2 || traces[1].WorstLevel != "ERROR" {
+		t.Errorf("リクエスト b の集計が期待値と異なります: %+v", traces[1])
+	}
+	if traces[0].Entries != nil {
+		t.Errorf("一覧にはログを含めないはずですが、%d 件含まれています", len(traces[0].Entries))
+	}
+
+	// 個別の取得ではログがタイムスタンプ順に並ぶ
+	trace, ok := correlator.Trace("a")
+	if !ok {
+		t.Fatal("リクエスト a が見つかりません")
+	}
+	expected := []string{"request start", "slow query", "request done"}
+	for i, message := range expected {
+		if trace.Entries[i].Message != message {
+			t.Errorf("%d 番目のログは %q であるべきですが、実際の値は %q です", i, message, trace.Entries[i].Message)
+		}
+	}
+	if len(trace.Sources) != 2 || trace.Sources[0] != "api" || trace.Sources[1] != "db" {
+		t.Errorf("発生源一覧が期待値と異なります: %v", trace.Sources)
+	}
+
+	// ID を持たないログは集計に含めない
+	if correlator.Uncorrelated() != 1 {
+		t.Errorf("ID を持たないログ数は 1 であるべきですが、実際の値は %d です", correlator.Uncorrelated())
+	}
+	if stats := correlator.GetStats(); stats.TotalCount != 6 {
+		t.Errorf("期待される総ログ数は 6 ですが、実際の値は %d です", stats.TotalCount)
+	}
+}
+
+// TestCorrelator_Limits は保持するリクエスト数とログ数の上限をテストします。
+func TestCorrelator_Limits(t *testing.T) {
+	// Correlator のインスタンスを作成
+	correlator := NewCorrelator(CorrelatorConfig{Field: "trace_id", MaxTraces: 2, MaxEntries: 2})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// t1 に3件、t2 に1件、t1 を更新した後に t3 を追加する
+	for i, id := range []string{"t1", "t1", "t1", "t2", "t1", "t3"} {
+		correlator.Add(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Second), Level: "INFO", Message: fmt.Sprintf("step %d", i), Fields: map[string]string{"trace_id": id}})
+	}
+
+	// 最も長く更新のない t2 が破棄される
+	if _, ok := correlator.Trace("t2"); ok {
+		t.Error("リクエスト t2 は破棄されているはずですが、残っています")
+	}
+	if correlator.Evicted() != 1 {
+		t.Errorf("破棄されたリクエスト数は 1 であるべきですが、実際の値は %d です", correlator.Evicted())
+	}
+
+	// 件数はすべて数え、ログは上限まで保持する
+	trace, ok := correlator.Trace("t1")
+	if !ok {
+		t.Fatal("リクエスト t1 が見つかりません")
+	}
+	if trace.Count != 4 || len(trace.Entries) != 2 || trace.Dropped != 2 {
+		t.Errorf("リクエスト t1 の集計が期待値と異なります: count=%d entries=%d dropped=%d", trace.Count, len(trace.Entries), trace.Dropped)
+	}
+}
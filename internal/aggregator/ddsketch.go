@@ -0,0 +1,238 @@
+package aggregator
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * math パッケージは基本的な数学関数を提供します。
+ * sort パッケージはスライスのソートを提供します。
+ */
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// defaultRelativeAccuracy は分位点スケッチの既定の相対精度 (1%) です。
+const defaultRelativeAccuracy = 0.01
+
+// DDSketch は相対誤差を保証する統合可能な分位点スケッチです。
+type DDSketch struct {
+	// 相対精度
+	relativeAccuracy float64
+	// ビンの幅を決める係数 (1+α)/(1-α)
+	gamma float64
+	// log(gamma)
+	logGamma float64
+	// 0 の件数
+	zeroCount uint64
+	// 正の値のビン
+	positive map[int]uint64
+	// 負の値のビン (絶対値で管理)
+	negative map[int]uint64
+	// 値の総数
+	count uint64
+	// 合計
+	sum float64
+	// 最小値
+	min float64
+	// 最大値
+	max float64
+}
+
+// NewDDSketch は指定した相対精度で DDSketch の新しいインスタンスを作成します。
+// 範囲外の精度が指定された場合は既定の精度を使用します。
+func NewDDSketch(relativeAccuracy float64) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = defaultRelativeAccuracy
+	}
+
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		positive:         make(map[int]uint64),
+		negative:         make(map[int]uint64),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+// Insert は値をスケッチに追加します。
+func (ds *DDSketch) Insert(value float64) {
+	ds.InsertN(value, 1)
+}
+
+// InsertN は同じ値を n 回ぶんスケッチに追加します。
+func (ds *DDSketch) InsertN(value float64, n uint64) {
+	if n == 0 {
+		return
+	}
+
+	switch {
+	case value > 0:
+		ds.positive[ds.binIndex(value)] += n
+	case value < 0:
+		ds.negative[ds.binIndex(-value)] += n
+	default:
+		ds.zeroCount += n
+	}
+
+	ds.count += n
+	ds.sum += value * float64(n)
+	ds.min = math.Min(ds.min, value)
+	ds.max = math.Max(ds.max, value)
+}
+
+// Merge は別のスケッチを統合します。相対精度が異なる場合はエラーを返します。
+func (ds *DDSketch) Merge(other *DDSketch) error {
+	if ds.relativeAccuracy != other.relativeAccuracy {
+		return fmt.Errorf("相対精度の異なる DDSketch は統合できません: %g, %g", ds.relativeAccuracy, other.relativeAccuracy)
+	}
+
+	for index, count := range other.positive {
+		ds.positive[index] += count
+	}
+	for index, count := range other.negative {
+		ds.negative[index] += count
+	}
+
+	ds.zeroCount += other.zeroCount
+	ds.count += other.count
+	ds.sum += other.sum
+	ds.min = math.Min(ds.min, other.min)
+	ds.max = math.Max(ds.max, other.max)
+
+	return nil
+}
+
+// Quantile は q (0.0〜1.0) 分位点の推定値を返します。値がない場合は 0 を返します。
+func (ds *DDSketch) Quantile(q float64) float64 {
+	if ds.count == 0 {
+		return 0
+	}
+
+	// 端点は正確な値を返す
+	if q <= 0 {
+		return ds.min
+	}
+	if q >= 1 {
+		return ds.max
+	}
+
+	rank := uint64(q * float64(ds.count-1))
+
+	// 負の値 (絶対値の大きい順) → 0 → 正の値 の順に累積する
+	var cumulative uint64
+	negativeKeys := sortedKeys(ds.negative)
+	for i := len(negativeKeys) - 1; i >= 0; i-- {
+		cumulative += ds.negative[negativeKeys[i]]
+		if cumulative > rank {
+			return ds.clamp(-ds.binValue(negativeKeys[i]))
+		}
+	}
+
+	cumulative += ds.zeroCount
+	if cumulative > rank {
+		return 0
+	}
+
+	for _, key := range sortedKeys(ds.positive) {
+		cumulative += ds.positive[key]
+		if cumulative > rank {
+			return ds.clamp(ds.binValue(key))
+		}
+	}
+
+	return ds.max
+}
+
+// Summary はスケッチから統計量を計算します。
+func (ds *DDSketch) Summary() models.NumericSummary {
+	summary := models.NumericSummary{
+		Count:  ds.count,
+		Sum:    ds.sum,
+		Sketch: ds.State(),
+	}
+	if ds.count == 0 {
+		return summary
+	}
+
+	summary.Min = ds.min
+	summary.Max = ds.max
+	summary.Mean = ds.sum / float64(ds.count)
+	summary.P50 = ds.Quantile(0.50)
+	summary.P90 = ds.Quantile(0.90)
+	summary.P95 = ds.Quantile(0.95)
+	summary.P99 = ds.Quantile(0.99)
+
+	return summary
+}
+
+// State はスケッチの内部状態のコピーを返します。
+func (ds *DDSketch) State() *models.SketchState {
+	state := &models.SketchState{
+		RelativeAccuracy: ds.relativeAccuracy,
+		ZeroCount:        ds.zeroCount,
+		Positive:         make(map[int]uint64, len(ds.positive)),
+		Negative:         make(map[int]uint64, len(ds.negative)),
+	}
+	for index, count := range ds.positive {
+		state.Positive[index] = count
+	}
+	for index, count := range ds.negative {
+		state.Negative[index] = count
+	}
+
+	return state
+}
+
+// binIndex は正の値が属するビン番号を返します。
+func (ds *DDSketch) binIndex(value float64) int {
+	return int(math.Ceil(math.Log(value) / ds.logGamma))
+}
+
+// binValue はビンの代表値を返します。
+func (ds *DDSketch) binValue(index int) float64 {
+	return 2 * math.Pow(ds.gamma, float64(index)) / (ds.gamma + 1)
+}
+
+// clamp は推定値を観測された最小値と最大値の範囲に収めます。
+func (ds *DDSketch) clamp(value float64) float64 {
+	return math.Max(ds.min, math.Min(ds.max, value))
+}
+
+// newDDSketchFromSummary は統計量に含まれる状態からスケッチを復元します。
+func newDDSketchFromSummary(summary models.NumericSummary) (*DDSketch, error) {
+	if summary.Sketch == nil {
+		return nil, fmt.Errorf("スケッチの状態が含まれていません")
+	}
+
+	ds := NewDDSketch(summary.Sketch.RelativeAccuracy)
+	for index, count := range summary.Sketch.Positive {
+		ds.positive[index] = count
+	}
+	for index, count := range summary.Sketch.Negative {
+		ds.negative[index] = count
+	}
+	ds.zeroCount = summary.Sketch.ZeroCount
+	ds.count = summary.Count
+	ds.sum = summary.Sum
+	if summary.Count > 0 {
+		ds.min = summary.Min
+		ds.max = summary.Max
+	}
+
+	return ds, nil
+}
+
+// sortedKeys はビン番号を昇順に並べて返します。
+func sortedKeys(bins map[int]uint64) []int {
+	keys := make([]int, 0, len(bins))
+	for key := range bins {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
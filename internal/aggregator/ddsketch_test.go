@@ -0,0 +1,72 @@
+package aggregator
+
+import (
+	"math"
+	"testing"
+)
+
+// TestDDSketch_Quantile は DDSketch の分位点が相対精度の範囲内であることをテストします。
+func TestDDSketch_Quantile(t *testing.T) {
+	// DDSketch のインスタンスを作成
+	sketch := NewDDSketch(0.01)
+
+	// 1〜10000 を追加
+	for i := 1; i <= 10000; i++ {
+		sketch.Insert(float64(i))
+	}
+
+	// 分位点の検証
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99} {
+		expected := q * 9999
+		actual := sketch.Quantile(q)
+
+		t.Logf("q=%.2f 期待値: %.1f, 推定値: %.1f", q, expected, actual)
+
+		if math.Abs(actual-expected)/expected > 0.011 {
+			t.Errorf("q=%.2f の推定値が相対精度の範囲外です。期待値: %.1f, 推定値: %.1f", q, expected, actual)
+		}
+	}
+
+	// 端点は正確な値
+	if sketch.Quantile(0) != 1 || sketch.Quantile(1) != 10000 {
+		t.Errorf("最小値と最大値が期待値と異なります: %f, %f", sketch.Quantile(0), sketch.Quantile(1))
+	}
+}
+
+// TestDDSketch_Merge は DDSketch を統合した結果が一括で追加した結果と一致することをテストします。
+func TestDDSketch_Merge(t *testing.T) {
+	a := NewDDSketch(0.02)
+	b := NewDDSketch(0.02)
+	all := NewDDSketch(0.02)
+
+	// 負の値と0を含む値を2つのスケッチに分けて追加
+	for i := -500; i <= 1500; i++ {
+		if i%2 == 0 {
+			a.Insert(float64(i))
+		} else {
+			b.Insert(float64(i))
+		}
+		all.Insert(float64(i))
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("統合に失敗しました: %v", err)
+	}
+
+	merged := a.Summary()
+	expected := all.Summary()
+
+	t.Logf("統合結果: %+v", merged)
+
+	if merged.Count != expected.Count || merged.Sum != expected.Sum || merged.Min != expected.Min || merged.Max != expected.Max {
+		t.Errorf("統合結果が期待値と異なります。期待値: %+v, 実際: %+v", expected, merged)
+	}
+	if merged.P50 != expected.P50 || merged.P99 != expected.P99 {
+		t.Errorf("統合後の分位点が期待値と異なります。期待値: %f/%f, 実際: %f/%f", expected.P50, expected.P99, merged.P50, merged.P99)
+	}
+
+	// 相対精度が異なる場合はエラー
+	if err := a.Merge(NewDDSketch(0.05)); err == nil {
+		t.Errorf("相対精度の異なるスケッチの統合はエラーになるはずです")
+	}
+}
@@ -0,0 +1,111 @@
+package aggregator
+
+import "github.com/Yamituki/go-review-logagg/pkg/models"
+
+// DistinctCounter は指定したフィールドの異なり数を HyperLogLog で推定する拡張です。
+type DistinctCounter struct {
+	// 対象のフィールド名
+	field string
+	// 異なり数のスケッチ
+	sketch *HyperLogLog
+}
+
+// NewDistinctCounter は指定したフィールドの DistinctCounter を既定の精度で作成します。
+func NewDistinctCounter(field string) *DistinctCounter {
+	return NewDistinctCounterWithPrecision(field, defaultPrecision)
+}
+
+// NewDistinctCounterWithPrecision は指定したフィールドと精度で DistinctCounter を作成します。
+func NewDistinctCounterWithPrecision(field string, precision uint8) *DistinctCounter {
+	return &DistinctCounter{
+		field:  field,
+		sketch: NewHyperLogLog(precision),
+	}
+}
+
+// Add はエントリのフィールドの値をスケッチに追加します。フィールドがない場合は無視します。
+func (dc *DistinctCounter) Add(entry models.LogEntry) {
+	if value, ok := entry.Field(dc.field); ok {
+		dc.sketch.Insert(value)
+	}
+}
+
+// Apply は異なり数の推定値を統計情報に書き込みます。
+func (dc *DistinctCounter) Apply(stats *models.Stats) {
+	if stats.Distinct == nil {
+		stats.Distinct = make(map[string]models.DistinctEstimate)
+	}
+	stats.Distinct[dc.field] = newDistinctEstimate(dc.sketch)
+}
+
+// Reset はスケッチをリセットします。
+func (dc *DistinctCounter) Reset() {
+	dc.sketch.Reset()
+}
+
+// Merge は同じフィールドを対象とする別の DistinctCounter を統合します。
+func (dc *DistinctCounter) Merge(other *DistinctCounter) error {
+	return dc.sketch.Merge(other.sketch)
+}
+
+// newDistinctEstimate はスケッチから推定値と95%信頼区間を計算します。
+func newDistinctEstimate(sketch *HyperLogLog) models.DistinctEstimate {
+	estimate := sketch.Estimate()
+	standardError := sketch.StandardError()
+
+	// 95%信頼区間 (±1.96σ)
+	margin := float64(estimate) * standardError * 1.96
+	lower := uint64(0)
+	if float64(estimate) > margin {
+		lower = uint64(float64(estimate) - margin)
+	}
+
+	return models.DistinctEstimate{
+		Estimate:      estimate,
+		StandardError: standardError,
+		Lower:         lower,
+		Upper:         uint64(float64(estimate) + margin + 0.5),
+		Registers:     sketch.Registers(),
+	}
+}
+
+// mergeDistinct はフィールドごとの異なり数の推定値をスケッチ単位で統合します。
+// 統合できない組み合わせの場合は、推定値の大きい方を採用します。
+func mergeDistinct(a, b map[string]models.DistinctEstimate) map[string]models.DistinctEstimate {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]models.DistinctEstimate, len(a)+len(b))
+	for field, estimate := range a {
+		merged[field] = estimate
+	}
+
+	for field, estimate := range b {
+		current, ok := merged[field]
+		if !ok {
+			merged[field] = estimate
+			continue
+		}
+
+		// レジスタからスケッチを復元して統合
+		sketch, err := newHyperLogLogFromRegisters(current.Registers)
+		if err == nil {
+			var other *HyperLogLog
+			other, err = newHyperLogLogFromRegisters(estimate.Registers)
+			if err == nil {
+				err = sketch.Merge(other)
+			}
+		}
+		if err != nil {
+			if estimate.Estimate > current.Estimate {
+				merged[field] = estimate
+			}
+			continue
+		}
+
+		merged[field] = newDistinctEstimate(sketch)
+	}
+
+	return merged
+}
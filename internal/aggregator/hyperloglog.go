@@ -0,0 +1,154 @@
+package aggregator
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * hash/fnv パッケージは FNV ハッシュ関数を提供します。
+ * math パッケージは基本的な数学関数を提供します。
+ * math/bits パッケージはビット操作を提供します。
+ */
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	// HyperLogLog の精度の最小値
+	minPrecision = 4
+	// HyperLogLog の精度の最大値
+	maxPrecision = 16
+	// 既定の精度 (レジスタ数 16384、相対標準誤差 約0.8%)
+	defaultPrecision = 14
+)
+
+// HyperLogLog は異なり数を固定サイズのメモリで推定するためのスケッチです。
+type HyperLogLog struct {
+	// 精度 (レジスタ数は 2^precision)
+	precision uint8
+	// レジスタ一覧
+	registers []uint8
+}
+
+// NewHyperLogLog は指定した精度で HyperLogLog の新しいインスタンスを作成します。
+// 範囲外の精度が指定された場合は既定の精度を使用します。
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < minPrecision || precision > maxPrecision {
+		precision = defaultPrecision
+	}
+
+	return &HyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+// newHyperLogLogFromRegisters はレジスタ一覧から HyperLogLog を復元します。
+func newHyperLogLogFromRegisters(registers []uint8) (*HyperLogLog, error) {
+	// レジスタ数から精度を求める
+	precision := bits.TrailingZeros(uint(len(registers)))
+	if len(registers) == 0 || 1<<precision != len(registers) || precision < minPrecision || precision > maxPrecision {
+		return nil, fmt.Errorf("不正なレジスタ数です: %d", len(registers))
+	}
+
+	return &HyperLogLog{
+		precision: uint8(precision),
+		registers: append([]uint8(nil), registers...),
+	}, nil
+}
+
+// Insert は値をスケッチに追加します。
+func (hll *HyperLogLog) Insert(value string) {
+	hash := HashString(value)
+
+	// 上位ビットでレジスタを選び、残りのビットの先頭の0の数を記録する
+	index := hash >> (64 - hll.precision)
+	rank := uint8(bits.LeadingZeros64(hash<<hll.precision|1<<(hll.precision-1))) + 1
+
+	if rank > hll.registers[index] {
+		hll.registers[index] = rank
+	}
+}
+
+// Merge は別のスケッチを統合します。精度が異なる場合はエラーを返します。
+func (hll *HyperLogLog) Merge(other *HyperLogLog) error {
+	if hll.precision != other.precision {
+		return fmt.Errorf("精度の異なる HyperLogLog は統合できません: %d, %d", hll.precision, other.precision)
+	}
+
+	for i, rank := range other.registers {
+		if rank > hll.registers[i] {
+			hll.registers[i] = rank
+		}
+	}
+
+	return nil
+}
+
+// Estimate は異なり数の推定値を返します。
+func (hll *HyperLogLog) Estimate() uint64 {
+	m := float64(len(hll.registers))
+
+	// 調和平均の計算
+	sum := 0.0
+	zeros := 0
+	for _, rank := range hll.registers {
+		sum += 1.0 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := alpha(len(hll.registers)) * m * m / sum
+
+	// 小さな値では線形カウンティングで補正する
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// StandardError は推定値の相対標準誤差を返します。
+func (hll *HyperLogLog) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(len(hll.registers)))
+}
+
+// Registers はレジスタ一覧のコピーを返します。
+func (hll *HyperLogLog) Registers() []uint8 {
+	return append([]uint8(nil), hll.registers...)
+}
+
+// Reset はすべてのレジスタを0に戻します。
+func (hll *HyperLogLog) Reset() {
+	clear(hll.registers)
+}
+
+// alpha はレジスタ数に応じた補正係数を返します。
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
+
+// HashString は文字列の一様に分布する64ビットハッシュ値を返します。
+// FNV-1a の結果を splitmix64 の最終処理で攪拌し、ビットの偏りを抑えます。
+// 異なり数の推定とサンプリングで共通して使用します。
+func HashString(value string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	hash := hasher.Sum64()
+
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	hash ^= hash >> 31
+
+	return hash
+}
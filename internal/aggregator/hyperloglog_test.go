@@ -0,0 +1,104 @@
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestHyperLogLog_Estimate は HyperLogLog の推定値が誤差の範囲内であることをテストします。
+func TestHyperLogLog_Estimate(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		// HyperLogLog のインスタンスを作成
+		sketch := NewHyperLogLog(defaultPrecision)
+
+		// 重複を含めて値を追加
+		for i := 0; i < n; i++ {
+			sketch.Insert(fmt.Sprintf("host-%d", i))
+			sketch.Insert(fmt.Sprintf("host-%d", i))
+		}
+
+		estimate := sketch.Estimate()
+
+		t.Logf("実際の異なり数: %d, 推定値: %d", n, estimate)
+
+		// 標準誤差の4倍以内であることを検証
+		tolerance := math.Max(1, float64(n)*sketch.StandardError()*4)
+		if math.Abs(float64(estimate)-float64(n)) > tolerance {
+			t.Errorf("推定値が誤差の範囲外です。実際: %d, 推定値: %d", n, estimate)
+		}
+	}
+}
+
+// TestHyperLogLog_Merge は統合したスケッチの推定値が和集合の異なり数に近いことをテストします。
+func TestHyperLogLog_Merge(t *testing.T) {
+	a := NewHyperLogLog(12)
+	b := NewHyperLogLog(12)
+
+	// 半分が重複する2つの集合
+	for i := 0; i < 20000; i++ {
+		a.Insert(fmt.Sprintf("user-%d", i))
+		b.Insert(fmt.Sprintf("user-%d", i+10000))
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("統合に失敗しました: %v", err)
+	}
+
+	estimate := a.Estimate()
+
+	t.Logf("統合後の推定値: %d", estimate)
+
+	if math.Abs(float64(estimate)-30000) > 30000*a.StandardError()*4 {
+		t.Errorf("統合後の推定値が誤差の範囲外です: %d", estimate)
+	}
+
+	// 精度が異なる場合はエラー
+	if err := a.Merge(NewHyperLogLog(10)); err == nil {
+		t.Errorf("精度の異なるスケッチの統合はエラーになるはずです")
+	}
+}
+
+// TestLogAggregator_DistinctCounter は LogAggregator に付加した DistinctCounter が統計情報に含まれることをテストします。
+func TestLogAggregator_DistinctCounter(t *testing.T) {
+	// ワーカーごとの集約器を作成
+	first := NewLogAggregator()
+	first.Attach(NewDistinctCounter("host"))
+	second := NewLogAggregator()
+	second.Attach(NewDistinctCounter("host"))
+
+	// 一部のホストが重複するエントリを追加
+	for i := 0; i < 300; i++ {
+		first.Add(models.LogEntry{Timestamp: time.Now(), Level: "INFO", Fields: map[string]string{"host": fmt.Sprintf("web-%d", i)}})
+		second.Add(models.LogEntry{Timestamp: time.Now(), Level: "INFO", Fields: map[string]string{"host": fmt.Sprintf("web-%d", i+100)}})
+	}
+
+	// フィールドのないエントリは無視される
+	first.Add(models.LogEntry{Timestamp: time.Now(), Level: "WARN"})
+
+	// 統計情報を統合
+	stats := MergeStats(first.GetStats(), second.GetStats())
+
+	estimate, ok := stats.Distinct["host"]
+	if !ok {
+		t.Fatalf("host の異なり数が統計情報に含まれていません")
+	}
+
+	t.Logf("host の異なり数: %+v", estimate)
+
+	if estimate.Estimate < 390 || estimate.Estimate > 410 {
+		t.Errorf("期待される異なり数は約 400 ですが、実際の値は %d です", estimate.Estimate)
+	}
+	if estimate.Lower > estimate.Estimate || estimate.Upper < estimate.Estimate {
+		t.Errorf("信頼区間が推定値を含んでいません: %+v", estimate)
+	}
+
+	// リセット後の検証
+	first.Reset()
+	if first.GetStats().Distinct["host"].Estimate != 0 {
+		t.Errorf("リセット後の異なり数は 0 であるべきですが、実際の値は %d です", first.GetStats().Distinct["host"].Estimate)
+	}
+}
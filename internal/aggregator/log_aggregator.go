@@ -2,66 +2,110 @@ package aggregator
 
 import "github.com/Yamituki/go-review-logagg/pkg/models"
 
+// StatsExtension は LogAggregator に付加して追加の統計情報を計算するためのインターフェースです。
+type StatsExtension interface {
+	// 1つのエントリを追加
+	Add(entry models.LogEntry)
+	// 統計情報に結果を書き込む
+	Apply(stats *models.Stats)
+	// 状態をリセット
+	Reset()
+}
+
 // LogAggregator はログデータを集約するための構造体です。
+// 統計情報は保持方針に関係なくすべてのエントリから計算されます。
 type LogAggregator struct {
-	// 保持するログ一覧
-	entries []models.LogEntry
+	// 保持方針に従って保持するログ一覧
+	entries *entryStore
 	// 統計情報
 	stats models.Stats
+	// 付加された拡張一覧
+	extensions []StatsExtension
 }
 
-// NewLogAggregator は LogAggregator の新しいインスタンスを作成します。
+// NewLogAggregator はエントリを保持しない LogAggregator の新しいインスタンスを作成します。
 func NewLogAggregator() *LogAggregator {
-	return &LogAggregator{}
+	return NewLogAggregatorWithRetention(RetentionPolicy{Mode: RetainNone})
+}
+
+// NewLogAggregatorWithRetention は指定した保持方針で LogAggregator の新しいインスタンスを作成します。
+func NewLogAggregatorWithRetention(policy RetentionPolicy) *LogAggregator {
+	return &LogAggregator{
+		entries: newEntryStore(policy),
+	}
 }
 
 // Add は1つのログエントリを追加します。
 func (la *LogAggregator) Add(entry models.LogEntry) error {
-	la.entries = append(la.entries, entry)
+	la.entries.add(entry)
 	la.updateStats(entry)
+	for _, ext := range la.extensions {
+		ext.Add(entry)
+	}
 	return nil
 }
 
+// Attach は拡張を付加します。以降に追加されたエントリから拡張の集計に含まれます。
+func (la *LogAggregator) Attach(ext StatsExtension) {
+	la.extensions = append(la.extensions, ext)
+}
+
+// Entries は保持方針に従って保持しているログエントリのコピーを返します。
+func (la *LogAggregator) Entries() []models.LogEntry {
+	return la.entries.list()
+}
+
 // GetStats は現在のログエントリに基づいて統計情報を取得します。
 func (la *LogAggregator) GetStats() models.Stats {
-	return la.stats
+	stats := la.stats
+	for _, ext := range la.extensions {
+		ext.Apply(&stats)
+	}
+	return stats
 }
 
 // Reset は集約されたログデータと統計情報をリセットします。
 func (la *LogAggregator) Reset() {
-	la.entries = []models.LogEntry{}
+	la.entries.reset()
 	la.stats = models.Stats{}
+	for _, ext := range la.extensions {
+		ext.Reset()
+	}
 }
 
 // 統計情報の更新メソッド
 func (la *LogAggregator) updateStats(entry models.LogEntry) {
+	// 重複をまとめたエントリは繰り返し回数ぶん数える
+	count := entry.Count()
+	first := la.stats.TotalCount == 0
+
 	// 総ログ数の更新
-	la.stats.TotalCount++
+	la.stats.TotalCount += count
 
 	// レベル別ログ数の更新
 	switch entry.Level {
 	case "INFO":
-		la.stats.InfoCount++
+		la.stats.InfoCount += count
 	case "WARN":
-		la.stats.WarnCount++
+		la.stats.WarnCount += count
 	case "ERROR":
-		la.stats.ErrorCount++
+		la.stats.ErrorCount += count
 	}
 
 	// 最初と最後のタイムスタンプの初期化
-	if la.stats.TotalCount == 1 {
+	if first {
 		la.stats.FirstTimestamp = entry.Timestamp
-		la.stats.LastTimestamp = entry.Timestamp
+		la.stats.LastTimestamp = entry.LastTimestamp()
 		return
 	}
 
 	// 最初のタイムスタンプの更新
-	if entry.Timestamp.Before(la.stats.FirstTimestamp) || la.stats.TotalCount == 1 {
+	if entry.Timestamp.Before(la.stats.FirstTimestamp) {
 		la.stats.FirstTimestamp = entry.Timestamp
 	}
 
 	// 最後のタイムスタンプの更新
-	if entry.Timestamp.After(la.stats.LastTimestamp) {
-		la.stats.LastTimestamp = entry.Timestamp
+	if entry.LastTimestamp().After(la.stats.LastTimestamp) {
+		la.stats.LastTimestamp = entry.LastTimestamp()
 	}
 }
@@ -0,0 +1,131 @@
+package aggregator
+
+import "github.com/Yamituki/go-review-logagg/pkg/models"
+
+// MergeStats は2つの統計情報を統合した結果を返します。
+func MergeStats(a, b models.Stats) models.Stats {
+	// 拡張による統計情報の統合
+	distinct := mergeDistinct(a.Distinct, b.Distinct)
+	numeric := mergeNumeric(a.Numeric, b.Numeric)
+	rules := mergeCounts(a.Rules, b.Rules)
+	redactions := mergeCounts(a.Redactions, b.Redactions)
+	sampling := mergeSampling(a.Sampling, b.Sampling)
+	window := mergeWindow(a.Window, b.Window)
+	health := mergeHealth(a.Health, b.Health)
+	rejected := a.Rejected + b.Rejected
+	filtered := a.Filtered + b.Filtered
+	rotations := append(append([]models.RotationEvent(nil), a.Rotations...), b.Rotations...)
+
+	// 片方が空の場合はもう片方の件数をそのまま使う
+	if a.TotalCount == 0 {
+		a = b
+	} else if b.TotalCount != 0 {
+		a = addCounts(a, b)
+	}
+
+	a.Distinct = distinct
+	a.Numeric = numeric
+	a.Rules = rules
+	a.Redactions = redactions
+	a.Sampling = sampling
+	a.Window = window
+	a.Health = health
+	a.Rejected = rejected
+	a.Filtered = filtered
+	if len(rotations) > 0 {
+		a.Rotations = rotations
+	}
+
+	return a
+}
+
+// addCounts は2つの空でない統計情報の件数とタイムスタンプの範囲を統合します。
+func addCounts(a, b models.Stats) models.Stats {
+	// 件数の合計
+	a.TotalCount += b.TotalCount
+	a.InfoCount += b.InfoCount
+	a.WarnCount += b.WarnCount
+	a.ErrorCount += b.ErrorCount
+
+	// 最小タイムスタンプの更新
+	if b.FirstTimestamp.Before(a.FirstTimestamp) {
+		a.FirstTimestamp = b.FirstTimestamp
+	}
+
+	// 最大タイムスタンプの更新
+	if b.LastTimestamp.After(a.LastTimestamp) {
+		a.LastTimestamp = b.LastTimestamp
+	}
+
+	return a
+}
+
+// mergeCounts は名前ごとの件数を合計します (ルールごと、マスキングの種類ごと、ウィンドウのレベルごとの件数で共通)。
+func mergeCounts(a, b map[string]int) map[string]int {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]int, len(a)+len(b))
+	for name, count := range a {
+		merged[name] += count
+	}
+	for name, count := range b {
+		merged[name] += count
+	}
+
+	return merged
+}
+
+// mergeSampling はサンプリングの情報を統合します。どちらかが推定値であれば統合結果も推定値とします。
+func mergeSampling(a, b *models.SamplingInfo) *models.SamplingInfo {
+	if a == nil && b == nil {
+		return nil
+	}
+
+	merged := &models.SamplingInfo{}
+	for _, info := range []*models.SamplingInfo{a, b} {
+		if info == nil {
+			continue
+		}
+		merged.Estimated = merged.Estimated || info.Estimated
+		merged.Observed += info.Observed
+		merged.Kept += info.Kept
+	}
+
+	return merged
+}
+
+// healthRank は監視の状態の深刻度です。
+var healthRank = map[models.HealthState]int{
+	models.HealthOK:      0,
+	models.HealthWaiting: 1,
+	models.HealthFailing: 2,
+}
+
+// mergeHealth は監視の状態を統合します。より深刻な状態 (同じ場合は連続した失敗の多い方) を採用し、最後に正常に読み込んだ時刻は新しい方とします。
+func mergeHealth(a, b *models.MonitorHealth) *models.MonitorHealth {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil {
+		a, b = b, a
+	}
+
+	merged := *a
+	if b == nil {
+		return &merged
+	}
+	if healthRank[b.State] > healthRank[a.State] ||
+		(b.State == a.State && b.ConsecutiveFailures > a.ConsecutiveFailures) {
+		merged = *b
+	}
+	if a.LastReadAt.After(merged.LastReadAt) {
+		merged.LastReadAt = a.LastReadAt
+	}
+	if b.LastReadAt.After(merged.LastReadAt) {
+		merged.LastReadAt = b.LastReadAt
+	}
+
+	return &merged
+}
@@ -0,0 +1,233 @@
+package aggregator
+
+/*
+ * math パッケージは基本的な数学関数を提供します。
+ * sort パッケージはスライスのソートを提供します。
+ * strconv パッケージは文字列と基本データ型の変換を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// NumericOptions は数値フィールドの集約方法を表す構造体です。
+type NumericOptions struct {
+	// グループ化に使用するフィールド名 (空の場合はグループ化しない)
+	GroupBy string
+	// 時間バケットの長さ (0 の場合は時間バケットを作らない)
+	BucketSize time.Duration
+	// 分位点スケッチの相対精度 (0 の場合は既定値)
+	RelativeAccuracy float64
+}
+
+// NumericCounter は数値フィールドの件数、合計、最小値、最大値、平均値、分位点を計算する拡張です。
+type NumericCounter struct {
+	// 対象のフィールド名
+	field string
+	// 集約方法
+	options NumericOptions
+	// 全体のスケッチ
+	overall *DDSketch
+	// グループ別のスケッチ
+	groups map[string]*DDSketch
+	// 時間バケット別のスケッチ (キーはバケットの開始時刻の UnixNano)
+	buckets map[int64]*DDSketch
+}
+
+// NewNumericCounter は指定したフィールドの NumericCounter を作成します。
+func NewNumericCounter(field string, options NumericOptions) *NumericCounter {
+	if options.RelativeAccuracy <= 0 {
+		options.RelativeAccuracy = defaultRelativeAccuracy
+	}
+	if options.BucketSize < 0 {
+		options.BucketSize = 0
+	}
+
+	nc := &NumericCounter{
+		field:   field,
+		options: options,
+	}
+	nc.Reset()
+
+	return nc
+}
+
+// Add はエントリのフィールドの値を追加します。フィールドがない場合や数値でない場合は無視します。
+// NaN と無限大は統計量を壊し JSON にも変換できないため、数値でない値として無視します。
+func (nc *NumericCounter) Add(entry models.LogEntry) {
+	raw, ok := entry.Field(nc.field)
+	if !ok {
+		return
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	// 重複をまとめたエントリは繰り返し回数ぶん数える
+	n := uint64(entry.Count())
+
+	// 全体の集計
+	nc.overall.InsertN(value, n)
+
+	// グループ別の集計
+	if nc.options.GroupBy != "" {
+		group, _ := entry.Field(nc.options.GroupBy)
+		sketch, ok := nc.groups[group]
+		if !ok {
+			sketch = NewDDSketch(nc.options.RelativeAccuracy)
+			nc.groups[group] = sketch
+		}
+		sketch.InsertN(value, n)
+	}
+
+	// 時間バケット別の集計
+	if nc.options.BucketSize > 0 {
+		start := entry.Timestamp.Truncate(nc.options.BucketSize).UnixNano()
+		sketch, ok := nc.buckets[start]
+		if !ok {
+			sketch = NewDDSketch(nc.options.RelativeAccuracy)
+			nc.buckets[start] = sketch
+		}
+		sketch.InsertN(value, n)
+	}
+}
+
+// Apply は数値フィールドの統計量を統計情報に書き込みます。
+func (nc *NumericCounter) Apply(stats *models.Stats) {
+	report := models.NumericReport{
+		Overall:    nc.overall.Summary(),
+		GroupBy:    nc.options.GroupBy,
+		BucketSize: nc.options.BucketSize,
+	}
+
+	// グループ別の統計量
+	if len(nc.groups) > 0 {
+		report.Groups = make(map[string]models.NumericSummary, len(nc.groups))
+		for group, sketch := range nc.groups {
+			report.Groups[group] = sketch.Summary()
+		}
+	}
+
+	// 時間バケット別の統計量
+	for start, sketch := range nc.buckets {
+		report.Buckets = append(report.Buckets, models.NumericBucket{
+			Start:   time.Unix(0, start).UTC(),
+			Summary: sketch.Summary(),
+		})
+	}
+	sortNumericBuckets(report.Buckets)
+
+	if stats.Numeric == nil {
+		stats.Numeric = make(map[string]models.NumericReport)
+	}
+	stats.Numeric[nc.field] = report
+}
+
+// Reset はすべてのスケッチをリセットします。
+func (nc *NumericCounter) Reset() {
+	nc.overall = NewDDSketch(nc.options.RelativeAccuracy)
+	nc.groups = make(map[string]*DDSketch)
+	nc.buckets = make(map[int64]*DDSketch)
+}
+
+// mergeNumeric はフィールドごとの数値統計をスケッチ単位で統合します。
+func mergeNumeric(a, b map[string]models.NumericReport) map[string]models.NumericReport {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]models.NumericReport, len(a)+len(b))
+	for field, report := range a {
+		merged[field] = report
+	}
+
+	for field, report := range b {
+		current, ok := merged[field]
+		if !ok {
+			merged[field] = report
+			continue
+		}
+
+		// 全体の統合
+		current.Overall = mergeSummary(current.Overall, report.Overall)
+
+		// グループ別の統合
+		if len(report.Groups) > 0 {
+			groups := make(map[string]models.NumericSummary, len(current.Groups)+len(report.Groups))
+			for group, summary := range current.Groups {
+				groups[group] = summary
+			}
+			for group, summary := range report.Groups {
+				if existing, ok := groups[group]; ok {
+					summary = mergeSummary(existing, summary)
+				}
+				groups[group] = summary
+			}
+			current.Groups = groups
+		}
+
+		// 時間バケット別の統合
+		if len(report.Buckets) > 0 {
+			byStart := make(map[int64]models.NumericBucket, len(current.Buckets)+len(report.Buckets))
+			for _, bucket := range current.Buckets {
+				byStart[bucket.Start.UnixNano()] = bucket
+			}
+			for _, bucket := range report.Buckets {
+				if existing, ok := byStart[bucket.Start.UnixNano()]; ok {
+					bucket.Summary = mergeSummary(existing.Summary, bucket.Summary)
+				}
+				byStart[bucket.Start.UnixNano()] = bucket
+			}
+
+			current.Buckets = current.Buckets[:0:0]
+			for _, bucket := range byStart {
+				current.Buckets = append(current.Buckets, bucket)
+			}
+			sortNumericBuckets(current.Buckets)
+		}
+
+		merged[field] = current
+	}
+
+	return merged
+}
+
+// mergeSummary は2つの統計量をスケッチから統合します。統合できない場合は件数の多い方を採用します。
+func mergeSummary(a, b models.NumericSummary) models.NumericSummary {
+	if b.Count == 0 {
+		return a
+	}
+	if a.Count == 0 {
+		return b
+	}
+
+	sketch, err := newDDSketchFromSummary(a)
+	if err == nil {
+		var other *DDSketch
+		other, err = newDDSketchFromSummary(b)
+		if err == nil {
+			err = sketch.Merge(other)
+		}
+	}
+	if err != nil {
+		if b.Count > a.Count {
+			return b
+		}
+		return a
+	}
+
+	return sketch.Summary()
+}
+
+// sortNumericBuckets は時間バケットを開始時刻順に並べます。
+func sortNumericBuckets(buckets []models.NumericBucket) {
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+}
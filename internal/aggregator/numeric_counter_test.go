@@ -0,0 +1,132 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestNumericCounter_GroupsAndBuckets は NumericCounter がグループ別、時間バケット別に統計量を計算することをテストします。
+func TestNumericCounter_GroupsAndBuckets(t *testing.T) {
+	// LogAggregator に NumericCounter を付加
+	aggregator := NewLogAggregator()
+	aggregator.Attach(NewNumericCounter("duration_ms", NumericOptions{GroupBy: "host", BucketSize: time.Minute}))
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// web-1 は 12:00 台に 1〜100、web-2 は 12:01 台に 1000 を追加
+	for i := 1; i <= 100; i++ {
+		aggregator.Add(models.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * 100 * time.Millisecond),
+			Level:     "INFO",
+			Fields:    map[string]string{"host": "web-1", "duration_ms": fmt.Sprint(i)},
+		})
+	}
+	aggregator.Add(models.LogEntry{
+		Timestamp: base.Add(90 * time.Second),
+		Level:     "INFO",
+		Fields:    map[string]string{"host": "web-2", "duration_ms": "1000"},
+	})
+
+	// 数値でない値とフィールドのないエントリは無視される
+	aggregator.Add(models.LogEntry{Timestamp: base, Level: "INFO", Fields: map[string]string{"duration_ms": "n/a"}})
+	aggregator.Add(models.LogEntry{Timestamp: base, Level: "INFO"})
+
+	report := aggregator.GetStats().Numeric["duration_ms"]
+
+	t.Logf("全体の統計量: %+v", report.Overall)
+
+	// 全体の統計量の検証
+	if report.Overall.Count != 101 || report.Overall.Min != 1 || report.Overall.Max != 1000 {
+		t.Errorf("全体の統計量が期待値と異なります: %+v", report.Overall)
+	}
+	if math.Abs(report.Overall.Mean-(5050.0+1000)/101) > 1e-9 {
+		t.Errorf("平均値が期待値と異なります: %f", report.Overall.Mean)
+	}
+
+	// グループ別の統計量の検証
+	web1 := report.Groups["web-1"]
+	if web1.Count != 100 || math.Abs(web1.P50-50)/50 > 0.02 || math.Abs(web1.P99-99)/99 > 0.02 {
+		t.Errorf("web-1 の統計量が期待値と異なります: %+v", web1)
+	}
+	if report.Groups["web-2"].Max != 1000 {
+		t.Errorf("web-2 の統計量が期待値と異なります: %+v", report.Groups["web-2"])
+	}
+
+	// 時間バケット別の統計量の検証
+	if len(report.Buckets) != 2 {
+		t.Fatalf("期待される時間バケット数は 2 ですが、実際の値は %d です", len(report.Buckets))
+	}
+	if !report.Buckets[0].Start.Equal(base) || report.Buckets[0].Summary.Count != 100 {
+		t.Errorf("最初の時間バケットが期待値と異なります: %+v", report.Buckets[0])
+	}
+	if !report.Buckets[1].Start.Equal(base.Add(time.Minute)) || report.Buckets[1].Summary.Count != 1 {
+		t.Errorf("2番目の時間バケットが期待値と異なります: %+v", report.Buckets[1])
+	}
+}
+
+// TestNumericCounter_NonFinite は NaN と無限大の値が無視され、統計情報を JSON に変換できることをテストします。
+func TestNumericCounter_NonFinite(t *testing.T) {
+	aggregator := NewLogAggregator()
+	aggregator.Attach(NewNumericCounter("duration_ms", NumericOptions{GroupBy: "host", BucketSize: time.Minute}))
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, value := range []string{"10", "NaN", "Inf", "+Inf", "-Inf", "20"} {
+		aggregator.Add(models.LogEntry{
+			Timestamp: base,
+			Level:     "INFO",
+			Fields:    map[string]string{"host": "web-1", "duration_ms": value},
+		})
+	}
+
+	stats := aggregator.GetStats()
+	overall := stats.Numeric["duration_ms"].Overall
+	if overall.Count != 2 || overall.Sum != 30 || overall.Min != 10 || overall.Max != 20 {
+		t.Errorf("全体の統計量が期待値と異なります: %+v", overall)
+	}
+	if _, err := json.Marshal(stats); err != nil {
+		t.Errorf("統計情報を JSON に変換できません: %v", err)
+	}
+}
+
+// TestMergeStats_Numeric はワーカーごとの数値統計が MergeStats で統合されることをテストします。
+func TestMergeStats_Numeric(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 2つのワーカーの集約器
+	var workers []*LogAggregator
+	for w := 0; w < 2; w++ {
+		aggregator := NewLogAggregator()
+		aggregator.Attach(NewNumericCounter("size", NumericOptions{GroupBy: "host", BucketSize: time.Hour}))
+		for i := 0; i < 50; i++ {
+			aggregator.Add(models.LogEntry{
+				Timestamp: base,
+				Level:     "INFO",
+				Fields:    map[string]string{"host": "web-1", "size": fmt.Sprint(w*50 + i + 1)},
+			})
+		}
+		workers = append(workers, aggregator)
+	}
+
+	stats := MergeStats(workers[0].GetStats(), workers[1].GetStats())
+	report := stats.Numeric["size"]
+
+	t.Logf("統合後の統計量: %+v", report.Overall)
+
+	if report.Overall.Count != 100 || report.Overall.Min != 1 || report.Overall.Max != 100 {
+		t.Errorf("統合後の統計量が期待値と異なります: %+v", report.Overall)
+	}
+	if report.Groups["web-1"].Count != 100 {
+		t.Errorf("統合後のグループ別の件数が期待値と異なります: %d", report.Groups["web-1"].Count)
+	}
+	if len(report.Buckets) != 1 || report.Buckets[0].Summary.Count != 100 {
+		t.Errorf("統合後の時間バケットが期待値と異なります: %+v", report.Buckets)
+	}
+	if math.Abs(report.Overall.P90-90)/90 > 0.02 {
+		t.Errorf("統合後の90パーセンタイルが期待値と異なります: %f", report.Overall.P90)
+	}
+}
@@ -0,0 +1,102 @@
+package aggregator
+
+/*
+ * math/rand/v2 パッケージは擬似乱数の生成を提供します。
+ */
+import (
+	"math/rand/v2"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// RetentionMode はエントリの保持方式を表す型です。
+type RetentionMode int
+
+const (
+	// RetainNone はエントリを一切保持しません。メモリ使用量はエントリ数に依存せず O(1) です。
+	RetainNone RetentionMode = iota
+	// RetainLast は直近 N 件のエントリをリングバッファに保持します。メモリ使用量は O(N) で上限があります。
+	RetainLast
+	// RetainSample はリザーバサンプリングで一様に抽出した N 件を保持します。メモリ使用量は O(N) で上限があります。
+	RetainSample
+)
+
+// RetentionPolicy はエントリの保持方針を表す構造体です。
+type RetentionPolicy struct {
+	// 保持方式
+	Mode RetentionMode
+	// 保持する最大件数 (RetainNone では無視されます)
+	Size int
+}
+
+// entryStore は保持方針に従ってエントリを保持するための構造体です。
+type entryStore struct {
+	// 保持方針
+	policy RetentionPolicy
+	// 保持しているエントリ
+	entries []models.LogEntry
+	// リングバッファの次の書き込み位置
+	next int
+	// これまでに受け取ったエントリ数
+	seen int
+	// リザーバサンプリング用の乱数生成器
+	rng *rand.Rand
+}
+
+// newEntryStore は保持方針に従った entryStore を作成します。
+func newEntryStore(policy RetentionPolicy) *entryStore {
+	// 件数が指定されていない場合は保持しない
+	if policy.Size <= 0 {
+		policy.Mode = RetainNone
+	}
+
+	store := &entryStore{policy: policy}
+	if policy.Mode == RetainSample {
+		store.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	return store
+}
+
+// add は保持方針に従ってエントリを記録します。
+func (es *entryStore) add(entry models.LogEntry) {
+	es.seen++
+
+	switch es.policy.Mode {
+	case RetainLast:
+		// 上限に達するまでは追加し、以降は古いものを上書きする
+		if len(es.entries) < es.policy.Size {
+			es.entries = append(es.entries, entry)
+		} else {
+			es.entries[es.next] = entry
+		}
+		es.next = (es.next + 1) % es.policy.Size
+	case RetainSample:
+		// Algorithm R: i 番目のエントリを Size/i の確率で採用する
+		if len(es.entries) < es.policy.Size {
+			es.entries = append(es.entries, entry)
+		} else if j := es.rng.IntN(es.seen); j < es.policy.Size {
+			es.entries[j] = entry
+		}
+	}
+}
+
+// list は保持しているエントリのコピーを返します。RetainLast の場合は古い順に並べます。
+func (es *entryStore) list() []models.LogEntry {
+	entries := make([]models.LogEntry, 0, len(es.entries))
+
+	if es.policy.Mode == RetainLast && len(es.entries) == es.policy.Size {
+		entries = append(entries, es.entries[es.next:]...)
+		entries = append(entries, es.entries[:es.next]...)
+		return entries
+	}
+
+	return append(entries, es.entries...)
+}
+
+// reset は保持しているエントリを破棄します。
+func (es *entryStore) reset() {
+	es.entries = nil
+	es.next = 0
+	es.seen = 0
+}
@@ -0,0 +1,88 @@
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestLogAggregator_RetainNone はエントリを保持しない場合も統計情報が正しいことをテストします。
+func TestLogAggregator_RetainNone(t *testing.T) {
+	// LogAggregator のインスタンスを作成
+	aggregator := NewLogAggregator()
+
+	// エントリを追加
+	for i := 0; i < 100; i++ {
+		aggregator.Add(models.LogEntry{Timestamp: time.Now(), Level: "INFO", Message: "message"})
+	}
+
+	// エントリが保持されていないことの検証
+	if len(aggregator.Entries()) != 0 {
+		t.Errorf("保持されるエントリ数は 0 であるべきですが、実際の値は %d です", len(aggregator.Entries()))
+	}
+
+	// 統計情報の検証
+	if aggregator.GetStats().TotalCount != 100 {
+		t.Errorf("期待される総ログ数は 100 ですが、実際の値は %d です", aggregator.GetStats().TotalCount)
+	}
+}
+
+// TestLogAggregator_RetainLast は直近 N 件が古い順に保持されることをテストします。
+func TestLogAggregator_RetainLast(t *testing.T) {
+	// LogAggregator のインスタンスを作成
+	aggregator := NewLogAggregatorWithRetention(RetentionPolicy{Mode: RetainLast, Size: 3})
+
+	// エントリを追加
+	for i := 0; i < 10; i++ {
+		aggregator.Add(models.LogEntry{Level: "INFO", Message: fmt.Sprintf("message %d", i)})
+	}
+
+	entries := aggregator.Entries()
+
+	t.Logf("保持されたエントリ: %+v", entries)
+
+	// 保持されたエントリの検証
+	expected := []string{"message 7", "message 8", "message 9"}
+	if len(entries) != len(expected) {
+		t.Fatalf("期待されるエントリ数は %d ですが、実際の値は %d です", len(expected), len(entries))
+	}
+	for i, message := range expected {
+		if entries[i].Message != message {
+			t.Errorf("%d 番目のエントリが期待値と異なります。期待値: %s, 実際: %s", i, message, entries[i].Message)
+		}
+	}
+
+	// 統計情報の検証
+	if aggregator.GetStats().TotalCount != 10 {
+		t.Errorf("期待される総ログ数は 10 ですが、実際の値は %d です", aggregator.GetStats().TotalCount)
+	}
+}
+
+// TestLogAggregator_RetainSample はリザーバサンプリングで件数が上限を超えないことをテストします。
+func TestLogAggregator_RetainSample(t *testing.T) {
+	// LogAggregator のインスタンスを作成
+	aggregator := NewLogAggregatorWithRetention(RetentionPolicy{Mode: RetainSample, Size: 5})
+
+	// エントリを追加
+	for i := 0; i < 1000; i++ {
+		aggregator.Add(models.LogEntry{Level: "WARN", Message: fmt.Sprintf("message %d", i)})
+	}
+
+	// 保持されたエントリの検証
+	if len(aggregator.Entries()) != 5 {
+		t.Errorf("期待されるエントリ数は 5 ですが、実際の値は %d です", len(aggregator.Entries()))
+	}
+
+	// 統計情報の検証
+	if aggregator.GetStats().WarnCount != 1000 {
+		t.Errorf("期待されるWARNログ数は 1000 ですが、実際の値は %d です", aggregator.GetStats().WarnCount)
+	}
+
+	// リセット後の検証
+	aggregator.Reset()
+	if len(aggregator.Entries()) != 0 {
+		t.Errorf("リセット後のエントリ数は 0 であるべきですが、実際の値は %d です", len(aggregator.Entries()))
+	}
+}
@@ -0,0 +1,44 @@
+package aggregator
+
+import "github.com/Yamituki/go-review-logagg/pkg/models"
+
+// RuleCounter はルール名のタグを持つエントリをルールごとに数える拡張です。
+type RuleCounter struct {
+	// ルールごとのログ数 (一致がないルールも 0 件として含む)
+	counts map[string]int
+}
+
+// NewRuleCounter は指定したルール名を数える RuleCounter を作成します。
+func NewRuleCounter(names ...string) *RuleCounter {
+	rc := &RuleCounter{counts: make(map[string]int, len(names))}
+	for _, name := range names {
+		rc.counts[name] = 0
+	}
+	return rc
+}
+
+// Add はエントリのタグのうち、対象のルール名の件数を加算します。
+func (rc *RuleCounter) Add(entry models.LogEntry) {
+	for _, tag := range entry.Tags {
+		if _, ok := rc.counts[tag]; ok {
+			rc.counts[tag] += entry.Count()
+		}
+	}
+}
+
+// Apply はルールごとのログ数を統計情報に書き込みます。
+func (rc *RuleCounter) Apply(stats *models.Stats) {
+	if stats.Rules == nil {
+		stats.Rules = make(map[string]int, len(rc.counts))
+	}
+	for name, count := range rc.counts {
+		stats.Rules[name] = count
+	}
+}
+
+// Reset はすべてのルールの件数を 0 に戻します。
+func (rc *RuleCounter) Reset() {
+	for name := range rc.counts {
+		rc.counts[name] = 0
+	}
+}
@@ -0,0 +1,45 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestRuleCounter はルールごとのログ数と、MergeStats でのルールごと・マスキングの種類ごとの件数の統合をテストします。
+func TestRuleCounter(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// ワーカーごとにルールの一致を数える
+	workers := []*LogAggregator{NewLogAggregator(), NewLogAggregator()}
+	for _, worker := range workers {
+		worker.Attach(NewRuleCounter("timeout", "auth"))
+	}
+	workers[0].Add(models.LogEntry{Timestamp: base, Level: "ERROR", Message: "request timeout", Tags: []string{"timeout", "unknown"}})
+	workers[0].Add(models.LogEntry{Timestamp: base, Level: "ERROR", Message: "request timeout", Tags: []string{"timeout"}, Repeat: 3})
+	workers[1].Add(models.LogEntry{Timestamp: base, Level: "WARN", Message: "login failed", Tags: []string{"auth"}})
+
+	first := workers[0].GetStats()
+	first.Redactions = map[string]int{"email": 2}
+	second := workers[1].GetStats()
+	second.Redactions = map[string]int{"email": 1, "ipv4": 4}
+
+	// 一致がないルールも 0 件として含む
+	if first.Rules["timeout"] != 4 || first.Rules["auth"] != 0 || len(first.Rules) != 2 {
+		t.Errorf("ルールごとのログ数が期待値と異なります: %+v", first.Rules)
+	}
+
+	stats := MergeStats(first, second)
+	if stats.Rules["timeout"] != 4 || stats.Rules["auth"] != 1 {
+		t.Errorf("統合したルールごとのログ数が期待値と異なります: %+v", stats.Rules)
+	}
+	if stats.Redactions["email"] != 3 || stats.Redactions["ipv4"] != 4 {
+		t.Errorf("統合したマスキングの件数が期待値と異なります: %+v", stats.Redactions)
+	}
+
+	// 両方とも空の場合は nil のまま
+	if merged := MergeStats(models.Stats{}, models.Stats{}); merged.Rules != nil || merged.Redactions != nil {
+		t.Errorf("空の統計情報の統合結果が期待値と異なります: %+v", merged)
+	}
+}
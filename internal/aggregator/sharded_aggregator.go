@@ -0,0 +1,104 @@
+package aggregator
+
+/*
+ * runtime パッケージは Go ランタイムの情報を提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * sync/atomic パッケージはアトミックな操作を提供します。
+ */
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// aggregatorShard はロックで保護された1つのシャードです。
+type aggregatorShard struct {
+	// シャードを保護するミューテックス
+	mutex sync.Mutex
+	// シャードの集約器
+	aggregator *LogAggregator
+	// 隣接シャードとのフォールスシェアリングを避けるためのパディング
+	_ [64]byte
+}
+
+// ShardedAggregator は複数のゴルーチンから同時に Add できるロック分割型の集約器です。
+type ShardedAggregator struct {
+	// シャード一覧
+	shards []aggregatorShard
+	// 次に使用するシャードを決めるカウンタ
+	next atomic.Uint64
+}
+
+// NewShardedAggregator は指定したシャード数で ShardedAggregator の新しいインスタンスを作成します。
+// シャード数が0以下の場合は GOMAXPROCS を使用します。
+func NewShardedAggregator(shards int) *ShardedAggregator {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	sa := &ShardedAggregator{
+		shards: make([]aggregatorShard, shards),
+	}
+	for i := range sa.shards {
+		sa.shards[i].aggregator = NewLogAggregator()
+	}
+
+	return sa
+}
+
+// Attach は各シャードに拡張を付加します。拡張はシャードごとに factory で作成されます。
+func (sa *ShardedAggregator) Attach(factory func() StatsExtension) {
+	for i := range sa.shards {
+		sa.shards[i].mutex.Lock()
+		sa.shards[i].aggregator.Attach(factory())
+		sa.shards[i].mutex.Unlock()
+	}
+}
+
+// Add は1つのログエントリをいずれかのシャードに追加します。
+func (sa *ShardedAggregator) Add(entry models.LogEntry) error {
+	// シャードを順番に割り当ててロックの競合を分散する
+	shard := &sa.shards[sa.next.Add(1)%uint64(len(sa.shards))]
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	return shard.aggregator.Add(entry)
+}
+
+// GetStats はすべてのシャードを統合した統計情報を取得します。
+// すべてのシャードをロックした状態で統合するため、ある時点の一貫したスナップショットになります。
+func (sa *ShardedAggregator) GetStats() models.Stats {
+	// シャードの順序でロックを取得してデッドロックを避ける
+	for i := range sa.shards {
+		sa.shards[i].mutex.Lock()
+	}
+
+	var stats models.Stats
+	for i := range sa.shards {
+		stats = MergeStats(stats, sa.shards[i].aggregator.GetStats())
+	}
+
+	for i := range sa.shards {
+		sa.shards[i].mutex.Unlock()
+	}
+
+	return stats
+}
+
+// Reset はすべてのシャードの統計情報をリセットします。
+func (sa *ShardedAggregator) Reset() {
+	for i := range sa.shards {
+		sa.shards[i].mutex.Lock()
+	}
+
+	for i := range sa.shards {
+		sa.shards[i].aggregator.Reset()
+	}
+
+	for i := range sa.shards {
+		sa.shards[i].mutex.Unlock()
+	}
+}
@@ -0,0 +1,104 @@
+package aggregator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestShardedAggregator_ConcurrentAdd は複数のゴルーチンから同時に Add した結果が正しいことをテストします。
+func TestShardedAggregator_ConcurrentAdd(t *testing.T) {
+	// ShardedAggregator のインスタンスを作成
+	aggregator := NewShardedAggregator(4)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	levels := []string{"INFO", "WARN", "ERROR"}
+
+	// 8つのゴルーチンからそれぞれ300件追加
+	var waitGroup sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		waitGroup.Add(1)
+		go func(w int) {
+			defer waitGroup.Done()
+			for i := 0; i < 300; i++ {
+				entry := models.LogEntry{
+					Timestamp: base.Add(time.Duration(w*300+i) * time.Second),
+					Level:     levels[i%3],
+				}
+				if err := aggregator.Add(entry); err != nil {
+					t.Errorf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+				}
+			}
+		}(w)
+	}
+	waitGroup.Wait()
+
+	// 統計情報を取得
+	stats := aggregator.GetStats()
+
+	t.Logf("取得した統計情報: %+v", stats)
+
+	// 統計情報の検証
+	if stats.TotalCount != 2400 {
+		t.Errorf("期待される総ログ数は 2400 ですが、実際の値は %d です", stats.TotalCount)
+	}
+	if stats.InfoCount != 800 || stats.WarnCount != 800 || stats.ErrorCount != 800 {
+		t.Errorf("レベル別のログ数が期待値と異なります: %+v", stats)
+	}
+	if !stats.FirstTimestamp.Equal(base) {
+		t.Errorf("期待される最初のタイムスタンプは %v ですが、実際の値は %v です", base, stats.FirstTimestamp)
+	}
+	if expected := base.Add(2399 * time.Second); !stats.LastTimestamp.Equal(expected) {
+		t.Errorf("期待される最後のタイムスタンプは %v ですが、実際の値は %v です", expected, stats.LastTimestamp)
+	}
+
+	// リセット後の検証
+	aggregator.Reset()
+	if aggregator.GetStats().TotalCount != 0 {
+		t.Errorf("リセット後の総ログ数は 0 であるべきですが、実際の値は %d です", aggregator.GetStats().TotalCount)
+	}
+}
+
+// BenchmarkShardedAggregator_Add は共有の ShardedAggregator に並行して Add する性能を計測します。
+func BenchmarkShardedAggregator_Add(b *testing.B) {
+	aggregator := NewShardedAggregator(0)
+	entry := models.LogEntry{Timestamp: time.Now(), Level: "INFO", Message: "benchmark"}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			aggregator.Add(entry)
+		}
+	})
+
+	b.StopTimer()
+	if aggregator.GetStats().TotalCount != b.N {
+		b.Fatalf("期待される総ログ数は %d ですが、実際の値は %d です", b.N, aggregator.GetStats().TotalCount)
+	}
+}
+
+// BenchmarkPerWorkerAggregator_Merge はワーカーごとの LogAggregator に Add し、最後に統合する性能を計測します。
+func BenchmarkPerWorkerAggregator_Merge(b *testing.B) {
+	entry := models.LogEntry{Timestamp: time.Now(), Level: "INFO", Message: "benchmark"}
+
+	var mutex sync.Mutex
+	var stats models.Stats
+
+	b.RunParallel(func(pb *testing.PB) {
+		aggregator := NewLogAggregator()
+		for pb.Next() {
+			aggregator.Add(entry)
+		}
+
+		// ワーカー終了時に統合
+		mutex.Lock()
+		stats = MergeStats(stats, aggregator.GetStats())
+		mutex.Unlock()
+	})
+
+	b.StopTimer()
+	if stats.TotalCount != b.N {
+		b.Fatalf("期待される総ログ数は %d ですが、実際の値は %d です", b.N, stats.TotalCount)
+	}
+}
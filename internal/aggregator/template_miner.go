@@ -0,0 +1,343 @@
+package aggregator
+
+/*
+ * sort パッケージはスライスのソートを提供します。
+ * strings パッケージは文字列操作を提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * unicode パッケージは文字の種類の判定を提供します。
+ */
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// wildcard はテンプレート中の可変部分を表すトークンです。
+const wildcard = "<*>"
+
+// TemplateMinerConfig はテンプレートマイナーの設定を表す構造体です。
+type TemplateMinerConfig struct {
+	// 解析木の深さ (ルートとトークン数の層を含む)
+	Depth int
+	// 同じテンプレートとみなす類似度の閾値 (0.0〜1.0)
+	SimilarityThreshold float64
+	// 1つのノードが持てる子ノードの最大数
+	MaxChildren int
+	// テンプレートごとに保持するサンプルの最大数
+	MaxSamples int
+}
+
+// DefaultTemplateMinerConfig はテンプレートマイナーの既定の設定を返します。
+func DefaultTemplateMinerConfig() TemplateMinerConfig {
+	return TemplateMinerConfig{
+		Depth:               4,
+		SimilarityThreshold: 0.4,
+		MaxChildren:         100,
+		MaxSamples:          3,
+	}
+}
+
+// templateCluster は1つのテンプレートに属するログの集まりです。
+type templateCluster struct {
+	// テンプレートの識別子
+	id int
+	// テンプレートのトークン列
+	tokens []string
+	// 一致したログ数
+	count int
+	// レベル別のログ数
+	levelCounts map[string]int
+	// サンプルのログ
+	samples []models.LogEntry
+}
+
+// parseNode は固定深さの解析木のノードです。
+type parseNode struct {
+	// 子ノード (キーはトークン)
+	children map[string]*parseNode
+	// 葉ノードが保持するクラスタ
+	clusters []*templateCluster
+}
+
+// TemplateMiner は Drain 方式の固定深さ解析木でメッセージをテンプレートに分類する集約器です。
+type TemplateMiner struct {
+	// 設定
+	config TemplateMinerConfig
+	// トークン数ごとの解析木のルート
+	root map[int]*parseNode
+	// 作成順のクラスタ一覧
+	clusters []*templateCluster
+	// 全体の統計情報
+	base *LogAggregator
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// NewTemplateMiner は既定の設定で TemplateMiner の新しいインスタンスを作成します。
+func NewTemplateMiner() *TemplateMiner {
+	return NewTemplateMinerWithConfig(DefaultTemplateMinerConfig())
+}
+
+// NewTemplateMinerWithConfig は指定した設定で TemplateMiner の新しいインスタンスを作成します。
+func NewTemplateMinerWithConfig(config TemplateMinerConfig) *TemplateMiner {
+	// 不正な設定値は既定値で補う
+	defaults := DefaultTemplateMinerConfig()
+	if config.Depth < 3 {
+		config.Depth = defaults.Depth
+	}
+	if config.SimilarityThreshold <= 0 || config.SimilarityThreshold > 1 {
+		config.SimilarityThreshold = defaults.SimilarityThreshold
+	}
+	if config.MaxChildren <= 0 {
+		config.MaxChildren = defaults.MaxChildren
+	}
+	if config.MaxSamples < 0 {
+		config.MaxSamples = defaults.MaxSamples
+	}
+
+	return &TemplateMiner{
+		config: config,
+		root:   make(map[int]*parseNode),
+		base:   NewLogAggregator(),
+	}
+}
+
+// Add は1つのログエントリをテンプレートに分類して追加します。
+func (tm *TemplateMiner) Add(entry models.LogEntry) error {
+	tm.Classify(entry)
+	return nil
+}
+
+// Classify は1つのログエントリをテンプレートに分類して追加し、分類したテンプレートの識別子を返します。
+// 追加した後に Match で探し直す必要はありません。
+func (tm *TemplateMiner) Classify(entry models.LogEntry) int {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	// 全体の統計情報の更新
+	tm.base.Add(entry)
+
+	// メッセージのトークン化
+	tokens := strings.Fields(entry.Message)
+
+	// 葉ノードの探索と最も近いクラスタの検索
+	leaf := tm.findLeaf(tokens)
+	cluster := tm.findCluster(leaf, tokens)
+
+	if cluster == nil {
+		// 新しいクラスタの作成
+		cluster = &templateCluster{
+			id:          len(tm.clusters) + 1,
+			tokens:      append([]string(nil), tokens...),
+			levelCounts: make(map[string]int),
+		}
+		leaf.clusters = append(leaf.clusters, cluster)
+		tm.clusters = append(tm.clusters, cluster)
+	} else {
+		// 異なるトークンをワイルドカードに置き換える
+		for i, token := range tokens {
+			if cluster.tokens[i] != token {
+				cluster.tokens[i] = wildcard
+			}
+		}
+	}
+
+	// クラスタの集計の更新
+	cluster.count += entry.Count()
+	cluster.levelCounts[entry.Level] += entry.Count()
+	if len(cluster.samples) < tm.config.MaxSamples {
+		cluster.samples = append(cluster.samples, entry)
+	}
+
+	return cluster.id
+}
+
+// GetStats は追加されたすべてのログの統計情報を取得します。
+func (tm *TemplateMiner) GetStats() models.Stats {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	return tm.base.GetStats()
+}
+
+// Reset はテンプレートと統計情報をリセットします。
+func (tm *TemplateMiner) Reset() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.root = make(map[int]*parseNode)
+	tm.clusters = nil
+	tm.base.Reset()
+}
+
+// Templates は件数の多い順にテンプレートの一覧を返します。
+func (tm *TemplateMiner) Templates() []models.LogTemplate {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	templates := make([]models.LogTemplate, 0, len(tm.clusters))
+	for _, cluster := range tm.clusters {
+		templates = append(templates, cluster.toModel())
+	}
+
+	// 件数の降順、同数の場合は作成順に並べる
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].Count > templates[j].Count
+	})
+
+	return templates
+}
+
+// Template は指定した識別子のテンプレートを返します。
+func (tm *TemplateMiner) Template(id int) (models.LogTemplate, bool) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if id < 1 || id > len(tm.clusters) {
+		return models.LogTemplate{}, false
+	}
+
+	return tm.clusters[id-1].toModel(), true
+}
+
+// Match はメッセージが属するテンプレートを、木を更新せずに返します。
+func (tm *TemplateMiner) Match(message string) (models.LogTemplate, bool) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tokens := strings.Fields(message)
+
+	// トークン数の層から順に辿る
+	node, ok := tm.root[len(tokens)]
+	if !ok {
+		return models.LogTemplate{}, false
+	}
+	for depth := 0; depth < tm.config.Depth-2 && depth < len(tokens); depth++ {
+		next, ok := node.children[treeKey(tokens[depth])]
+		if !ok {
+			next, ok = node.children[wildcard]
+			if !ok {
+				return models.LogTemplate{}, false
+			}
+		}
+		node = next
+	}
+
+	cluster := tm.findCluster(node, tokens)
+	if cluster == nil {
+		return models.LogTemplate{}, false
+	}
+
+	return cluster.toModel(), true
+}
+
+// findLeaf はトークン列に対応する葉ノードを探索し、存在しない場合は作成します。
+func (tm *TemplateMiner) findLeaf(tokens []string) *parseNode {
+	// 第1層: トークン数
+	node, ok := tm.root[len(tokens)]
+	if !ok {
+		node = newParseNode()
+		tm.root[len(tokens)] = node
+	}
+
+	// 第2層以降: 先頭からのトークン
+	for depth := 0; depth < tm.config.Depth-2 && depth < len(tokens); depth++ {
+		key := treeKey(tokens[depth])
+
+		next, ok := node.children[key]
+		if !ok {
+			// 子ノードが上限に達した場合はワイルドカードのノードにまとめる
+			if len(node.children) >= tm.config.MaxChildren-1 {
+				key = wildcard
+				next, ok = node.children[key]
+			}
+			if !ok {
+				next = newParseNode()
+				node.children[key] = next
+			}
+		}
+		node = next
+	}
+
+	return node
+}
+
+// findCluster は葉ノードのクラスタのうち、類似度が閾値以上で最も高いものを返します。
+func (tm *TemplateMiner) findCluster(leaf *parseNode, tokens []string) *templateCluster {
+	var best *templateCluster
+	bestSimilarity := -1.0
+	bestWildcards := -1
+
+	for _, cluster := range leaf.clusters {
+		similarity, wildcards := similarity(cluster.tokens, tokens)
+
+		// 類似度が同じ場合はワイルドカードの多いものを優先する
+		if similarity > bestSimilarity || (similarity == bestSimilarity && wildcards > bestWildcards) {
+			best = cluster
+			bestSimilarity = similarity
+			bestWildcards = wildcards
+		}
+	}
+
+	if best == nil || bestSimilarity < tm.config.SimilarityThreshold {
+		return nil
+	}
+
+	return best
+}
+
+// newParseNode は空の解析木ノードを作成します。
+func newParseNode() *parseNode {
+	return &parseNode{children: make(map[string]*parseNode)}
+}
+
+// similarity はテンプレートとトークン列の一致率とワイルドカード数を返します。
+func similarity(template, tokens []string) (float64, int) {
+	// トークンのないメッセージ同士は完全一致とみなす
+	if len(tokens) == 0 {
+		return 1.0, 0
+	}
+
+	matches := 0
+	wildcards := 0
+	for i, token := range template {
+		if token == wildcard {
+			wildcards++
+			continue
+		}
+		if token == tokens[i] {
+			matches++
+		}
+	}
+
+	return float64(matches) / float64(len(tokens)), wildcards
+}
+
+// treeKey は解析木の探索に使うキーを返します。数字を含むトークンは可変部分とみなします。
+func treeKey(token string) string {
+	for _, r := range token {
+		if unicode.IsDigit(r) {
+			return wildcard
+		}
+	}
+	return token
+}
+
+// toModel はクラスタを公開用のモデルに変換します。
+func (tc *templateCluster) toModel() models.LogTemplate {
+	levelCounts := make(map[string]int, len(tc.levelCounts))
+	for level, count := range tc.levelCounts {
+		levelCounts[level] = count
+	}
+
+	return models.LogTemplate{
+		ID:          tc.id,
+		Template:    strings.Join(tc.tokens, " "),
+		Count:       tc.count,
+		LevelCounts: levelCounts,
+		Samples:     append([]models.LogEntry(nil), tc.samples...),
+	}
+}
@@ -0,0 +1,111 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestTemplateMiner_Add_Clustering は TemplateMiner が類似メッセージを同じテンプレートにまとめることをテストします。
+func TestTemplateMiner_Add_Clustering(t *testing.T) {
+	// TemplateMiner のインスタンスを作成
+	miner := NewTemplateMiner()
+
+	// 複数のログエントリを追加
+	entries := []models.LogEntry{
+		{Timestamp: time.Now(), Level: "INFO", Message: "login succeeded for alice from 10.0.0.1"},
+		{Timestamp: time.Now(), Level: "INFO", Message: "login succeeded for bob from 10.0.0.2"},
+		{Timestamp: time.Now(), Level: "WARN", Message: "login succeeded for carol from 10.0.0.3"},
+		{Timestamp: time.Now(), Level: "ERROR", Message: "connection to db-1 refused"},
+	}
+
+	for _, entry := range entries {
+		if err := miner.Add(entry); err != nil {
+			t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+		}
+	}
+
+	// テンプレート一覧を取得
+	templates := miner.Templates()
+
+	t.Logf("取得したテンプレート: %+v", templates)
+
+	// テンプレートの検証
+	if len(templates) != 2 {
+		t.Fatalf("期待されるテンプレート数は 2 ですが、実際の値は %d です", len(templates))
+	}
+
+	expectedTemplate := "login succeeded for <*> from <*>"
+	if templates[0].Template != expectedTemplate {
+		t.Errorf("期待されるテンプレートは %q ですが、実際の値は %q です", expectedTemplate, templates[0].Template)
+	}
+
+	if templates[0].Count != 3 {
+		t.Errorf("期待される件数は 3 ですが、実際の値は %d です", templates[0].Count)
+	}
+
+	if templates[0].LevelCounts["INFO"] != 2 || templates[0].LevelCounts["WARN"] != 1 {
+		t.Errorf("レベル別の件数が期待値と異なります: %v", templates[0].LevelCounts)
+	}
+
+	if len(templates[0].Samples) != 3 {
+		t.Errorf("期待されるサンプル数は 3 ですが、実際の値は %d です", len(templates[0].Samples))
+	}
+
+	// 全体の統計情報の検証
+	stats := miner.GetStats()
+	if stats.TotalCount != 4 {
+		t.Errorf("期待される総ログ数は 4 ですが、実際の値は %d です", stats.TotalCount)
+	}
+}
+
+// TestTemplateMiner_Match は TemplateMiner の Match メソッドが既存のテンプレートを返すことをテストします。
+func TestTemplateMiner_Match(t *testing.T) {
+	// TemplateMiner のインスタンスを作成
+	miner := NewTemplateMiner()
+
+	miner.Add(models.LogEntry{Level: "ERROR", Message: "request 123 failed after 5 retries"})
+	miner.Add(models.LogEntry{Level: "ERROR", Message: "request 456 failed after 3 retries"})
+
+	// 一致するメッセージ
+	template, ok := miner.Match("request 789 failed after 1 retries")
+	if !ok {
+		t.Fatalf("テンプレートが見つかるはずですが、見つかりませんでした")
+	}
+
+	t.Logf("一致したテンプレート: %+v", template)
+
+	if template.Template != "request <*> failed after <*> retries" {
+		t.Errorf("テンプレートが期待値と異なります: %q", template.Template)
+	}
+
+	// 一致しないメッセージ
+	if _, ok := miner.Match("completely different message"); ok {
+		t.Errorf("テンプレートが見つからないはずですが、見つかりました")
+	}
+
+	// Match は木を更新しない
+	if len(miner.Templates()) != 1 {
+		t.Errorf("期待されるテンプレート数は 1 ですが、実際の値は %d です", len(miner.Templates()))
+	}
+}
+
+// TestTemplateMiner_Reset は TemplateMiner の Reset メソッドをテストします。
+func TestTemplateMiner_Reset(t *testing.T) {
+	// TemplateMiner のインスタンスを作成
+	miner := NewTemplateMiner()
+
+	miner.Add(models.LogEntry{Level: "INFO", Message: "service started"})
+
+	// Reset メソッドを呼び出し
+	miner.Reset()
+
+	if len(miner.Templates()) != 0 {
+		t.Errorf("リセット後のテンプレート数は 0 であるべきですが、実際の値は %d です", len(miner.Templates()))
+	}
+
+	if miner.GetStats().TotalCount != 0 {
+		t.Errorf("リセット後の総ログ数は 0 であるべきですが、実際の値は %d です", miner.GetStats().TotalCount)
+	}
+}
@@ -0,0 +1,272 @@
+package aggregator
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// noBucket は未使用のバケットを表すバケット番号です。
+const noBucket int64 = -1 << 62
+
+const (
+	// MaxWindowBuckets はスライディングウィンドウのバケット数 (長さ / 分解能) の上限です。
+	MaxWindowBuckets = 10000
+	// MinWindowResolution はスライディングウィンドウの時間分解能の下限です。
+	MinWindowResolution = time.Millisecond
+)
+
+// TimeSource はスライディングウィンドウの現在時刻の決め方を表す型です。
+type TimeSource int
+
+const (
+	// EventTime はエントリのタイムスタンプの最大値を現在時刻とします (過去ログの再生向け)。
+	EventTime TimeSource = iota
+	// WallClock は実際の時計を現在時刻とします (リアルタイム監視向け)。
+	WallClock
+)
+
+// windowBucket は1つの時間分解能ぶんのログ数を保持するバケットです。
+type windowBucket struct {
+	// バケットの番号 (時刻 / 分解能)
+	index int64
+	// レベル別ログ数
+	counts map[string]int
+	// バケット内の最初のログ時刻
+	first time.Time
+	// バケット内の最後のログ時刻
+	last time.Time
+}
+
+// SlidingWindowAggregator は直近の一定時間に含まれるログだけを集約する集約器です。
+type SlidingWindowAggregator struct {
+	// ウィンドウの長さ
+	window time.Duration
+	// バケットの時間分解能
+	resolution time.Duration
+	// 現在時刻の決め方
+	source TimeSource
+	// 現在時刻を返す関数 (WallClock で使用)
+	now func() time.Time
+	// リングバッファとして使うバケット一覧
+	buckets []windowBucket
+	// これまでに観測した最新のバケット番号 (EventTime で使用)
+	head int64
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// CheckWindow はスライディングウィンドウの長さと時間分解能を検証します。
+// 分解能が下限より細かい場合や、バケット数が上限を超える場合はエラーを返します。
+func CheckWindow(window, resolution time.Duration) error {
+	if resolution < MinWindowResolution {
+		return fmt.Errorf("ウィンドウの時間分解能は %v 以上で指定してください: %v", MinWindowResolution, resolution)
+	}
+	if buckets := bucketCount(window, resolution); buckets > MaxWindowBuckets {
+		return fmt.Errorf("ウィンドウのバケット数 (長さ / 時間分解能) は %d 以下にしてください: %d", MaxWindowBuckets, buckets)
+	}
+	return nil
+}
+
+// bucketCount は長さを分解能で割って切り上げた数を返します (長さが上限に近い場合もあふれないように計算します)。
+func bucketCount(window, resolution time.Duration) int64 {
+	count := int64(window / resolution)
+	if window%resolution != 0 {
+		count++
+	}
+	return count
+}
+
+// NewSlidingWindowAggregator は SlidingWindowAggregator の新しいインスタンスを作成します。
+// ウィンドウは分解能の倍数に切り上げられます。バケット数が上限を超える場合は、上限に収まるよう分解能を粗くします。
+func NewSlidingWindowAggregator(window, resolution time.Duration, source TimeSource) *SlidingWindowAggregator {
+	// 不正な値の補正
+	if resolution <= 0 {
+		resolution = time.Second
+	}
+	resolution = max(resolution, MinWindowResolution)
+	if window < resolution {
+		window = resolution
+	}
+	if bucketCount(window, resolution) > MaxWindowBuckets {
+		resolution = time.Duration(bucketCount(window, MaxWindowBuckets))
+	}
+
+	// バケット数の計算
+	size := int(bucketCount(window, resolution))
+
+	swa := &SlidingWindowAggregator{
+		window:     time.Duration(size) * resolution,
+		resolution: resolution,
+		source:     source,
+		now:        time.Now,
+		buckets:    make([]windowBucket, size),
+	}
+	swa.clear()
+
+	return swa
+}
+
+// Add は1つのログエントリをウィンドウに追加します。ウィンドウより古いエントリは無視されます。
+func (swa *SlidingWindowAggregator) Add(entry models.LogEntry) error {
+	swa.mutex.Lock()
+	defer swa.mutex.Unlock()
+
+	// エントリが属するバケット番号の決定
+	var index int64
+	if swa.source == WallClock {
+		index = swa.bucketIndex(swa.now())
+		swa.head = index
+	} else {
+		index = swa.bucketIndex(entry.Timestamp)
+		if index > swa.head {
+			swa.head = index
+		}
+	}
+
+	// ウィンドウより古いエントリは無視
+	if index <= swa.head-int64(len(swa.buckets)) {
+		return nil
+	}
+
+	// バケットの再利用
+	bucket := &swa.buckets[swa.slot(index)]
+	if bucket.index != index {
+		*bucket = windowBucket{index: index, counts: make(map[string]int)}
+	}
+
+	// バケットの集計の更新
+	bucket.counts[entry.Level] += entry.Count()
+	if bucket.first.IsZero() || entry.Timestamp.Before(bucket.first) {
+		bucket.first = entry.Timestamp
+	}
+	if entry.LastTimestamp().After(bucket.last) {
+		bucket.last = entry.LastTimestamp()
+	}
+
+	return nil
+}
+
+// GetStats はウィンドウ内のログの統計情報を取得します。
+func (swa *SlidingWindowAggregator) GetStats() models.Stats {
+	swa.mutex.Lock()
+	defer swa.mutex.Unlock()
+
+	var stats models.Stats
+	for _, bucket := range swa.activeBuckets() {
+		// バケットごとの統計情報を統合
+		var bucketStats models.Stats
+		for level, count := range bucket.counts {
+			bucketStats.TotalCount += count
+			switch level {
+			case "INFO":
+				bucketStats.InfoCount += count
+			case "WARN":
+				bucketStats.WarnCount += count
+			case "ERROR":
+				bucketStats.ErrorCount += count
+			}
+		}
+		bucketStats.FirstTimestamp = bucket.first
+		bucketStats.LastTimestamp = bucket.last
+
+		stats = MergeStats(stats, bucketStats)
+	}
+
+	return stats
+}
+
+// Reset はウィンドウ内のすべてのバケットを破棄します。
+func (swa *SlidingWindowAggregator) Reset() {
+	swa.mutex.Lock()
+	defer swa.mutex.Unlock()
+
+	swa.clear()
+}
+
+// Window はウィンドウ内のレベル別のログ数、1分あたりのレート、割合を返します。
+func (swa *SlidingWindowAggregator) Window() models.WindowStats {
+	swa.mutex.Lock()
+	defer swa.mutex.Unlock()
+
+	result := models.WindowStats{
+		Window:         swa.window,
+		LevelCounts:    make(map[string]int),
+		RatesPerMinute: make(map[string]float64),
+		Ratios:         make(map[string]float64),
+	}
+
+	// ウィンドウの範囲 (エントリ未観測の EventTime では空のまま)
+	if current := swa.currentIndex(); current != noBucket {
+		result.End = time.Unix(0, (current+1)*int64(swa.resolution)).UTC()
+		result.Start = result.End.Add(-swa.window)
+	}
+
+	// レベル別ログ数の集計
+	for _, bucket := range swa.activeBuckets() {
+		for level, count := range bucket.counts {
+			result.LevelCounts[level] += count
+			result.TotalCount += count
+		}
+	}
+
+	// レートと割合の計算
+	minutes := swa.window.Minutes()
+	for level, count := range result.LevelCounts {
+		result.RatesPerMinute[level] = float64(count) / minutes
+		result.Ratios[level] = float64(count) / float64(result.TotalCount)
+	}
+
+	return result
+}
+
+// activeBuckets は現在のウィンドウに含まれるバケットを返します。
+func (swa *SlidingWindowAggregator) activeBuckets() []windowBucket {
+	current := swa.currentIndex()
+
+	var buckets []windowBucket
+	for _, bucket := range swa.buckets {
+		if bucket.index != noBucket && bucket.index > current-int64(len(swa.buckets)) && bucket.index <= current {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets
+}
+
+// currentIndex は現在時刻のバケット番号を返します。
+func (swa *SlidingWindowAggregator) currentIndex() int64 {
+	if swa.source == WallClock {
+		return swa.bucketIndex(swa.now())
+	}
+	return swa.head
+}
+
+// bucketIndex は時刻をバケット番号に変換します。
+func (swa *SlidingWindowAggregator) bucketIndex(t time.Time) int64 {
+	return t.UnixNano() / int64(swa.resolution)
+}
+
+// slot はバケット番号に対応するリングバッファの位置を返します。
+func (swa *SlidingWindowAggregator) slot(index int64) int {
+	slot := index % int64(len(swa.buckets))
+	if slot < 0 {
+		slot += int64(len(swa.buckets))
+	}
+	return int(slot)
+}
+
+// clear はすべてのバケットを未使用の状態に戻します。
+func (swa *SlidingWindowAggregator) clear() {
+	for i := range swa.buckets {
+		swa.buckets[i] = windowBucket{index: noBucket}
+	}
+	swa.head = noBucket
+}
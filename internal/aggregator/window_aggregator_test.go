@@ -0,0 +1,145 @@
+package aggregator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestSlidingWindowAggregator_EventTime はエントリのタイムスタンプでウィンドウが進むことをテストします。
+func TestSlidingWindowAggregator_EventTime(t *testing.T) {
+	// 5分のウィンドウを1分の分解能で作成
+	aggregator := NewSlidingWindowAggregator(5*time.Minute, time.Minute, EventTime)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 12:00〜12:09 に毎分 INFO を1件、12:07〜12:09 に ERROR を2件ずつ追加
+	for i := 0; i < 10; i++ {
+		aggregator.Add(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Minute), Level: "INFO"})
+		if i >= 7 {
+			aggregator.Add(models.LogEntry{Timestamp: base.Add(time.Duration(i)*time.Minute + time.Second), Level: "ERROR"})
+			aggregator.Add(models.LogEntry{Timestamp: base.Add(time.Duration(i)*time.Minute + 2*time.Second), Level: "ERROR"})
+		}
+	}
+
+	// ウィンドウより古いエントリは無視される
+	aggregator.Add(models.LogEntry{Timestamp: base, Level: "ERROR"})
+
+	window := aggregator.Window()
+
+	t.Logf("取得したウィンドウ統計: %+v", window)
+
+	// 12:05〜12:09 の5分間が対象
+	if window.LevelCounts["INFO"] != 5 {
+		t.Errorf("期待されるINFOログ数は 5 ですが、実際の値は %d です", window.LevelCounts["INFO"])
+	}
+	if window.LevelCounts["ERROR"] != 6 {
+		t.Errorf("期待されるERRORログ数は 6 ですが、実際の値は %d です", window.LevelCounts["ERROR"])
+	}
+
+	// 1分あたりのERROR数と割合
+	if rate := window.RatesPerMinute["ERROR"]; math.Abs(rate-1.2) > 1e-9 {
+		t.Errorf("期待されるERRORレートは 1.2 ですが、実際の値は %f です", rate)
+	}
+	if ratio := window.Ratios["ERROR"]; math.Abs(ratio-6.0/11.0) > 1e-9 {
+		t.Errorf("期待されるERROR割合は %f ですが、実際の値は %f です", 6.0/11.0, ratio)
+	}
+
+	// ウィンドウの範囲
+	if !window.Start.Equal(base.Add(5*time.Minute)) || !window.End.Equal(base.Add(10*time.Minute)) {
+		t.Errorf("ウィンドウの範囲が期待値と異なります: %v - %v", window.Start, window.End)
+	}
+
+	// GetStats もウィンドウ内のみを対象とする
+	stats := aggregator.GetStats()
+	if stats.TotalCount != 11 || stats.ErrorCount != 6 {
+		t.Errorf("統計情報が期待値と異なります: %+v", stats)
+	}
+	if !stats.FirstTimestamp.Equal(base.Add(5 * time.Minute)) {
+		t.Errorf("期待される最初のタイムスタンプは %v ですが、実際の値は %v です", base.Add(5*time.Minute), stats.FirstTimestamp)
+	}
+}
+
+// TestCheckWindow はスライディングウィンドウの長さと時間分解能の検証のテストを行います。
+func TestCheckWindow(t *testing.T) {
+	tests := []struct {
+		window     time.Duration
+		resolution time.Duration
+		valid      bool
+	}{
+		{5 * time.Minute, time.Second, true},
+		{MaxWindowBuckets * time.Second, time.Second, true},
+		{MaxWindowBuckets*time.Second + 1, time.Second, false},
+		{time.Second, time.Nanosecond, false},
+		{2540400 * time.Hour, time.Millisecond, false},
+		{2540400 * time.Hour, 2540400 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		if err := CheckWindow(tt.window, tt.resolution); (err == nil) != tt.valid {
+			t.Errorf("CheckWindow(%v, %v): 期待される検証結果 %v, エラー %v", tt.window, tt.resolution, tt.valid, err)
+		}
+	}
+
+	// 上限を超える指定は分解能を粗くしてバケット数を上限に収める
+	swa := NewSlidingWindowAggregator(2540400*time.Hour, time.Nanosecond, EventTime)
+	if len(swa.buckets) > MaxWindowBuckets || swa.window < 2540400*time.Hour {
+		t.Errorf("補正後のウィンドウが期待値と異なります: バケット数 %d, 長さ %v", len(swa.buckets), swa.window)
+	}
+}
+
+// TestSlidingWindowAggregator_WallClock は実際の時計でウィンドウが進むことをテストします。
+func TestSlidingWindowAggregator_WallClock(t *testing.T) {
+	// 1分のウィンドウを10秒の分解能で作成
+	aggregator := NewSlidingWindowAggregator(time.Minute, 10*time.Second, WallClock)
+
+	// 時計を固定
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	aggregator.now = func() time.Time { return now }
+
+	aggregator.Add(models.LogEntry{Level: "ERROR"})
+	aggregator.Add(models.LogEntry{Level: "INFO"})
+
+	if aggregator.Window().TotalCount != 2 {
+		t.Errorf("期待される総ログ数は 2 ですが、実際の値は %d です", aggregator.Window().TotalCount)
+	}
+
+	// 時計を進めるとウィンドウから外れる
+	now = now.Add(2 * time.Minute)
+	if aggregator.Window().TotalCount != 0 {
+		t.Errorf("期待される総ログ数は 0 ですが、実際の値は %d です", aggregator.Window().TotalCount)
+	}
+
+	// リセット後の検証
+	aggregator.Add(models.LogEntry{Level: "WARN"})
+	aggregator.Reset()
+	if aggregator.GetStats().TotalCount != 0 {
+		t.Errorf("リセット後の総ログ数は 0 であるべきですが、実際の値は %d です", aggregator.GetStats().TotalCount)
+	}
+}
+
+// TestWindowCounter_Merge は拡張として計算したウィンドウの統計情報の統合をテストします。
+func TestWindowCounter_Merge(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 同じ終了時刻のウィンドウは件数を合計する
+	first := NewLogAggregator()
+	first.Attach(NewWindowCounter(time.Minute, time.Second, EventTime))
+	first.Add(models.LogEntry{Timestamp: base, Level: "INFO"})
+	second := NewLogAggregator()
+	second.Attach(NewWindowCounter(time.Minute, time.Second, EventTime))
+	second.Add(models.LogEntry{Timestamp: base, Level: "ERROR"})
+
+	merged := MergeStats(first.GetStats(), second.GetStats())
+	if merged.Window == nil || merged.Window.TotalCount != 2 || merged.Window.Ratios["ERROR"] != 0.5 {
+		t.Fatalf("統合したウィンドウが期待値と異なります: %+v", merged.Window)
+	}
+
+	// 終了時刻が異なる場合は新しい方を採用する
+	second.Add(models.LogEntry{Timestamp: base.Add(time.Hour), Level: "WARN"})
+	merged = MergeStats(first.GetStats(), second.GetStats())
+	if merged.Window.TotalCount != 1 || merged.Window.LevelCounts["WARN"] != 1 {
+		t.Errorf("統合したウィンドウが期待値と異なります: %+v", merged.Window)
+	}
+}
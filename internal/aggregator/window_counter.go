@@ -0,0 +1,66 @@
+package aggregator
+
+/*
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// WindowCounter は直近の一定時間のレベル別のログ数、レート、割合を SlidingWindowAggregator で計算する拡張です。
+type WindowCounter struct {
+	// スライディングウィンドウ
+	window *SlidingWindowAggregator
+}
+
+// NewWindowCounter は指定したウィンドウの長さ、時間分解能、現在時刻の決め方で WindowCounter を作成します。
+func NewWindowCounter(window, resolution time.Duration, source TimeSource) *WindowCounter {
+	return &WindowCounter{window: NewSlidingWindowAggregator(window, resolution, source)}
+}
+
+// Add はエントリをウィンドウに追加します。
+func (wc *WindowCounter) Add(entry models.LogEntry) {
+	wc.window.Add(entry)
+}
+
+// Apply はウィンドウ内の統計情報を統計情報に書き込みます。
+func (wc *WindowCounter) Apply(stats *models.Stats) {
+	window := wc.window.Window()
+	stats.Window = &window
+}
+
+// Reset はウィンドウ内のすべてのバケットを破棄します。
+func (wc *WindowCounter) Reset() {
+	wc.window.Reset()
+}
+
+// mergeWindow はウィンドウ内の統計情報を統合します。
+// 終了時刻が同じウィンドウ (実際の時計を使用する監視の複数ファイルなど) は件数を合計し、
+// 異なる場合は終了時刻が新しい方を採用します (停止した監視の古いウィンドウを含めないため)。
+func mergeWindow(a, b *models.WindowStats) *models.WindowStats {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil || (b != nil && b.End.After(a.End)) {
+		a, b = b, a
+	}
+
+	merged := *a
+	if b == nil || !b.End.Equal(a.End) || b.Window != a.Window {
+		return &merged
+	}
+
+	// レベル別ログ数の合計とレート、割合の再計算
+	merged.TotalCount += b.TotalCount
+	merged.LevelCounts = mergeCounts(a.LevelCounts, b.LevelCounts)
+	merged.RatesPerMinute = make(map[string]float64, len(merged.LevelCounts))
+	merged.Ratios = make(map[string]float64, len(merged.LevelCounts))
+	for level, count := range merged.LevelCounts {
+		merged.RatesPerMinute[level] = float64(count) / merged.Window.Minutes()
+		merged.Ratios[level] = float64(count) / float64(merged.TotalCount)
+	}
+
+	return &merged
+}
@@ -0,0 +1,132 @@
+package alert
+
+/*
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * text/template パッケージはテキストのテンプレートを提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// ChannelConfig は名前付きの通知先の設定を表す構造体です。Webhook、SMTP、Command のいずれか1つを指定します。
+type ChannelConfig struct {
+	// JSON の Webhook
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+	// SMTP のメール
+	SMTP *SMTPConfig `json:"smtp,omitempty"`
+	// コマンドの実行
+	Command *CommandConfig `json:"command,omitempty"`
+}
+
+// Notifier は設定から通知先を作成します。設定が不正な場合はエラーを返します。
+func (cc ChannelConfig) Notifier() (Notifier, error) {
+	var notifiers []Notifier
+	if cc.Webhook != nil {
+		notifier, err := NewWebhookNotifier(*cc.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if cc.SMTP != nil {
+		notifier, err := NewSMTPNotifier(*cc.SMTP)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if cc.Command != nil {
+		notifier, err := NewCommandNotifier(*cc.Command)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	if len(notifiers) != 1 {
+		return nil, fmt.Errorf("通知先には webhook、smtp、command のいずれか1つを指定してください")
+	}
+	return notifiers[0], nil
+}
+
+// NewEngine は設定ファイルのルールと通知先で Engine を作成します。
+// ルールが存在しない通知先を指定している場合はエラーを返します。
+func (rc RuleConfig) NewEngine() (*Engine, error) {
+	engine, err := NewEngine(rc.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// 通知先の作成
+	for name, channel := range rc.Channels {
+		notifier, err := channel.Notifier()
+		if err != nil {
+			return nil, fmt.Errorf("通知先 %s の設定が不正です: %v", name, err)
+		}
+		if err := engine.AddChannel(name, notifier); err != nil {
+			return nil, err
+		}
+	}
+
+	// ルールの通知先の検証
+	for _, rule := range rc.Rules {
+		for _, name := range rule.Notify {
+			if _, ok := rc.Channels[name]; !ok {
+				return nil, fmt.Errorf("アラートのルール %s の通知先 %s がありません", rule.Name, name)
+			}
+		}
+	}
+
+	return engine, nil
+}
+
+// templateFuncs はテンプレートで使用できる関数です。
+var templateFuncs = template.FuncMap{
+	// json は値を JSON の値として書き出します (文字列の埋め込みに使用)
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// parseTemplate は通知の内容のテンプレートを解析します。text が空の場合は fallback を使用します。
+func parseTemplate(name, text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("テンプレート %s が不正です: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// render はアラートをテンプレートに適用した結果を返します。
+func render(tmpl *template.Template, alert models.Alert) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, alert); err != nil {
+		return nil, fmt.Errorf("テンプレート %s の適用に失敗しました: %v", tmpl.Name(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseDuration は設定の時間を解析します。value が空の場合は fallback を返します。
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("時間の指定が不正です: %s", value)
+	}
+	return duration, nil
+}
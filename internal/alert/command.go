@@ -0,0 +1,93 @@
+package alert
+
+/*
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * context パッケージはタイムアウトを提供します。
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * os パッケージは環境変数を提供します。
+ * os/exec パッケージはコマンドの実行を提供します。
+ * strconv パッケージは文字列と基本データ型の変換を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// defaultCommandTimeout はコマンドの実行の既定のタイムアウトです。
+const defaultCommandTimeout = 30 * time.Second
+
+// CommandConfig はコマンドの実行の設定を表す構造体です。
+type CommandConfig struct {
+	// 実行するコマンドと引数 (シェルを介さずに実行する)
+	Args []string `json:"args"`
+	// 実行のタイムアウト (例: "30s"、既定は30秒)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// CommandNotifier はアラートごとにローカルのコマンドを実行する通知先です。
+// アラートは JSON として標準入力に渡し、主な項目は ALERT_RULE、ALERT_STATE、ALERT_SUMMARY、ALERT_COUNT、ALERT_FILE、ALERT_SAMPLE の環境変数にも設定します。
+type CommandNotifier struct {
+	// 設定
+	config CommandConfig
+	// 実行のタイムアウト
+	timeout time.Duration
+}
+
+// NewCommandNotifier は設定を検証して CommandNotifier を作成します。設定が不正な場合はエラーを返します。
+func NewCommandNotifier(config CommandConfig) (*CommandNotifier, error) {
+	if len(config.Args) == 0 || config.Args[0] == "" {
+		return nil, fmt.Errorf("実行するコマンドが指定されていません")
+	}
+
+	timeout, err := parseDuration(config.Timeout, defaultCommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandNotifier{config: config, timeout: timeout}, nil
+}
+
+// Notify はコマンドを実行します。コマンドが0以外の終了コードで終了した場合は標準エラー出力を含むエラーを返します。
+func (cn *CommandNotifier) Notify(alert models.Alert) error {
+	input, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cn.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, cn.config.Args[0], cn.config.Args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"ALERT_RULE="+alert.Rule,
+		"ALERT_STATE="+string(alert.State),
+		"ALERT_SUMMARY="+alert.Summary,
+		"ALERT_COUNT="+strconv.Itoa(alert.Count),
+		"ALERT_FILE="+alert.File,
+		"ALERT_SAMPLE="+alert.Sample,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// 子プロセスが標準エラー出力を開いたままでも、タイムアウト後に待ち続けない
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("コマンドが %s 以内に終了しませんでした", cn.timeout)
+		}
+		return fmt.Errorf("コマンドの実行に失敗しました: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
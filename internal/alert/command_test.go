@@ -0,0 +1,75 @@
+package alert
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestCommandNotifier_Notify はアラートが標準入力と環境変数でコマンドに渡されることをテストします。
+func TestCommandNotifier_Notify(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh がない環境ではスキップします")
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "alert.json")
+	cn, err := NewCommandNotifier(CommandConfig{
+		Args: []string{"sh", "-c", `cat > "$1"; echo "$ALERT_RULE $ALERT_STATE" > "$1.env"`, "sh", output},
+	})
+	if err != nil {
+		t.Fatalf("CommandNotifier の作成に失敗: %v", err)
+	}
+
+	if err := cn.Notify(models.Alert{Rule: "oom", State: models.AlertResolved, Summary: "解決しました"}); err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	// 標準入力の JSON
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("コマンドの出力の読み込みに失敗: %v", err)
+	}
+	var alert models.Alert
+	if err := json.Unmarshal(data, &alert); err != nil || alert.Rule != "oom" || alert.Summary != "解決しました" {
+		t.Errorf("標準入力の内容が期待値と異なります: %s (%v)", data, err)
+	}
+
+	// 環境変数
+	env, err := os.ReadFile(output + ".env")
+	if err != nil {
+		t.Fatalf("コマンドの出力の読み込みに失敗: %v", err)
+	}
+	if strings.TrimSpace(string(env)) != "oom resolved" {
+		t.Errorf("環境変数が期待値と異なります: %s", env)
+	}
+}
+
+// TestCommandNotifier_Notify_Errors は失敗したコマンドとタイムアウトのエラーをテストします。
+func TestCommandNotifier_Notify_Errors(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh がない環境ではスキップします")
+	}
+
+	// 0以外の終了コードは標準エラー出力を含むエラーになる
+	failing, _ := NewCommandNotifier(CommandConfig{Args: []string{"sh", "-c", "echo 送信に失敗しました >&2; exit 3"}})
+	if err := failing.Notify(models.Alert{Rule: "oom"}); err == nil || !strings.Contains(err.Error(), "送信に失敗しました") {
+		t.Errorf("標準エラー出力を含むエラーが返されませんでした: %v", err)
+	}
+
+	// タイムアウト
+	slow, _ := NewCommandNotifier(CommandConfig{Args: []string{"sh", "-c", "exec sleep 5"}, Timeout: "50ms"})
+	if err := slow.Notify(models.Alert{Rule: "oom"}); err == nil || !strings.Contains(err.Error(), "終了しませんでした") {
+		t.Errorf("タイムアウトのエラーが返されませんでした: %v", err)
+	}
+
+	// コマンドの指定なし
+	if _, err := NewCommandNotifier(CommandConfig{}); err == nil {
+		t.Error("コマンドの指定がない場合にエラーが返されませんでした")
+	}
+}
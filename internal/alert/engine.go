@@ -0,0 +1,525 @@
+package alert
+
+/*
+ * errors パッケージはエラーの結合を提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * sort パッケージはスライスのソートを提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/monitor"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+const (
+	// evaluationInterval はモニターを監視中にルールを評価する間隔です。
+	evaluationInterval = time.Second
+	// subscriptionBuffer はモニターの購読のバッファの大きさです。
+	subscriptionBuffer = 4096
+	// deliveryBuffer はモニターを監視中に送信を待つ通知の最大数です。
+	deliveryBuffer = 256
+)
+
+// Notifier はアラートの発火と解決を通知する先のインターフェースです。
+type Notifier interface {
+	// Notify はアラートを通知します。
+	Notify(alert models.Alert) error
+}
+
+// delivery は評価で決まった1つのアラートの通知です。
+type delivery struct {
+	// 通知するルール
+	rule *ruleState
+	// 通知するアラート
+	alert models.Alert
+	// 通知先一覧
+	notifiers []Notifier
+}
+
+// Silence はアラートの通知を抑止する期間を表す構造体です。
+type Silence struct {
+	// 対象のルール名 (空の場合はすべてのルール)
+	Rule string `json:"rule,omitempty"`
+	// 開始時刻
+	Start time.Time `json:"start"`
+	// 終了時刻
+	End time.Time `json:"end"`
+}
+
+// covers は時刻 t にルールの通知を抑止するかどうかを返します。
+func (s Silence) covers(rule string, t time.Time) bool {
+	return (s.Rule == "" || s.Rule == rule) && !t.Before(s.Start) && t.Before(s.End)
+}
+
+// ruleState は1つのルールの評価の状態です。
+type ruleState struct {
+	// ルール
+	compiledRule
+	// 一致したログ数 (件数の条件の場合)
+	counter *windowCounter
+	// 最後に一致した時刻 (一致するエントリがないことの条件の場合)
+	lastMatch time.Time
+	// 最後に一致したログのファイルパス
+	lastFile string
+	// 最後に一致したログのメッセージ
+	lastMessage string
+	// 状態
+	state models.AlertState
+	// 条件を満たし始めた時刻
+	activeSince time.Time
+	// 発火した時刻
+	firedAt time.Time
+	// 発火を通知したかどうか
+	notified bool
+	// 最後に通知した時刻
+	lastNotified time.Time
+	// 最後の評価で通知を抑止していたかどうか
+	silenced bool
+	// 最後の通知の失敗
+	lastError string
+}
+
+// Engine はモニターが集約したエントリに対してアラートのルールを評価し、発火と解決を通知する構造体です。
+//
+// ルールは次の状態を遷移します。
+//   - inactive: 条件を満たしていない
+//   - pending: 条件を満たしているが、For の時間に達していない
+//   - firing: 条件を For の時間以上満たしている (発火を通知する)
+//
+// 発火中のアラートは Repeat を指定しない限り一度だけ通知し (重複の抑止)、条件を満たさなくなった時点で解決を通知します。
+// 通知を抑止している間に発火したアラートは、抑止が終わった時点でまだ発火していれば通知します。
+type Engine struct {
+	// ルールごとの状態 (定義順)
+	rules []*ruleState
+	// すべてのルールの通知先一覧
+	notifiers []Notifier
+	// 名前付きの通知先 (ルールの Notify で選択する)
+	channels map[string]Notifier
+	// 通知を抑止する期間一覧
+	silences []Silence
+	// 現在時刻を返す関数
+	now func() time.Time
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+	// 監視中のモニターの購読 (監視していない場合は nil)
+	subscription *monitor.Subscription
+	// 終了した購読で破棄したイベント数
+	dropped uint64
+	// 監視の停止を通知するチャネル
+	stop chan struct{}
+	// 監視の終了を通知するチャネル
+	done chan struct{}
+}
+
+// NewEngine はルールを検証して Engine を作成します。ルールが不正な場合はエラーを返します。
+func NewEngine(rules []Rule) (*Engine, error) {
+	e := &Engine{now: time.Now, channels: make(map[string]Notifier)}
+	names := make(map[string]bool, len(rules))
+
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("アラートのルール名が重複しています: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		rs := &ruleState{compiledRule: compiled, state: models.AlertInactive}
+		if compiled.absent == 0 {
+			rs.counter = newWindowCounter(compiled.window)
+		}
+		e.rules = append(e.rules, rs)
+	}
+
+	return e, nil
+}
+
+// AddNotifier はすべてのルールのアラートを通知する通知先を追加します。Start の前に呼び出してください。
+func (e *Engine) AddNotifier(notifier Notifier) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.notifiers = append(e.notifiers, notifier)
+}
+
+// AddChannel は名前付きの通知先を追加します。Start の前に呼び出してください。
+// Notify を指定したルールのアラートは指定した名前の通知先にのみ、指定しないルールのアラートはすべての名前付きの通知先に通知します。
+func (e *Engine) AddChannel(name string, notifier Notifier) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("通知先の名前が指定されていません")
+	}
+	if _, ok := e.channels[name]; ok {
+		return fmt.Errorf("通知先の名前が重複しています: %s", name)
+	}
+	e.channels[name] = notifier
+
+	return nil
+}
+
+// Silence は指定した期間のルールの通知を抑止します。rule が空の場合はすべてのルールの通知を抑止します。
+func (e *Engine) Silence(silence Silence) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.silences = append(e.silences, silence)
+}
+
+// Silences は終了していない通知を抑止する期間を返します。
+func (e *Engine) Silences() []Silence {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.pruneSilences(e.now())
+	return append([]Silence(nil), e.silences...)
+}
+
+// Observe はエントリを評価の対象に加えます。at はエントリを読み込んだ時刻です。
+func (e *Engine) Observe(file string, entry models.LogEntry, at time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, rs := range e.rules {
+		if !rs.matches(file, entry) {
+			continue
+		}
+		if rs.counter != nil {
+			rs.counter.add(at, entry.Count())
+		}
+		if at.After(rs.lastMatch) {
+			rs.lastMatch = at
+		}
+		rs.lastFile = file
+		rs.lastMessage = entry.Message
+	}
+}
+
+// Evaluate は時刻 now でルールを評価し、状態の変化を呼び出し元のゴルーチンで通知します。
+// 通知はロックを解放してから行うため、通知先の応答が遅くても Alerts や Observe は待たされません。
+// 通知に失敗した場合は、すべての通知を試みたうえで失敗を結合したエラーを返します。
+func (e *Engine) Evaluate(now time.Time) error {
+	var errs []error
+	for _, d := range e.collect(now) {
+		if err := e.deliver(d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Alerts はルールごとの現在の評価結果を定義順に返します。
+// 監視中に受信が遅れて破棄したイベントがある場合は、その数を Dropped に含めます (件数が実際より少ない可能性があります)。
+func (e *Engine) Alerts() []models.Alert {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := e.now()
+	dropped := e.droppedEvents()
+	alerts := make([]models.Alert, 0, len(e.rules))
+	for _, rs := range e.rules {
+		alert := rs.alert(rs.state, now)
+		alert.Dropped = dropped
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// collect は時刻 now でルールを評価し、通知する内容を返します。
+func (e *Engine) collect(now time.Time) []delivery {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.pruneSilences(now)
+	var deliveries []delivery
+	for _, rs := range e.rules {
+		if d, ok := e.evaluate(rs, now); ok {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries
+}
+
+// deliver はアラートを通知し、結果をルールの評価結果に記録します。
+func (e *Engine) deliver(d delivery) error {
+	err := d.send()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	d.rule.lastError = ""
+	if err != nil {
+		d.rule.lastError = err.Error()
+	}
+	return err
+}
+
+// enqueue は通知を送信の待ち行列に加えます。待ち行列が一杯の場合は通知を破棄し、ルールの評価結果に記録します。
+func (e *Engine) enqueue(deliveries []delivery, queue chan<- delivery) {
+	for _, d := range deliveries {
+		select {
+		case queue <- d:
+		default:
+			e.mutex.Lock()
+			d.rule.lastError = fmt.Sprintf("送信を待つ通知が %d 件に達したため、アラート %s の %s の通知を破棄しました", deliveryBuffer, d.alert.Rule, d.alert.State)
+			e.mutex.Unlock()
+		}
+	}
+}
+
+// droppedEvents はこれまでの購読で破棄したイベント数を返します。ロックを保持して呼び出してください。
+func (e *Engine) droppedEvents() uint64 {
+	dropped := e.dropped
+	if e.subscription != nil {
+		dropped += e.subscription.Dropped()
+	}
+	return dropped
+}
+
+// Start はモニターを購読し、集約したエントリを評価の対象に加えながら定期的にルールを評価します。
+// 受信が遅れた場合はモニターを止めないようにイベントを破棄し、その数を Alerts の Dropped で返します。
+// 通知は評価とは別のゴルーチンで順に送信するため、通知先の応答が遅くても購読の受信は止まりません。
+// Stop の後に再び呼び出すと、ルールの状態と通知の抑止を保持したままモニターの評価を再開します。
+func (e *Engine) Start(m monitor.Monitor) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.subscription != nil {
+		return fmt.Errorf("アラートの評価はすでに開始されています")
+	}
+
+	// 開始時点から一致するエントリがないことを数える (停止していた間は数えない)
+	now := e.now()
+	for _, rs := range e.rules {
+		if rs.lastMatch.Before(now) {
+			rs.lastMatch = now
+		}
+	}
+
+	e.subscription = m.Subscribe(monitor.SubscribeOptions{Buffer: subscriptionBuffer})
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+	queue := make(chan delivery, deliveryBuffer)
+	go e.run(e.subscription, queue, e.stop, e.done)
+	go e.send(queue)
+
+	return nil
+}
+
+// Stop はモニターの購読を終了し、評価を停止します。
+// 送信を待つ通知は停止後も別のゴルーチンで送信を続けるため、通知先の応答を待たずに戻ります。
+func (e *Engine) Stop() {
+	e.mutex.Lock()
+	subscription, stop, done := e.subscription, e.stop, e.done
+	e.subscription = nil
+	if subscription != nil {
+		e.dropped += subscription.Dropped()
+	}
+	e.mutex.Unlock()
+
+	if subscription == nil {
+		return
+	}
+	close(stop)
+	subscription.Close()
+	<-done
+}
+
+// send は待ち行列の通知を順に送信します。待ち行列が閉じられると終了します。
+func (e *Engine) send(queue <-chan delivery) {
+	for d := range queue {
+		// 通知の失敗はルールの評価結果に記録される
+		e.deliver(d)
+	}
+}
+
+// run はモニターのイベントを受信してルールを評価し、通知を待ち行列に加えます。
+func (e *Engine) run(subscription *monitor.Subscription, queue chan delivery, stop, done chan struct{}) {
+	defer close(done)
+	defer close(queue)
+
+	ticker := time.NewTicker(evaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// モニターが停止した
+				return
+			}
+			if event.Kind == monitor.EntryEvent {
+				e.Observe(event.File, event.Entry, event.Time)
+			} else {
+				// 追記を集約し終えた時点で評価し、一致したエントリを直ちに反映する
+				e.enqueue(e.collect(e.now()), queue)
+			}
+		case <-ticker.C:
+			e.enqueue(e.collect(e.now()), queue)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// evaluate は1つのルールを評価し、状態が変化した場合は通知する内容を返します。
+func (e *Engine) evaluate(rs *ruleState, now time.Time) (delivery, bool) {
+	if rs.lastMatch.IsZero() {
+		rs.lastMatch = now
+	}
+	active := rs.active(now)
+	rs.silenced = rs.silencedAt(now) || e.silencedRule(rs.Name, now)
+
+	// 状態の遷移
+	switch {
+	case active && rs.state == models.AlertInactive:
+		rs.state = models.AlertPending
+		rs.activeSince = now
+	case !active && rs.state == models.AlertPending:
+		rs.state = models.AlertInactive
+		rs.activeSince = time.Time{}
+	case !active && rs.state == models.AlertFiring:
+		// 発火を通知していた場合は解決を通知する
+		var d delivery
+		notified := rs.notified
+		if notified {
+			d = e.notify(rs, models.AlertResolved, now)
+		}
+		rs.state = models.AlertInactive
+		rs.activeSince = time.Time{}
+		rs.firedAt = time.Time{}
+		rs.notified = false
+		return d, notified
+	}
+	if rs.state == models.AlertPending && now.Sub(rs.activeSince) >= rs.forDuration {
+		rs.state = models.AlertFiring
+		rs.firedAt = now
+	}
+
+	// 発火の通知 (通知済みの場合は Repeat の間隔が経過するまで通知しない)
+	if rs.state != models.AlertFiring || rs.silenced {
+		return delivery{}, false
+	}
+	if rs.notified && (rs.repeat == 0 || now.Sub(rs.lastNotified) < rs.repeat) {
+		return delivery{}, false
+	}
+	rs.notified = true
+	return e.notify(rs, models.AlertFiring, now), true
+}
+
+// notify はアラートを通知済みとして記録し、ルールの通知先への通知を返します。
+func (e *Engine) notify(rs *ruleState, state models.AlertState, now time.Time) delivery {
+	rs.lastNotified = now
+	d := delivery{rule: rs, alert: rs.alert(state, now)}
+	d.alert.Dropped = e.droppedEvents()
+
+	// すべてのルールの通知先
+	d.notifiers = append(d.notifiers, e.notifiers...)
+
+	// 名前付きの通知先 (ルールが指定しない場合はすべて)
+	if len(rs.Notify) == 0 {
+		names := make([]string, 0, len(e.channels))
+		for name := range e.channels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d.notifiers = append(d.notifiers, e.channels[name])
+		}
+		return d
+	}
+	for _, name := range rs.Notify {
+		notifier, ok := e.channels[name]
+		if !ok {
+			notifier = missingChannel(name)
+		}
+		d.notifiers = append(d.notifiers, notifier)
+	}
+
+	return d
+}
+
+// send はアラートを通知先に順に通知します。失敗した場合も残りの通知先への通知を続けます。
+func (d delivery) send() error {
+	var errs []error
+	for _, notifier := range d.notifiers {
+		if err := notifier.Notify(d.alert); err != nil {
+			errs = append(errs, fmt.Errorf("アラート %s の通知に失敗しました: %v", d.alert.Rule, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// missingChannel は存在しない名前の通知先です。通知すると常にエラーを返します。
+type missingChannel string
+
+// Notify は通知先が存在しないことをエラーとして返します。
+func (mc missingChannel) Notify(alert models.Alert) error {
+	return fmt.Errorf("通知先 %s がありません", string(mc))
+}
+
+// silencedRule は時刻 now にルールの通知を抑止する期間があるかどうかを返します。
+func (e *Engine) silencedRule(rule string, now time.Time) bool {
+	for _, silence := range e.silences {
+		if silence.covers(rule, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneSilences は終了した通知を抑止する期間を取り除きます。
+func (e *Engine) pruneSilences(now time.Time) {
+	kept := e.silences[:0]
+	for _, silence := range e.silences {
+		if now.Before(silence.End) {
+			kept = append(kept, silence)
+		}
+	}
+	e.silences = kept
+}
+
+// active は時刻 now にルールの条件を満たしているかどうかを返します。
+func (rs *ruleState) active(now time.Time) bool {
+	if rs.counter == nil {
+		return now.Sub(rs.lastMatch) >= rs.absent
+	}
+	return rs.counter.count(now) > rs.Threshold
+}
+
+// alert は現在の評価結果を指定した状態のアラートとして返します。
+func (rs *ruleState) alert(state models.AlertState, now time.Time) models.Alert {
+	alert := models.Alert{
+		Rule:        rs.Name,
+		State:       state,
+		File:        rs.lastFile,
+		Sample:      rs.lastMessage,
+		ActiveSince: rs.activeSince,
+		FiredAt:     rs.firedAt,
+		Silenced:    rs.silenced,
+		Error:       rs.lastError,
+	}
+	if state == models.AlertResolved {
+		alert.ResolvedAt = now
+	}
+
+	// 評価結果の説明
+	if rs.counter == nil {
+		alert.Summary = fmt.Sprintf("%s: 最後に一致したログから %s 経過しています (条件 %s 以上)", rs.Name, now.Sub(rs.lastMatch).Truncate(time.Second), rs.absent)
+		return alert
+	}
+	alert.Count = rs.counter.count(now)
+	alert.Summary = fmt.Sprintf("%s: 直近 %s に一致したログが %d 件です (閾値 %d 件)", rs.Name, rs.window, alert.Count, rs.Threshold)
+
+	return alert
+}
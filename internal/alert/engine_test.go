@@ -0,0 +1,448 @@
+package alert
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/monitor"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// recorder は通知されたアラートを記録する通知先です。
+type recorder struct {
+	// 通知されたアラート一覧
+	alerts []models.Alert
+	// 通知で返すエラー
+	err error
+	// 通知を受け取るたびに送信するチャネル (nil の場合は送信しない)
+	notified chan models.Alert
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// Notify はアラートを記録します。
+func (r *recorder) Notify(alert models.Alert) error {
+	r.mutex.Lock()
+	r.alerts = append(r.alerts, alert)
+	r.mutex.Unlock()
+
+	if r.notified != nil {
+		r.notified <- alert
+	}
+	return r.err
+}
+
+// states は通知されたアラートのルール名と状態を "rule:state" の形式で返し、記録を消去します。
+func (r *recorder) states() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	states := make([]string, 0, len(r.alerts))
+	for _, alert := range r.alerts {
+		states = append(states, alert.Rule+":"+string(alert.State))
+	}
+	r.alerts = nil
+	return states
+}
+
+// newTestEngine はルールと記録する通知先を設定した Engine を作成します。
+func newTestEngine(t *testing.T, rules ...Rule) (*Engine, *recorder) {
+	t.Helper()
+
+	engine, err := NewEngine(rules)
+	if err != nil {
+		t.Fatalf("Engine の作成に失敗: %v", err)
+	}
+	rec := &recorder{}
+	engine.AddNotifier(rec)
+	return engine, rec
+}
+
+// expectStates は通知されたアラートが期待値と一致することを確認します。
+func expectStates(t *testing.T, rec *recorder, expected ...string) {
+	t.Helper()
+
+	got := rec.states()
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("通知が期待値と異なります: 期待値=%v, 実際=%v", expected, got)
+	}
+}
+
+// TestEngine_Threshold は件数の閾値のルールが継続時間の後に一度だけ発火し、件数が減ると解決することをテストします。
+func TestEngine_Threshold(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{
+		Rule:      pipeline.Rule{Name: "error_burst", Level: "ERROR"},
+		Threshold: 2,
+		Window:    "1m",
+		For:       "30s",
+	})
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 閾値以下では発火しない
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "失敗しました。"}, start)
+	engine.Observe("app.log", models.LogEntry{Level: "WARN", Message: "遅延しています。"}, start)
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "失敗しました。"}, start)
+	engine.Evaluate(start)
+	expectStates(t, rec)
+	if engine.Alerts()[0].State != models.AlertInactive {
+		t.Errorf("状態が期待値と異なります: %+v", engine.Alerts()[0])
+	}
+
+	// 閾値を超えても継続時間に達するまでは保留する
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "停止しました。"}, start.Add(10*time.Second))
+	engine.Evaluate(start.Add(10 * time.Second))
+	expectStates(t, rec)
+	if engine.Alerts()[0].State != models.AlertPending {
+		t.Errorf("状態が期待値と異なります: %+v", engine.Alerts()[0])
+	}
+
+	// 継続時間に達すると発火し、発火中は重複して通知しない
+	engine.Evaluate(start.Add(40 * time.Second))
+	engine.Evaluate(start.Add(50 * time.Second))
+	expectStates(t, rec, "error_burst:firing")
+
+	// 件数が閾値以下になると解決する
+	engine.Evaluate(start.Add(65 * time.Second))
+	expectStates(t, rec, "error_burst:resolved")
+	if engine.Alerts()[0].State != models.AlertInactive {
+		t.Errorf("状態が期待値と異なります: %+v", engine.Alerts()[0])
+	}
+}
+
+// TestEngine_Match はメッセージの一致のルールが直ちに発火し、一致した内容が通知されることをテストします。
+func TestEngine_Match(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "oom", Message: "OutOfMemory"}})
+	rec.notified = make(chan models.Alert, 1)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	engine.Observe("/var/log/api.log", models.LogEntry{Level: "ERROR", Message: "java.lang.OutOfMemoryError"}, start)
+	engine.Evaluate(start)
+
+	alert := <-rec.notified
+	if alert.State != models.AlertFiring || alert.Count != 1 || alert.File != "/var/log/api.log" || alert.Sample != "java.lang.OutOfMemoryError" {
+		t.Errorf("通知されたアラートが期待値と異なります: %+v", alert)
+	}
+	if !alert.FiredAt.Equal(start) {
+		t.Errorf("発火した時刻が期待値と異なります: %v", alert.FiredAt)
+	}
+}
+
+// TestEngine_Absent は一致するログがないことのルールの発火と、ログが届いたときの解決をテストします。
+func TestEngine_Absent(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "silent"}, Absent: "5m"})
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 最初の評価の時点から数える
+	engine.Evaluate(start)
+	engine.Evaluate(start.Add(4 * time.Minute))
+	expectStates(t, rec)
+
+	engine.Evaluate(start.Add(5 * time.Minute))
+	expectStates(t, rec, "silent:firing")
+
+	// ログが届くと解決する
+	engine.Observe("app.log", models.LogEntry{Level: "INFO", Message: "再開しました。"}, start.Add(6*time.Minute))
+	engine.Evaluate(start.Add(6 * time.Minute))
+	expectStates(t, rec, "silent:resolved")
+}
+
+// TestEngine_Restart は停止していた間を一致するログがない時間として数えないことをテストします。
+func TestEngine_Restart(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "silent"}, Absent: "5m"})
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	engine.now = func() time.Time { return now }
+
+	fm := monitor.NewFileMonitor(filepath.Join(t.TempDir(), "app.log"), time.Hour)
+	if err := engine.Start(fm); err != nil {
+		t.Fatalf("Engine の Start に失敗: %v", err)
+	}
+	engine.Stop()
+
+	// 1時間後に再開した時点から数える
+	now = start.Add(time.Hour)
+	if err := engine.Start(fm); err != nil {
+		t.Fatalf("Engine の再度の Start に失敗: %v", err)
+	}
+	engine.Stop()
+	engine.Evaluate(now.Add(time.Minute))
+	expectStates(t, rec)
+
+	engine.Evaluate(now.Add(5 * time.Minute))
+	expectStates(t, rec, "silent:firing")
+}
+
+// TestEngine_Silence は抑止中の発火を通知せず、抑止が終わった時点で通知し、Repeat の間隔で通知を繰り返すことをテストします。
+func TestEngine_Silence(t *testing.T) {
+	engine, rec := newTestEngine(t,
+		Rule{Rule: pipeline.Rule{Name: "errors", Level: "ERROR"}, Window: "1h", Repeat: "10m"},
+		Rule{Rule: pipeline.Rule{Name: "warnings", Level: "WARN"}, Window: "1h"},
+	)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return start }
+	engine.Silence(Silence{Rule: "errors", Start: start, End: start.Add(time.Minute)})
+
+	// 抑止しているルールのみ通知しない
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "失敗しました。"}, start)
+	engine.Evaluate(start)
+	expectStates(t, rec, "warnings:firing")
+	if alert := engine.Alerts()[0]; alert.State != models.AlertFiring || !alert.Silenced {
+		t.Errorf("抑止中のアラートの状態が期待値と異なります: %+v", alert)
+	}
+	if silences := engine.Silences(); len(silences) != 1 {
+		t.Errorf("抑止の期間が期待値と異なります: %+v", silences)
+	}
+
+	// 抑止が終わると通知し、Repeat の間隔が経過するまでは繰り返さない
+	engine.Evaluate(start.Add(time.Minute))
+	engine.Evaluate(start.Add(5 * time.Minute))
+	expectStates(t, rec, "errors:firing")
+	engine.Evaluate(start.Add(11 * time.Minute))
+	expectStates(t, rec, "errors:firing")
+
+	// 終了した抑止の期間は取り除かれる
+	if silences := engine.Silences(); len(silences) != 0 {
+		t.Errorf("終了した抑止の期間が残っています: %+v", silences)
+	}
+}
+
+// TestEngine_NotifyError は通知の失敗がエラーとして返され、評価結果に記録されることをテストします。
+func TestEngine_NotifyError(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "oom", Message: "OutOfMemory"}})
+	rec.err = errors.New("接続に失敗しました")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "OutOfMemory"}, start)
+	if err := engine.Evaluate(start); err == nil {
+		t.Error("通知の失敗がエラーとして返されませんでした")
+	}
+	if alert := engine.Alerts()[0]; !strings.Contains(alert.Error, "接続に失敗しました") {
+		t.Errorf("通知の失敗が記録されていません: %+v", alert)
+	}
+}
+
+// TestEngine_Start はモニターが集約したエントリでルールが評価され、通知されることをテストします。
+func TestEngine_Start(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("2024-01-01 12:00:00 [INFO] 起動しました。\n"), 0644); err != nil {
+		t.Fatalf("ログファイルの作成に失敗: %v", err)
+	}
+
+	fm := monitor.NewFileMonitor(path, 10*time.Millisecond)
+	engine, rec := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "oom", Message: "OutOfMemory"}})
+	rec.notified = make(chan models.Alert, 1)
+	if err := engine.Start(fm); err != nil {
+		t.Fatalf("Engine の Start に失敗: %v", err)
+	}
+	defer engine.Stop()
+	if err := engine.Start(fm); err == nil {
+		t.Error("二重の Start がエラーになりませんでした")
+	}
+	if err := fm.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	defer fm.Stop()
+
+	// 追記したログでルールが発火する
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("ログファイルを開けません: %v", err)
+	}
+	file.WriteString("2024-01-01 12:01:00 [ERROR] java.lang.OutOfMemoryError\n")
+	file.Close()
+
+	select {
+	case alert := <-rec.notified:
+		if alert.Rule != "oom" || alert.State != models.AlertFiring || alert.File != path {
+			t.Errorf("通知されたアラートが期待値と異なります: %+v", alert)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("アラートが通知されませんでした")
+	}
+}
+
+// blockingNotifier は解放されるまで通知から戻らない通知先です。
+type blockingNotifier struct {
+	// 通知を受け取ったことを送信するチャネル
+	started chan struct{}
+	// 閉じると通知から戻るチャネル
+	release chan struct{}
+}
+
+// Notify は解放されるまで待ちます。
+func (bn *blockingNotifier) Notify(alert models.Alert) error {
+	bn.started <- struct{}{}
+	<-bn.release
+	return nil
+}
+
+// TestEngine_SlowNotifier は通知先の応答が遅くても、モニターのエントリを評価の対象に加え続けることをテストします。
+func TestEngine_SlowNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("2024-01-01 12:00:00 [ERROR] 接続に失敗しました。\n"), 0644); err != nil {
+		t.Fatalf("ログファイルの作成に失敗: %v", err)
+	}
+
+	fm := monitor.NewFileMonitor(path, 10*time.Millisecond)
+	engine, err := NewEngine([]Rule{{Rule: pipeline.Rule{Name: "errors", Level: "ERROR"}}})
+	if err != nil {
+		t.Fatalf("Engine の作成に失敗: %v", err)
+	}
+	bn := &blockingNotifier{started: make(chan struct{}, 1), release: make(chan struct{})}
+	engine.AddNotifier(bn)
+	if err := engine.Start(fm); err != nil {
+		t.Fatalf("Engine の Start に失敗: %v", err)
+	}
+	defer engine.Stop()
+	defer close(bn.release)
+	if err := fm.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	defer fm.Stop()
+
+	select {
+	case <-bn.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("アラートが通知されませんでした")
+	}
+
+	// 通知から戻らない間に追記したログも数える
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("ログファイルを開けません: %v", err)
+	}
+	file.WriteString("2024-01-01 12:01:00 [ERROR] 接続に失敗しました。\n2024-01-01 12:02:00 [ERROR] 接続に失敗しました。\n")
+	file.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for engine.Alerts()[0].Count != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("通知中に追記したログが数えられていません: %+v", engine.Alerts()[0])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestEngine_Dropped は受信が遅れて破棄したイベント数を Alerts で返すことをテストします。
+func TestEngine_Dropped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	var lines strings.Builder
+	for range subscriptionBuffer + 100 {
+		lines.WriteString("2024-01-01 12:00:00 [ERROR] 接続に失敗しました。\n")
+	}
+	if err := os.WriteFile(path, []byte(lines.String()), 0644); err != nil {
+		t.Fatalf("ログファイルの作成に失敗: %v", err)
+	}
+
+	fm := monitor.NewFileMonitor(path, 10*time.Millisecond)
+	engine, _ := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "errors", Level: "ERROR"}})
+	if err := engine.Start(fm); err != nil {
+		t.Fatalf("Engine の Start に失敗: %v", err)
+	}
+	defer engine.Stop()
+
+	// 評価のロックを保持して受信を止めている間にすべての行を集約させる
+	engine.mutex.Lock()
+	if err := fm.Start(); err != nil {
+		engine.mutex.Unlock()
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	defer fm.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats, _ := fm.GetStats()
+		if stats.TotalCount == subscriptionBuffer+100 {
+			break
+		}
+		if time.Now().After(deadline) {
+			engine.mutex.Unlock()
+			t.Fatalf("ログが集約されません: %d", stats.TotalCount)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	engine.mutex.Unlock()
+
+	if alert := engine.Alerts()[0]; alert.Dropped == 0 {
+		t.Errorf("破棄したイベント数が返されていません: %+v", alert)
+	}
+
+	// 停止した後も破棄したイベント数を保持する
+	engine.Stop()
+	if alert := engine.Alerts()[0]; alert.Dropped == 0 {
+		t.Errorf("停止で破棄したイベント数が失われました: %+v", alert)
+	}
+}
+
+// TestEngine_QueueFull は送信を待つ通知が一杯の場合に、破棄した通知をルールの評価結果に記録することをテストします。
+func TestEngine_QueueFull(t *testing.T) {
+	engine, _ := newTestEngine(t, Rule{Rule: pipeline.Rule{Name: "errors", Level: "ERROR"}})
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "接続に失敗しました。"}, time.Now())
+
+	engine.enqueue(engine.collect(time.Now()), make(chan delivery))
+	if alert := engine.Alerts()[0]; alert.State != models.AlertFiring || !strings.Contains(alert.Error, "破棄しました") {
+		t.Errorf("破棄した通知が記録されていません: %+v", alert)
+	}
+}
+
+// TestEngine_Routing はルールの Notify で指定した通知先にのみ通知し、指定しないルールはすべての通知先に通知することをテストします。
+func TestEngine_Routing(t *testing.T) {
+	engine, all := newTestEngine(t,
+		Rule{Rule: pipeline.Rule{Name: "oom", Message: "OutOfMemory"}, Notify: []string{"pager"}},
+		Rule{Rule: pipeline.Rule{Name: "errors", Level: "ERROR"}},
+		Rule{Rule: pipeline.Rule{Name: "disk", Message: "No space"}, Notify: []string{"missing"}},
+	)
+	pager, mail := &recorder{}, &recorder{}
+	if err := engine.AddChannel("pager", pager); err != nil {
+		t.Fatalf("通知先の追加に失敗: %v", err)
+	}
+	if err := engine.AddChannel("mail", mail); err != nil {
+		t.Fatalf("通知先の追加に失敗: %v", err)
+	}
+	if err := engine.AddChannel("mail", mail); err == nil {
+		t.Error("重複した名前の通知先の追加がエラーになりませんでした")
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	engine.Observe("app.log", models.LogEntry{Level: "ERROR", Message: "java.lang.OutOfMemoryError"}, start)
+	engine.Observe("app.log", models.LogEntry{Level: "INFO", Message: "No space left on device"}, start)
+
+	// 存在しない通知先は失敗として記録され、他の通知は行われる
+	if err := engine.Evaluate(start); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("存在しない通知先のエラーが返されませんでした: %v", err)
+	}
+	expectStates(t, all, "oom:firing", "errors:firing", "disk:firing")
+	expectStates(t, pager, "oom:firing", "errors:firing")
+	expectStates(t, mail, "errors:firing")
+}
+
+// TestRuleConfig_NewEngine は設定ファイルの通知先の作成と検証をテストします。
+func TestRuleConfig_NewEngine(t *testing.T) {
+	config := RuleConfig{
+		Rules: []Rule{{Rule: pipeline.Rule{Name: "oom", Message: "OutOfMemory"}, Notify: []string{"hook"}}},
+		Channels: map[string]ChannelConfig{
+			"hook": {Webhook: &WebhookConfig{URL: "http://127.0.0.1:9/alerts"}},
+		},
+	}
+	if _, err := config.NewEngine(); err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	// 存在しない通知先の指定
+	config.Rules[0].Notify = []string{"pager"}
+	if _, err := config.NewEngine(); err == nil {
+		t.Error("存在しない通知先の指定がエラーになりませんでした")
+	}
+
+	// 通知先の種類が1つでない
+	config.Rules[0].Notify = nil
+	config.Channels["hook"] = ChannelConfig{}
+	if _, err := config.NewEngine(); err == nil {
+		t.Error("種類のない通知先がエラーになりませんでした")
+	}
+}
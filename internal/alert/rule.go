@@ -0,0 +1,273 @@
+package alert
+
+/*
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * os パッケージはファイル操作を提供します。
+ * path/filepath パッケージはファイルパスの照合を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// defaultWindow は件数を数える時間の既定値です。
+const defaultWindow = time.Minute
+
+// Rule はアラートのルールを表す構造体です。
+// 一致するエントリの条件はタグ付けのルールと同じ形式で指定し、条件を指定しない場合はすべてのエントリに一致します。
+//
+// Absent を指定した場合は、一致するエントリがその時間以上ないときに条件を満たします。
+// 指定しない場合は、直近の Window に一致したエントリが Threshold 件より多いときに条件を満たします
+// (Threshold が 0 の場合は1件でも一致すれば条件を満たします)。
+type Rule struct {
+	// ルール名と一致するエントリの条件
+	pipeline.Rule
+	// 条件を満たす件数の閾値 (この件数より多い場合)
+	Threshold int `json:"threshold,omitempty"`
+	// 件数を数える時間 (例: "1m"、既定は1分)
+	Window string `json:"window,omitempty"`
+	// 一致するエントリがないことを条件とする時間 (例: "5m")
+	Absent string `json:"absent,omitempty"`
+	// 発火するまでに条件を満たし続ける時間 (例: "30s"、既定は直ちに発火)
+	For string `json:"for,omitempty"`
+	// 発火中に通知を繰り返す間隔 (例: "1h"、既定は繰り返さない)
+	Repeat string `json:"repeat,omitempty"`
+	// 通知を抑止する毎日の時間帯 (例: "22:00-06:00"、ローカル時刻)
+	Silences []string `json:"silences,omitempty"`
+	// 対象とするファイルパスのパターン (filepath.Match の形式、既定はすべてのファイル)
+	Files []string `json:"files,omitempty"`
+	// 通知先の名前一覧 (既定はすべての通知先)
+	Notify []string `json:"notify,omitempty"`
+}
+
+// RuleConfig はアラートのルールの設定ファイルの構造を表します。
+type RuleConfig struct {
+	// ルール一覧
+	Rules []Rule `json:"rules"`
+	// 名前付きの通知先
+	Channels map[string]ChannelConfig `json:"channels,omitempty"`
+}
+
+// LoadConfigFile は JSON 形式のアラートの設定ファイルを読み込みます。
+func LoadConfigFile(path string) (RuleConfig, error) {
+	var config RuleConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("アラートの設定ファイルの読み込みに失敗しました: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("アラートの設定ファイルの解析に失敗しました: %v", err)
+	}
+
+	return config, nil
+}
+
+// LoadRuleFile は JSON 形式のアラートの設定ファイルからルール一覧を読み込みます。
+func LoadRuleFile(path string) ([]Rule, error) {
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+// dailyWindow は毎日の時間帯を表します (0時からの経過時間)。
+type dailyWindow struct {
+	// 開始
+	start time.Duration
+	// 終了 (開始より前の場合は翌日の時刻)
+	end time.Duration
+}
+
+// contains は時刻が時間帯に含まれるかどうかを返します。
+func (dw dailyWindow) contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if dw.start <= dw.end {
+		return offset >= dw.start && offset < dw.end
+	}
+	return offset >= dw.start || offset < dw.end
+}
+
+// parseDailyWindow は "22:00-06:00" の形式の時間帯を解析します。
+func parseDailyWindow(s string) (dailyWindow, error) {
+	var startHour, startMinute, endHour, endMinute int
+	if _, err := fmt.Sscanf(s, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute); err != nil ||
+		startHour < 0 || startHour > 24 || endHour < 0 || endHour > 24 ||
+		startMinute < 0 || startMinute > 59 || endMinute < 0 || endMinute > 59 {
+		return dailyWindow{}, fmt.Errorf("時間帯の指定が不正です: %s", s)
+	}
+
+	return dailyWindow{
+		start: time.Duration(startHour)*time.Hour + time.Duration(startMinute)*time.Minute,
+		end:   time.Duration(endHour)*time.Hour + time.Duration(endMinute)*time.Minute,
+	}, nil
+}
+
+// compiledRule は検証済みのアラートのルールです。
+type compiledRule struct {
+	// 元のルール
+	Rule
+	// 一致するエントリの条件 (条件を指定しない場合は nil)
+	matcher *pipeline.RuleSet
+	// 件数を数える時間
+	window time.Duration
+	// 一致するエントリがないことを条件とする時間 (0 の場合は件数の条件)
+	absent time.Duration
+	// 発火するまでに条件を満たし続ける時間
+	forDuration time.Duration
+	// 発火中に通知を繰り返す間隔
+	repeat time.Duration
+	// 通知を抑止する毎日の時間帯
+	silences []dailyWindow
+}
+
+// compileRule はルールを検証して compiledRule を作成します。ルールが不正な場合はエラーを返します。
+func compileRule(rule Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: rule, window: defaultWindow}
+
+	// 名前の検証
+	if rule.Name == "" {
+		return cr, fmt.Errorf("アラートのルール名が指定されていません")
+	}
+
+	// 一致するエントリの条件
+	if rule.Message != "" || rule.Field != "" || rule.Level != "" || rule.Equals != "" || rule.Min != nil || rule.Max != nil {
+		matcher, err := pipeline.NewRuleSet([]pipeline.Rule{rule.Rule})
+		if err != nil {
+			return cr, err
+		}
+		cr.matcher = matcher
+	}
+	for _, pattern := range rule.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return cr, fmt.Errorf("アラートのルール %s のファイルのパターンが不正です: %s", rule.Name, pattern)
+		}
+	}
+
+	// 時間の指定
+	durations := []struct {
+		value  string
+		target *time.Duration
+	}{
+		{rule.Window, &cr.window},
+		{rule.Absent, &cr.absent},
+		{rule.For, &cr.forDuration},
+		{rule.Repeat, &cr.repeat},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil || duration <= 0 {
+			return cr, fmt.Errorf("アラートのルール %s の時間の指定が不正です: %s", rule.Name, d.value)
+		}
+		*d.target = duration
+	}
+	if rule.Threshold < 0 {
+		return cr, fmt.Errorf("アラートのルール %s の閾値が不正です: %d", rule.Name, rule.Threshold)
+	}
+	if cr.absent > 0 && (rule.Threshold > 0 || rule.Window != "") {
+		return cr, fmt.Errorf("アラートのルール %s の absent は threshold や window と同時に指定できません", rule.Name)
+	}
+
+	// 通知を抑止する時間帯
+	for _, silence := range rule.Silences {
+		window, err := parseDailyWindow(silence)
+		if err != nil {
+			return cr, fmt.Errorf("アラートのルール %s の%v", rule.Name, err)
+		}
+		cr.silences = append(cr.silences, window)
+	}
+
+	return cr, nil
+}
+
+// matches はエントリがルールの条件に一致するかどうかを返します。
+func (cr compiledRule) matches(file string, entry models.LogEntry) bool {
+	if len(cr.Files) > 0 {
+		matched := false
+		for _, pattern := range cr.Files {
+			if ok, _ := filepath.Match(pattern, file); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return cr.matcher == nil || len(cr.matcher.Match(entry)) > 0
+}
+
+// silencedAt は時刻がルールの通知を抑止する時間帯に含まれるかどうかを返します。
+func (cr compiledRule) silencedAt(t time.Time) bool {
+	local := t.Local()
+	for _, window := range cr.silences {
+		if window.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// windowCounter は直近の一定時間に一致したログ数を時間バケットごとに数える構造体です。
+// 時間の境界は時間バケットの長さ (時間の 1/60、最小1秒) の精度で扱います。
+type windowCounter struct {
+	// 時間バケットの長さ
+	resolution time.Duration
+	// リングバッファとして使う時間バケット一覧
+	buckets []counterBucket
+}
+
+// counterBucket は1つの時間バケットのログ数です。
+type counterBucket struct {
+	// 時間バケットの番号 (時刻 / 時間バケットの長さ)
+	index int64
+	// ログ数
+	count int
+}
+
+// newWindowCounter は指定した時間のログ数を数える windowCounter を作成します。
+func newWindowCounter(window time.Duration) *windowCounter {
+	resolution := max(window/60, time.Second)
+	size := int((window + resolution - 1) / resolution)
+
+	return &windowCounter{
+		resolution: resolution,
+		buckets:    make([]counterBucket, size),
+	}
+}
+
+// add は時刻 t に count 件のログを加えます。
+func (wc *windowCounter) add(t time.Time, count int) {
+	index := t.UnixNano() / int64(wc.resolution)
+	bucket := &wc.buckets[index%int64(len(wc.buckets))]
+	if bucket.index != index {
+		bucket.index = index
+		bucket.count = 0
+	}
+	bucket.count += count
+}
+
+// count は時刻 now までの直近の時間のログ数を返します。
+func (wc *windowCounter) count(now time.Time) int {
+	current := now.UnixNano() / int64(wc.resolution)
+	total := 0
+	for _, bucket := range wc.buckets {
+		if bucket.index <= current && bucket.index > current-int64(len(wc.buckets)) {
+			total += bucket.count
+		}
+	}
+	return total
+}
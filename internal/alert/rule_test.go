@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestCompileRule_Invalid は不正なアラートのルールがエラーになることをテストします。
+func TestCompileRule_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "名前なし", rule: Rule{Rule: pipeline.Rule{Level: "ERROR"}}},
+		{name: "不正な正規表現", rule: Rule{Rule: pipeline.Rule{Name: "a", Message: "("}}},
+		{name: "不正な時間", rule: Rule{Rule: pipeline.Rule{Name: "a"}, Window: "1分"}},
+		{name: "負の時間", rule: Rule{Rule: pipeline.Rule{Name: "a"}, For: "-1s"}},
+		{name: "負の閾値", rule: Rule{Rule: pipeline.Rule{Name: "a"}, Threshold: -1}},
+		{name: "absent と threshold", rule: Rule{Rule: pipeline.Rule{Name: "a"}, Absent: "5m", Threshold: 3}},
+		{name: "不正な時間帯", rule: Rule{Rule: pipeline.Rule{Name: "a"}, Silences: []string{"22時-6時"}}},
+		{name: "不正なファイルのパターン", rule: Rule{Rule: pipeline.Rule{Name: "a"}, Files: []string{"["}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileRule(tt.rule); err == nil {
+				t.Error("エラーが発生するはずですが、エラーが発生しませんでした")
+			}
+		})
+	}
+}
+
+// TestCompiledRule_Matches はエントリの条件とファイルのパターンによる照合をテストします。
+func TestCompiledRule_Matches(t *testing.T) {
+	cr, err := compileRule(Rule{
+		Rule:  pipeline.Rule{Name: "oom", Message: "OutOfMemory"},
+		Files: []string{"/var/log/api/*.log"},
+	})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	if !cr.matches("/var/log/api/app.log", models.LogEntry{Level: "ERROR", Message: "java.lang.OutOfMemoryError"}) {
+		t.Error("条件に一致するエントリが一致しませんでした")
+	}
+	if cr.matches("/var/log/web/app.log", models.LogEntry{Level: "ERROR", Message: "java.lang.OutOfMemoryError"}) {
+		t.Error("パターンに一致しないファイルのエントリが一致しました")
+	}
+	if cr.matches("/var/log/api/app.log", models.LogEntry{Level: "ERROR", Message: "timeout"}) {
+		t.Error("条件に一致しないエントリが一致しました")
+	}
+
+	// 条件を指定しない場合はすべてのエントリに一致する
+	all, err := compileRule(Rule{Rule: pipeline.Rule{Name: "any"}, Absent: "5m"})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	if !all.matches("/var/log/app.log", models.LogEntry{Level: "INFO", Message: "ok"}) {
+		t.Error("条件のないルールがエントリに一致しませんでした")
+	}
+}
+
+// TestParseDailyWindow は毎日の時間帯の解析と、日付をまたぐ時間帯の判定をテストします。
+func TestParseDailyWindow(t *testing.T) {
+	window, err := parseDailyWindow("22:00-06:30")
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	tests := []struct {
+		clock    string
+		expected bool
+	}{
+		{"21:59", false},
+		{"22:00", true},
+		{"03:00", true},
+		{"06:29", true},
+		{"06:30", false},
+		{"12:00", false},
+	}
+	for _, tt := range tests {
+		clock, _ := time.Parse("15:04", tt.clock)
+		if got := window.contains(clock); got != tt.expected {
+			t.Errorf("%s の判定が期待値と異なります: 期待値=%v, 実際=%v", tt.clock, tt.expected, got)
+		}
+	}
+}
+
+// TestWindowCounter は直近の時間のログ数の集計と、時間が経過したログの除外をテストします。
+func TestWindowCounter(t *testing.T) {
+	wc := newWindowCounter(time.Minute)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	wc.add(start, 3)
+	wc.add(start.Add(30*time.Second), 2)
+	if got := wc.count(start.Add(30 * time.Second)); got != 5 {
+		t.Errorf("ログ数が期待値と異なります: 期待値=%d, 実際=%d", 5, got)
+	}
+
+	// 1分を過ぎたログは数えない
+	if got := wc.count(start.Add(70 * time.Second)); got != 2 {
+		t.Errorf("ログ数が期待値と異なります: 期待値=%d, 実際=%d", 2, got)
+	}
+	if got := wc.count(start.Add(2 * time.Minute)); got != 0 {
+		t.Errorf("ログ数が期待値と異なります: 期待値=%d, 実際=%d", 0, got)
+	}
+}
+
+// TestLoadRuleFile はアラートの設定ファイルの読み込みをテストします。
+func TestLoadRuleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	data := `{"rules": [
+		{"name": "error_burst", "level": "ERROR", "threshold": 10, "window": "1m", "for": "30s"},
+		{"name": "oom", "message": "OutOfMemory"},
+		{"name": "silent", "absent": "5m", "silences": ["02:00-04:00"]}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("設定ファイルの作成に失敗: %v", err)
+	}
+
+	rules, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("ルール数が期待値と異なります: %d", len(rules))
+	}
+	if rules[0].Name != "error_burst" || rules[0].Level != "ERROR" || rules[0].Threshold != 10 || rules[0].For != "30s" {
+		t.Errorf("ルールが期待値と異なります: %+v", rules[0])
+	}
+	if _, err := NewEngine(rules); err != nil {
+		t.Errorf("読み込んだルールの検証に失敗: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package alert
+
+/*
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * crypto/tls パッケージは TLS を提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * mime パッケージはヘッダーの符号化を提供します。
+ * mime/quotedprintable パッケージは本文の符号化を提供します。
+ * net パッケージはネットワークの接続を提供します。
+ * net/smtp パッケージは SMTP クライアントを提供します。
+ * strings パッケージは文字列操作を提供します。
+ * text/template パッケージはテキストのテンプレートを提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+const (
+	// defaultSMTPTimeout は SMTP の送信の既定のタイムアウトです。
+	defaultSMTPTimeout = 30 * time.Second
+	// defaultSubjectTemplate はメールの件名の既定のテンプレートです。
+	defaultSubjectTemplate = `[{{.State}}] {{.Rule}}`
+	// defaultBodyTemplate はメールの本文の既定のテンプレートです。
+	defaultBodyTemplate = `{{.Summary}}
+
+ルール: {{.Rule}}
+状態: {{.State}}
+件数: {{.Count}}
+{{- if .File}}
+ファイル: {{.File}}{{end}}
+{{- if .Sample}}
+最後に一致したログ: {{.Sample}}{{end}}
+{{- if not .ActiveSince.IsZero}}
+条件を満たし始めた時刻: {{.ActiveSince.Format "2006-01-02 15:04:05 MST"}}{{end}}
+{{- if not .ResolvedAt.IsZero}}
+解決した時刻: {{.ResolvedAt.Format "2006-01-02 15:04:05 MST"}}{{end}}
+`
+)
+
+// SMTPConfig は SMTP のメールの設定を表す構造体です。
+type SMTPConfig struct {
+	// SMTP サーバーのアドレス (例: "smtp.example.com:587")
+	Addr string `json:"addr"`
+	// 送信者のアドレス
+	From string `json:"from"`
+	// 宛先のアドレス一覧
+	To []string `json:"to"`
+	// 認証のユーザー名 (指定しない場合は認証しない)
+	Username string `json:"username,omitempty"`
+	// 認証のパスワード
+	Password string `json:"password,omitempty"`
+	// 件名のテンプレート (text/template の形式)
+	Subject string `json:"subject,omitempty"`
+	// 本文のテンプレート (text/template の形式)
+	Body string `json:"body,omitempty"`
+	// 送信のタイムアウト (例: "30s"、既定は30秒)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// SMTPNotifier はアラートをメールで送信する通知先です。
+// サーバーが STARTTLS に対応している場合は暗号化してから認証と送信を行います。
+type SMTPNotifier struct {
+	// 設定
+	config SMTPConfig
+	// サーバーのホスト名
+	host string
+	// 件名のテンプレート
+	subject *template.Template
+	// 本文のテンプレート
+	body *template.Template
+	// 送信のタイムアウト
+	timeout time.Duration
+}
+
+// NewSMTPNotifier は設定を検証して SMTPNotifier を作成します。設定が不正な場合はエラーを返します。
+func NewSMTPNotifier(config SMTPConfig) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP サーバーのアドレスが不正です: %s", config.Addr)
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("メールの送信者と宛先を指定してください")
+	}
+	for _, address := range append([]string{config.From}, config.To...) {
+		if strings.ContainsAny(address, "\r\n") {
+			return nil, fmt.Errorf("メールのアドレスが不正です: %q", address)
+		}
+	}
+
+	sn := &SMTPNotifier{config: config, host: host}
+	if sn.subject, err = parseTemplate("subject", config.Subject, defaultSubjectTemplate); err != nil {
+		return nil, err
+	}
+	if sn.body, err = parseTemplate("body", config.Body, defaultBodyTemplate); err != nil {
+		return nil, err
+	}
+	if sn.timeout, err = parseDuration(config.Timeout, defaultSMTPTimeout); err != nil {
+		return nil, err
+	}
+
+	return sn, nil
+}
+
+// Notify はアラートをメールで送信します。
+func (sn *SMTPNotifier) Notify(alert models.Alert) error {
+	message, err := sn.message(alert)
+	if err != nil {
+		return err
+	}
+	if err := sn.send(message); err != nil {
+		return fmt.Errorf("メールの送信に失敗しました: %v", err)
+	}
+	return nil
+}
+
+// message はアラートからメールのヘッダーと本文を作成します。
+func (sn *SMTPNotifier) message(alert models.Alert) ([]byte, error) {
+	subject, err := render(sn.subject, alert)
+	if err != nil {
+		return nil, err
+	}
+	body, err := render(sn.body, alert)
+	if err != nil {
+		return nil, err
+	}
+
+	// ヘッダー (件名は改行を除いて符号化する)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", sn.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(sn.config.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", strings.Join(strings.Fields(string(subject)), " ")))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	// 本文
+	writer := quotedprintable.NewWriter(&buf)
+	writer.Write(body)
+	writer.Close()
+
+	return buf.Bytes(), nil
+}
+
+// send は SMTP サーバーに接続してメールを送信します。
+func (sn *SMTPNotifier) send(message []byte) error {
+	conn, err := net.DialTimeout("tcp", sn.config.Addr, sn.timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sn.timeout))
+
+	client, err := smtp.NewClient(conn, sn.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	// 暗号化と認証
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: sn.host}); err != nil {
+			return err
+		}
+	}
+	if sn.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", sn.config.Username, sn.config.Password, sn.host)); err != nil {
+			return err
+		}
+	}
+
+	// 送信
+	if err := client.Mail(sn.config.From); err != nil {
+		return err
+	}
+	for _, to := range sn.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
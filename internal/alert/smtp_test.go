@@ -0,0 +1,155 @@
+package alert
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// fakeSMTPServer はテスト用の最小限の SMTP サーバーです。1つの接続を受け付け、受信したコマンドとメールを記録します。
+type fakeSMTPServer struct {
+	// 待ち受け
+	listener net.Listener
+	// 受信したコマンド一覧
+	commands []string
+	// 受信したメール
+	data string
+	// 接続の終了を通知するチャネル
+	done chan struct{}
+}
+
+// newFakeSMTPServer はローカルのポートで待ち受ける fakeSMTPServer を起動します。
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("待ち受けに失敗: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return s
+}
+
+// serve は1つの接続で SMTP のやりとりを行います。
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, command)
+
+		switch verb := strings.ToUpper(strings.Fields(command + " ")[0]); verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 認証しました")
+		case "DATA":
+			reply("354 本文を送信してください")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 受け付けました")
+		case "QUIT":
+			reply("221 終了します")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// TestSMTPNotifier_Notify は認証、宛先の指定、件名と本文の符号化をテストします。
+func TestSMTPNotifier_Notify(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	sn, err := NewSMTPNotifier(SMTPConfig{
+		Addr:     server.listener.Addr().String(),
+		From:     "logagg@example.com",
+		To:       []string{"ops@example.com", "oncall@example.com"},
+		Username: "logagg",
+		Password: "secret",
+		Subject:  "[{{.State}}] {{.Rule}} のアラート",
+	})
+	if err != nil {
+		t.Fatalf("SMTPNotifier の作成に失敗: %v", err)
+	}
+
+	alert := models.Alert{Rule: "oom", State: models.AlertFiring, Summary: "oom: 直近 1m0s に一致したログが 1 件です", Count: 1, Sample: "java.lang.OutOfMemoryError"}
+	if err := sn.Notify(alert); err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	<-server.done
+
+	// 認証と宛先
+	commands := strings.Join(server.commands, "\n")
+	for _, expected := range []string{"AUTH PLAIN", "MAIL FROM:<logagg@example.com>", "RCPT TO:<ops@example.com>", "RCPT TO:<oncall@example.com>"} {
+		if !strings.Contains(commands, expected) {
+			t.Errorf("コマンド %s が送信されていません: %v", expected, server.commands)
+		}
+	}
+
+	// 件名と本文
+	message, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("メールの解析に失敗: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil || subject != "[firing] oom のアラート" {
+		t.Errorf("件名が期待値と異なります: %s (%v)", subject, err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+	if err != nil {
+		t.Fatalf("本文の復号に失敗: %v", err)
+	}
+	if !strings.Contains(string(body), alert.Summary) || !strings.Contains(string(body), "最後に一致したログ: java.lang.OutOfMemoryError") {
+		t.Errorf("本文が期待値と異なります: %s", body)
+	}
+}
+
+// TestSMTPNotifier_Notify_Unreachable は SMTP サーバーに接続できない場合にエラーを返すことをテストします。
+func TestSMTPNotifier_Notify_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("待ち受けに失敗: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	sn, err := NewSMTPNotifier(SMTPConfig{Addr: addr, From: "logagg@example.com", To: []string{"ops@example.com"}})
+	if err != nil {
+		t.Fatalf("SMTPNotifier の作成に失敗: %v", err)
+	}
+	if err := sn.Notify(models.Alert{Rule: "oom", State: models.AlertFiring}); err == nil {
+		t.Error("接続できない場合にエラーが返されませんでした")
+	}
+}
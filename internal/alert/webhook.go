@@ -0,0 +1,178 @@
+package alert
+
+/*
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * io パッケージは基本的な入出力インターフェースを提供します。
+ * net/http パッケージは HTTP クライアントを提供します。
+ * net/url パッケージは URL の解析を提供します。
+ * text/template パッケージはテキストのテンプレートを提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+const (
+	// defaultWebhookTimeout は Webhook の1回の送信の既定のタイムアウトです。
+	defaultWebhookTimeout = 10 * time.Second
+	// defaultWebhookRetries は Webhook の既定の再送回数です。
+	defaultWebhookRetries = 3
+	// defaultWebhookBackoff は Webhook の最初の再送までの既定の待ち時間です。
+	defaultWebhookBackoff = time.Second
+	// maxWebhookBackoff は Webhook の再送までの待ち時間の上限です。
+	maxWebhookBackoff = 30 * time.Second
+	// maxResponseExcerpt はエラーに含める応答の本文の最大バイト数です。
+	maxResponseExcerpt = 512
+)
+
+// WebhookConfig は JSON の Webhook の設定を表す構造体です。
+type WebhookConfig struct {
+	// 送信先の URL
+	URL string `json:"url"`
+	// HTTP メソッド (既定は POST)
+	Method string `json:"method,omitempty"`
+	// 追加するヘッダー
+	Headers map[string]string `json:"headers,omitempty"`
+	// 本文のテンプレート (text/template の形式、既定はアラートの JSON)
+	// 例: {"text": {{json .Summary}}}
+	Template string `json:"template,omitempty"`
+	// 1回の送信のタイムアウト (例: "10s"、既定は10秒)
+	Timeout string `json:"timeout,omitempty"`
+	// 失敗した場合の再送回数 (既定は3回、負の値は再送しない)
+	Retries int `json:"retries,omitempty"`
+	// 最初の再送までの待ち時間 (例: "1s"、再送ごとに2倍、上限は30秒)
+	Backoff string `json:"backoff,omitempty"`
+}
+
+// WebhookNotifier はアラートを JSON の Webhook に送信する通知先です。
+// 接続の失敗、5xx、429 の応答は待ち時間を2倍にしながら再送し、それ以外の 4xx の応答は再送しません。
+type WebhookNotifier struct {
+	// 設定
+	config WebhookConfig
+	// 本文のテンプレート (既定の場合は nil)
+	template *template.Template
+	// 再送回数
+	retries int
+	// 最初の再送までの待ち時間
+	backoff time.Duration
+	// HTTP クライアント
+	client *http.Client
+	// 再送まで待つ関数
+	sleep func(time.Duration)
+}
+
+// NewWebhookNotifier は設定を検証して WebhookNotifier を作成します。設定が不正な場合はエラーを返します。
+func NewWebhookNotifier(config WebhookConfig) (*WebhookNotifier, error) {
+	// URL の検証
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Webhook の URL が不正です: %s", config.URL)
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+
+	wn := &WebhookNotifier{
+		config:  config,
+		retries: config.Retries,
+		sleep:   time.Sleep,
+	}
+	if config.Retries == 0 {
+		wn.retries = defaultWebhookRetries
+	}
+
+	// テンプレートと時間の解析
+	if config.Template != "" {
+		if wn.template, err = parseTemplate("webhook", config.Template, ""); err != nil {
+			return nil, err
+		}
+	}
+	timeout, err := parseDuration(config.Timeout, defaultWebhookTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if wn.backoff, err = parseDuration(config.Backoff, defaultWebhookBackoff); err != nil {
+		return nil, err
+	}
+	wn.client = &http.Client{Timeout: timeout}
+
+	return wn, nil
+}
+
+// Notify はアラートを Webhook に送信します。再送しても成功しない場合は最後のエラーを返します。
+func (wn *WebhookNotifier) Notify(alert models.Alert) error {
+	// 本文の作成
+	body, err := wn.body(alert)
+	if err != nil {
+		return err
+	}
+
+	// 送信 (失敗した場合は待ち時間を2倍にしながら再送)
+	backoff := wn.backoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := wn.send(body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= wn.retries {
+			return err
+		}
+
+		wn.sleep(backoff)
+		backoff = min(backoff*2, maxWebhookBackoff)
+	}
+}
+
+// body はアラートから送信する本文を作成します。
+func (wn *WebhookNotifier) body(alert models.Alert) ([]byte, error) {
+	if wn.template == nil {
+		return json.Marshal(alert)
+	}
+
+	body, err := render(wn.template, alert)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("Webhook のテンプレートの結果が JSON ではありません: %s", body)
+	}
+	return body, nil
+}
+
+// send は本文を1回送信し、失敗した場合は再送できるかどうかとエラーを返します。
+func (wn *WebhookNotifier) send(body []byte) (bool, error) {
+	req, err := http.NewRequest(wn.config.Method, wn.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range wn.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("Webhook の送信に失敗しました: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("Webhook がエラーを返しました: %s: %s", resp.Status, bytes.TrimSpace(excerpt))
+}
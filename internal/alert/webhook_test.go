@@ -0,0 +1,124 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestWebhookNotifier_Notify はテンプレートから作成した本文とヘッダーの送信と、一時的な失敗の再送をテストします。
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var attempts atomic.Int32
+	var body, token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初の2回は一時的に失敗する
+		if attempts.Add(1) <= 2 {
+			http.Error(w, "メンテナンス中", http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		token = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	wn, err := NewWebhookNotifier(WebhookConfig{
+		URL:      server.URL,
+		Headers:  map[string]string{"Authorization": "Bearer secret"},
+		Template: `{"text": {{json .Summary}}, "state": "{{.State}}"}`,
+	})
+	if err != nil {
+		t.Fatalf("WebhookNotifier の作成に失敗: %v", err)
+	}
+	var waits []time.Duration
+	wn.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	alert := models.Alert{Rule: "oom", State: models.AlertFiring, Summary: `oom: "OutOfMemory" が 1 件です`}
+	if err := wn.Notify(alert); err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	// 待ち時間を2倍にしながら再送する
+	if attempts.Load() != 3 {
+		t.Errorf("送信回数が期待値と異なります: %d", attempts.Load())
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Errorf("再送の待ち時間が期待値と異なります: %v", waits)
+	}
+
+	// 文字列は JSON として埋め込まれる
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("本文が JSON ではありません: %s", body)
+	}
+	if payload["text"] != alert.Summary || payload["state"] != "firing" {
+		t.Errorf("本文が期待値と異なります: %s", body)
+	}
+	if token != "Bearer secret" {
+		t.Errorf("ヘッダーが期待値と異なります: %s", token)
+	}
+}
+
+// TestWebhookNotifier_Notify_Errors は再送しない応答と、再送しても成功しない場合のエラーをテストします。
+func TestWebhookNotifier_Notify_Errors(t *testing.T) {
+	var attempts atomic.Int32
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "不正なリクエストです", status)
+	}))
+	defer server.Close()
+
+	wn, err := NewWebhookNotifier(WebhookConfig{URL: server.URL, Retries: 2})
+	if err != nil {
+		t.Fatalf("WebhookNotifier の作成に失敗: %v", err)
+	}
+	wn.sleep = func(time.Duration) {}
+
+	// 4xx は再送しない
+	err = wn.Notify(models.Alert{Rule: "oom", State: models.AlertFiring})
+	if err == nil || !strings.Contains(err.Error(), "不正なリクエストです") {
+		t.Errorf("応答の本文を含むエラーが返されませんでした: %v", err)
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("4xx の応答が再送されました: %d", attempts.Load())
+	}
+
+	// 5xx は再送回数まで再送する
+	attempts.Store(0)
+	status = http.StatusInternalServerError
+	if err := wn.Notify(models.Alert{Rule: "oom", State: models.AlertFiring}); err == nil {
+		t.Error("再送しても成功しない場合にエラーが返されませんでした")
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("送信回数が期待値と異なります: %d", attempts.Load())
+	}
+}
+
+// TestNewWebhookNotifier_Invalid は不正な設定がエラーになることをテストします。
+func TestNewWebhookNotifier_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config WebhookConfig
+	}{
+		{name: "URL なし", config: WebhookConfig{}},
+		{name: "不正なスキーム", config: WebhookConfig{URL: "ftp://example.com"}},
+		{name: "不正なテンプレート", config: WebhookConfig{URL: "http://example.com", Template: "{{.Rule"}},
+		{name: "不正なタイムアウト", config: WebhookConfig{URL: "http://example.com", Timeout: "10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWebhookNotifier(tt.config); err == nil {
+				t.Error("エラーが発生するはずですが、エラーが発生しませんでした")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package checkpoint
+
+/*
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/reader"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// version は状態ファイルの形式のバージョンです。
+const version = 1
+
+// State は監視を再開するために保存する状態を表す構造体です。
+type State struct {
+	// 状態ファイルの形式のバージョン
+	Version int `json:"version"`
+	// 保存した時刻
+	SavedAt time.Time `json:"saved_at"`
+	// ファイルごとの状態
+	Files []FileState `json:"files"`
+	// ローテーションで読み終えたファイル (名前を変えて再び見つかっても新しいファイルとして扱わない)
+	Consumed []ConsumedFile `json:"consumed,omitempty"`
+}
+
+// ConsumedFile は読み終えたファイルの識別子と、最後に確認したときの大きさと更新時刻を表す構造体です。
+// 再開時は大きさと更新時刻も一致する場合のみ同じファイルとみなし、削除されたファイルの inode を再利用した新しいファイルと区別します。
+type ConsumedFile struct {
+	// デバイス番号と inode 番号
+	reader.FileID
+	// 大きさ
+	Size int64 `json:"size,omitempty"`
+	// 更新時刻 (以前の形式で保存された場合はゼロ値で、識別子のみで判定する)
+	ModTime time.Time `json:"mod_time,omitzero"`
+}
+
+// FileState は1つのファイルの読み込み位置と集約した統計情報を表す構造体です。
+type FileState struct {
+	// ログファイルのパス
+	Path string `json:"path"`
+	// 読み込み位置
+	Tail reader.TailCheckpoint `json:"tail"`
+	// 集約した統計情報 (統合用のスケッチを含む)
+	Stats models.Stats `json:"-"`
+	// 追跡をやめたファイルかどうか (統計情報のみを保持する)
+	Retired bool `json:"retired,omitempty"`
+}
+
+// File は指定したパスのファイルの状態を返します。
+func (s State) File(path string) (FileState, bool) {
+	for _, file := range s.Files {
+		if file.Path == path {
+			return file, true
+		}
+	}
+	return FileState{}, false
+}
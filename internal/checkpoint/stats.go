@@ -0,0 +1,128 @@
+package checkpoint
+
+/*
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ */
+import (
+	"encoding/json"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// savedStats は統計情報と、API の出力には含めない統合用のスケッチをあわせて保存する形式です。
+// 再開後の統計情報を再開前の統計情報とスケッチ単位で統合するために使用します。
+type savedStats struct {
+	// 統計情報
+	models.Stats
+	// フィールドごとの HyperLogLog レジスタ
+	Registers map[string][]uint8 `json:"registers,omitempty"`
+	// 数値フィールドごとの分位点スケッチ
+	Sketches map[string]savedSketches `json:"sketches,omitempty"`
+}
+
+// savedSketches は1つの数値フィールドの分位点スケッチです。
+type savedSketches struct {
+	// 全体のスケッチ
+	Overall *models.SketchState `json:"overall,omitempty"`
+	// グループ別のスケッチ
+	Groups map[string]*models.SketchState `json:"groups,omitempty"`
+	// 時間バケット別のスケッチ (統計情報の時間バケットと同じ順序)
+	Buckets []*models.SketchState `json:"buckets,omitempty"`
+}
+
+// savedFileState は FileState の保存形式です。
+type savedFileState struct {
+	fileStateFields
+	// 統計情報
+	Stats savedStats `json:"stats"`
+}
+
+// fileStateFields は FileState の独自の JSON 変換を持たない別名です。
+type fileStateFields FileState
+
+// MarshalJSON は統合用のスケッチを含めて FileState を JSON に変換します。
+func (fs FileState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(savedFileState{
+		fileStateFields: fileStateFields(fs),
+		Stats:           newSavedStats(fs.Stats),
+	})
+}
+
+// UnmarshalJSON は JSON から統合用のスケッチを含めて FileState を復元します。
+func (fs *FileState) UnmarshalJSON(data []byte) error {
+	var saved savedFileState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	*fs = FileState(saved.fileStateFields)
+	fs.Stats = saved.Stats.restore()
+
+	return nil
+}
+
+// newSavedStats は統計情報から統合用のスケッチを取り出して保存形式を作成します。
+func newSavedStats(stats models.Stats) savedStats {
+	saved := savedStats{Stats: stats}
+
+	for field, estimate := range stats.Distinct {
+		if saved.Registers == nil {
+			saved.Registers = make(map[string][]uint8)
+		}
+		saved.Registers[field] = estimate.Registers
+	}
+
+	for field, report := range stats.Numeric {
+		sketches := savedSketches{Overall: report.Overall.Sketch}
+		for group, summary := range report.Groups {
+			if sketches.Groups == nil {
+				sketches.Groups = make(map[string]*models.SketchState)
+			}
+			sketches.Groups[group] = summary.Sketch
+		}
+		for _, bucket := range report.Buckets {
+			sketches.Buckets = append(sketches.Buckets, bucket.Summary.Sketch)
+		}
+
+		if saved.Sketches == nil {
+			saved.Sketches = make(map[string]savedSketches)
+		}
+		saved.Sketches[field] = sketches
+	}
+
+	return saved
+}
+
+// restore は保存形式から統合用のスケッチを戻した統計情報を返します。
+func (saved savedStats) restore() models.Stats {
+	stats := saved.Stats
+
+	for field, registers := range saved.Registers {
+		if estimate, ok := stats.Distinct[field]; ok {
+			estimate.Registers = registers
+			stats.Distinct[field] = estimate
+		}
+	}
+
+	for field, sketches := range saved.Sketches {
+		report, ok := stats.Numeric[field]
+		if !ok {
+			continue
+		}
+		report.Overall.Sketch = sketches.Overall
+		for group, sketch := range sketches.Groups {
+			if summary, ok := report.Groups[group]; ok {
+				summary.Sketch = sketch
+				report.Groups[group] = summary
+			}
+		}
+		for i, sketch := range sketches.Buckets {
+			if i < len(report.Buckets) {
+				report.Buckets[i].Summary.Sketch = sketch
+			}
+		}
+		stats.Numeric[field] = report
+	}
+
+	return stats
+}
@@ -0,0 +1,96 @@
+package checkpoint
+
+/*
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * errors パッケージはエラーの判定を提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * io/fs パッケージはファイルシステムのエラーを提供します。
+ * os パッケージはファイル操作を提供します。
+ * path/filepath パッケージはファイルパスの操作を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Load は状態ファイルを読み込みます。ファイルが存在しない場合は空の状態を返します。
+func Load(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return State{Version: version}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("状態ファイルを読み込めませんでした: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, fmt.Errorf("状態ファイルの形式が不正です: %w", err)
+	}
+	if state.Version != version {
+		return State{}, fmt.Errorf("未対応の状態ファイルのバージョンです: %d", state.Version)
+	}
+
+	return state, nil
+}
+
+// Save は状態をファイルに保存します。
+// 同じディレクトリの一時ファイルに書き込んでから名前を変更するため、書き込みの途中で停止しても以前の状態ファイルは壊れません。
+func Save(path string, state State) error {
+	state.Version = version
+	state.SavedAt = time.Now()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("状態の変換に失敗しました: %w", err)
+	}
+
+	return WriteFile(path, data)
+}
+
+// WriteFile はデータをファイルに書き込みます。
+// 同じディレクトリの一時ファイルに書き込んでから名前を変更するため、書き込みの途中で停止しても以前のファイルは壊れません。
+func WriteFile(path string, data []byte) error {
+	// 一時ファイルへの書き込み
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("状態ファイルの一時ファイルを作成できませんでした: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+
+	// 名前の変更より先に内容をディスクに書き出す
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+
+	// 名前の変更で置き換える
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("状態ファイルの置き換えに失敗しました: %w", err)
+	}
+
+	// 名前の変更をディスクに書き出す (ディレクトリを同期できないプラットフォームでは無視する)
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
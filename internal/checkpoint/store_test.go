@@ -0,0 +1,113 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/reader"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestSave_Load は状態ファイルの保存と読み込みで、読み込み位置と統合用のスケッチを含む統計情報が復元されることをテストします。
+func TestSave_Load(t *testing.T) {
+	// 異なり数と数値統計を含む統計情報
+	ag := aggregator.NewLogAggregator()
+	ag.Attach(aggregator.NewDistinctCounter("user"))
+	ag.Attach(aggregator.NewNumericCounter("latency", aggregator.NumericOptions{GroupBy: "status"}))
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 100 {
+		ag.Add(models.LogEntry{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Level:     "INFO",
+			Fields:    map[string]string{"user": "u" + strconv.Itoa(i%10), "latency": strconv.Itoa(i), "status": "200"},
+		})
+	}
+	stats := ag.GetStats()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	state := State{
+		Files: []FileState{{
+			Path:  "/var/log/app.log",
+			Tail:  reader.TailCheckpoint{ID: &reader.FileID{Device: 1, Inode: 2}, Offset: 1234, Lines: 100},
+			Stats: stats,
+		}},
+		Consumed: []ConsumedFile{{FileID: reader.FileID{Device: 1, Inode: 3}, Size: 120, ModTime: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}},
+	}
+	if err := Save(path, state); err != nil {
+		t.Fatalf("Save に失敗しました: %v", err)
+	}
+
+	// 一時ファイルが残っていないこと
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("一時ファイルが残っています: %v", entries)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load に失敗しました: %v", err)
+	}
+	file, ok := loaded.File("/var/log/app.log")
+	if !ok {
+		t.Fatalf("ファイルの状態が復元されていません: %+v", loaded)
+	}
+
+	// 読み込み位置
+	if file.Tail.Offset != 1234 || file.Tail.Lines != 100 || file.Tail.ID == nil || file.Tail.ID.Inode != 2 {
+		t.Errorf("読み込み位置が期待値と異なります: %+v", file.Tail)
+	}
+	if len(loaded.Consumed) != 1 || loaded.Consumed[0].Inode != 3 || loaded.Consumed[0].Size != 120 || loaded.Consumed[0].ModTime.IsZero() {
+		t.Errorf("読み終えたファイルが期待値と異なります: %+v", loaded.Consumed)
+	}
+
+	// 統計情報と統合用のスケッチ
+	if file.Stats.TotalCount != 100 || !file.Stats.LastTimestamp.Equal(stats.LastTimestamp) {
+		t.Errorf("統計情報が期待値と異なります: %+v", file.Stats)
+	}
+	if len(file.Stats.Distinct["user"].Registers) == 0 {
+		t.Error("HyperLogLog のレジスタが復元されていません")
+	}
+	numeric := file.Stats.Numeric["latency"]
+	if numeric.Overall.Sketch == nil || numeric.Groups["200"].Sketch == nil {
+		t.Error("分位点スケッチが復元されていません")
+	}
+
+	// 復元した統計情報はスケッチ単位で統合できる
+	merged := aggregator.MergeStats(file.Stats, stats)
+	if merged.Numeric["latency"].Overall.Count != 200 || merged.Distinct["user"].Estimate != stats.Distinct["user"].Estimate {
+		t.Errorf("統合した統計情報が期待値と異なります: %+v", merged)
+	}
+}
+
+// TestLoad_Errors は状態ファイルがない場合と壊れている場合の読み込みをテストします。
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	// ファイルがない場合は空の状態
+	state, err := Load(filepath.Join(dir, "missing.json"))
+	if err != nil || len(state.Files) != 0 {
+		t.Errorf("状態ファイルがない場合は空の状態を返すはずです: %+v, %v", state, err)
+	}
+
+	// 壊れている場合はエラー
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"version": 1, "files": [`), 0644); err != nil {
+		t.Fatalf("ファイルの書き込みに失敗しました: %v", err)
+	}
+	if _, err := Load(broken); err == nil {
+		t.Error("壊れた状態ファイルでエラーが返されませんでした")
+	}
+
+	// 未対応のバージョンはエラー
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version": 99, "files": []}`), 0644); err != nil {
+		t.Fatalf("ファイルの書き込みに失敗しました: %v", err)
+	}
+	if _, err := Load(future); err == nil {
+		t.Error("未対応のバージョンでエラーが返されませんでした")
+	}
+}
@@ -0,0 +1,91 @@
+package deadletter
+
+/*
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ */
+import (
+	"sync"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// Buffer は解析できなかったログ行を直近の一定件数だけメモリに保持する書き込み先です。
+type Buffer struct {
+	// 保持するログ行 (リングバッファ)
+	lines []models.RejectedLine
+	// 次に書き込む位置
+	next int
+	// 保持できる最大数
+	capacity int
+	// これまでに書き込まれた総数
+	total int
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// NewBuffer は直近 capacity 件を保持する Buffer を作成します。
+func NewBuffer(capacity int) *Buffer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Buffer{
+		lines:    make([]models.RejectedLine, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+// Write はログ行を保持します。上限を超えた場合は最も古いものを破棄します。
+func (b *Buffer) Write(line models.RejectedLine) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.lines) < b.capacity {
+		b.lines = append(b.lines, line)
+	} else {
+		b.lines[b.next] = line
+	}
+	b.next = (b.next + 1) % b.capacity
+	b.total++
+
+	return nil
+}
+
+// Lines は保持しているログ行を古い順に返します。file を指定した場合はそのファイルのログ行のみを返します。
+func (b *Buffer) Lines(file string) []models.RejectedLine {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// 上限に達している場合は next が最も古い位置になる
+	start := 0
+	if len(b.lines) == b.capacity {
+		start = b.next
+	}
+
+	var lines []models.RejectedLine
+	for i := 0; i < len(b.lines); i++ {
+		line := b.lines[(start+i)%len(b.lines)]
+		if file == "" || line.File == file {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// Total は破棄したものを含め、これまでに書き込まれたログ行の総数を返します。
+func (b *Buffer) Total() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.total
+}
+
+// Reset は保持しているログ行と総数をリセットします。
+func (b *Buffer) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lines = b.lines[:0]
+	b.next = 0
+	b.total = 0
+}
@@ -0,0 +1,80 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestBuffer_Write は Buffer が直近の行を古い順に保持することをテストします。
+func TestBuffer_Write(t *testing.T) {
+	// Buffer のインスタンスを作成
+	buffer := NewBuffer(3)
+
+	// 2つのファイルから5行を書き込む
+	for i := 1; i <= 5; i++ {
+		file := "a.log"
+		if i%2 == 0 {
+			file = "b.log"
+		}
+		buffer.Write(models.RejectedLine{File: file, Line: i, Raw: fmt.Sprintf("broken %d", i)})
+	}
+
+	// 直近3行が古い順に残る
+	lines := buffer.Lines("")
+	if len(lines) != 3 || lines[0].Line != 3 || lines[2].Line != 5 {
+		t.Errorf("保持している行が期待値と異なります: %+v", lines)
+	}
+	if buffer.Total() != 5 {
+		t.Errorf("期待される総数は 5 ですが、実際の値は %d です", buffer.Total())
+	}
+
+	// ファイルで絞り込む
+	if lines := buffer.Lines("b.log"); len(lines) != 1 || lines[0].Line != 4 {
+		t.Errorf("b.log の行が期待値と異なります: %+v", lines)
+	}
+
+	// リセット
+	buffer.Reset()
+	if len(buffer.Lines("")) != 0 || buffer.Total() != 0 {
+		t.Error("リセット後は空であるべきです")
+	}
+}
+
+// TestFileSink_Write は FileSink が行を JSON Lines 形式で追記することをテストします。
+func TestFileSink_Write(t *testing.T) {
+	path := t.TempDir() + "/rejected.jsonl"
+
+	// 2回に分けて追記する
+	for i := 1; i <= 2; i++ {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+		}
+		if err := sink.Write(models.RejectedLine{File: "app.log", Line: i, Raw: "???", Error: "不明なログレベル"}); err != nil {
+			t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+		}
+		sink.Close()
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	defer file.Close()
+	var lines []models.RejectedLine
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var line models.RejectedLine
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("書き込んだ行の解析に失敗しました: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 || lines[0].Line != 1 || lines[1].Line != 2 || lines[1].Error != "不明なログレベル" {
+		t.Errorf("読み込んだ行が期待値と異なります: %+v", lines)
+	}
+}
@@ -0,0 +1,22 @@
+package deadletter
+
+import "github.com/Yamituki/go-review-logagg/pkg/models"
+
+// Sink は解析できなかったログ行の書き込み先のインターフェースです。
+// 複数のワーカーから同時に呼ばれるため、スレッドセーフに実装します。
+type Sink interface {
+	// Write は解析できなかったログ行を1件書き込みます。
+	Write(line models.RejectedLine) error
+}
+
+// Discard は書き込まれたログ行を保持せずに捨てる書き込み先です。
+// 解析できなかった行の件数のみを数え、処理を続ける場合に使用します。
+var Discard Sink = discard{}
+
+// discard は Discard の実装です。
+type discard struct{}
+
+// Write は何もしません。
+func (discard) Write(line models.RejectedLine) error {
+	return nil
+}
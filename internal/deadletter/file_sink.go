@@ -0,0 +1,58 @@
+package deadletter
+
+/*
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * os パッケージはファイル操作を提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ */
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// FileSink は解析できなかったログ行を JSON Lines 形式でファイルに追記する書き込み先です。
+type FileSink struct {
+	// 書き込み先のファイル
+	file *os.File
+	// 1行ずつ JSON を書き込むエンコーダー
+	encoder *json.Encoder
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// NewFileSink は指定したパスのファイルを追記モードで開き、FileSink を作成します。
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("デッドレターのファイルを開けませんでした: %v", err)
+	}
+
+	return &FileSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// Write はログ行を1行の JSON としてファイルに追記します。
+func (fs *FileSink) Write(line models.RejectedLine) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if err := fs.encoder.Encode(line); err != nil {
+		return fmt.Errorf("デッドレターの書き込みに失敗しました: %v", err)
+	}
+	return nil
+}
+
+// Close はファイルを閉じます。
+func (fs *FileSink) Close() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	return fs.file.Close()
+}
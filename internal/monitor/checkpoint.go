@@ -0,0 +1,35 @@
+package monitor
+
+/*
+ * time パッケージは時間の測定と表示を提供します。
+ */
+import (
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/checkpoint"
+	"github.com/Yamituki/go-review-logagg/internal/processor"
+	"github.com/Yamituki/go-review-logagg/internal/reader"
+)
+
+// checkpointInterval は監視中に状態ファイルを保存する最短の間隔です。停止時には間隔によらず保存します。
+const checkpointInterval = 5 * time.Second
+
+// restoreFile は保存した状態からファイルの読み込み位置と統計情報を復元します。
+func restoreFile(state checkpoint.FileState, tail *reader.TailReader, ip *processor.IncrementalProcessor) error {
+	if err := tail.Restore(state.Tail); err != nil {
+		return err
+	}
+	ip.Restore(state.Stats)
+
+	return nil
+}
+
+// saveFile はファイルの読み込み位置と統計情報を保存する状態を作成します。
+// 処理段に保留しているエントリは統計情報に含まれないため、呼び出す前に finish で集約してください。
+func saveFile(path string, tail *reader.TailReader, ip *processor.IncrementalProcessor) checkpoint.FileState {
+	return checkpoint.FileState{
+		Path:  path,
+		Tail:  tail.Checkpoint(),
+		Stats: ip.GetStats(),
+	}
+}
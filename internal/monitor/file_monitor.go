@@ -2,19 +2,20 @@ package monitor
 
 /*
  * context　パッケージは、キャンセル可能なコンテキストを提供します。
- * fmt パッケージはフォーマットされたI/Oを提供します
- * os パッケージはプラットフォーム非依存のOS機能を提供します。
+ * errors パッケージはエラーの結合を提供します。
  * sync パッケージは基本的な同期プリミティブを提供します。
  * time パッケージは時間の測定と表示を提供します。
  */
 import (
 	"context"
-	"fmt"
-	"os"
+	"errors"
 	"sync"
 	"time"
 
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/checkpoint"
 	"github.com/Yamituki/go-review-logagg/internal/processor"
+	"github.com/Yamituki/go-review-logagg/internal/reader"
 	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
 
@@ -24,12 +25,16 @@ type FileMonitor struct {
 	filePath string
 	// 監視間隔
 	interval time.Duration
-	// データ処理用のコンカレントプロセッサ
-	processor processor.ConcurrentProcessor
+	// 追記された行を集約するプロセッサ
+	processor *processor.IncrementalProcessor
+	// 読み込み済みの位置を記憶するリーダー
+	tail *reader.TailReader
+	// ファイルの変更通知 (使用できない場合は nil)
+	notifier notifier
 	// 監視統計情報
 	stats models.Stats
-	// 最終更新時刻
-	lastModTime time.Time
+	// 監視の状態
+	health *healthTracker
 	// 監視の停止を制御するチャネル
 	mutex sync.Mutex
 	// コンテキスト
@@ -38,6 +43,16 @@ type FileMonitor struct {
 	cancel context.CancelFunc
 	// 監視の停止を通知するチャネル
 	done chan struct{}
+	// 異常検知器 (未設定の場合は nil)
+	detector *aggregator.AnomalyDetector
+	// イベントの購読者への配信
+	broadcaster *broadcaster
+	// 購読者へのエントリと統計情報の更新の配信
+	feed *feed
+	// 状態ファイルのパス (未設定の場合は保存しない)
+	checkpointPath string
+	// 状態ファイルを最後に保存した時刻
+	lastSaved time.Time
 }
 
 // NewFileMonitor は新しい FileMonitor インスタンスを作成します。
@@ -45,29 +60,91 @@ func NewFileMonitor(filePath string, interval time.Duration) *FileMonitor {
 	// コンテキストとキャンセル関数の作成
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &FileMonitor{
-		filePath:  filePath,
-		interval:  interval,
-		processor: *processor.NewConcurrentProcessor(2),
-		stats:     models.Stats{},
-		mutex:     sync.Mutex{},
-		ctx:       ctx,
-		cancel:    cancel,
-		done:      make(chan struct{}),
+	fm := &FileMonitor{
+		filePath:    filePath,
+		interval:    interval,
+		processor:   processor.NewIncrementalProcessor(filePath),
+		tail:        reader.NewTailReader(filePath),
+		stats:       models.Stats{},
+		health:      newHealthTracker(interval),
+		mutex:       sync.Mutex{},
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		broadcaster: newBroadcaster(ctx.Done()),
 	}
+
+	// 集約したエントリを購読者に配信する
+	fm.feed = newFeed(filePath, fm.broadcaster)
+	fm.processor.AddSink(fm.feed)
+
+	return fm
+}
+
+// Processor は追記された行を集約するプロセッサを返します。処理段や拡張の設定は Start の前に行ってください。
+func (fm *FileMonitor) Processor() *processor.IncrementalProcessor {
+	return fm.processor
+}
+
+// Subscribe は新しく集約したエントリと統計情報の更新を受け取る購読を開始します。
+// 購読は Close または Stop で終了し、イベントのチャネルが閉じられます。
+func (fm *FileMonitor) Subscribe(options SubscribeOptions) *Subscription {
+	return fm.broadcaster.subscribe(options)
+}
+
+// SetCheckpointFile は読み込み位置と統計情報を保存する状態ファイルを設定します。Start の前に呼び出してください。
+// Start は状態ファイルから読み込みを再開し、監視中は定期的に、Stop では必ず状態を保存します。
+func (fm *FileMonitor) SetCheckpointFile(path string) {
+	fm.checkpointPath = path
+}
+
+// AttachAnomalyDetector は読み込んだエントリを異常検知器に渡すように設定します。Start の前に呼び出してください。
+// 異常検知器の時間バケットは監視間隔ごとに実際の時計で進めるため、ログが途絶えた場合も急減として検知します。
+func (fm *FileMonitor) AttachAnomalyDetector(detector *aggregator.AnomalyDetector) {
+	fm.detector = detector
+	fm.processor.AddSink(detector)
+}
+
+// Anomalies は異常検知器が検知した異常を返します。異常検知器が未設定の場合は nil を返します。
+func (fm *FileMonitor) Anomalies() []models.Anomaly {
+	if fm.detector == nil {
+		return nil
+	}
+	return fm.detector.Anomalies()
 }
 
 // Start はファイル監視を開始します。
+// Linux では inotify の変更通知で書き込みやローテーションを直ちに検知し、変更がない間の監視間隔ごとの確認を省きます。
+// 変更通知を使用できない場合は監視間隔ごとにファイルを確認します。
+//
+// 読み込みに失敗した場合 (ファイルが存在しない場合を含む) は、再確認までの待ち時間を監視間隔から2倍にしていき (上限は1分)、
+// 成功すると元の監視間隔に戻します。変更通知を受け取った場合は待ち時間によらず直ちに再確認します。
+// 失敗の状況は GetStats の Health で確認できます。
 func (fm *FileMonitor) Start() error {
+	// 状態ファイルからの再開
+	if err := fm.restore(); err != nil {
+		return err
+	}
+
 	// 監視間隔
 	ticker := time.NewTicker(fm.interval)
 
+	// 変更通知の開始 (使用できない場合は nil のまま監視間隔ごとに確認する)
+	var changes <-chan struct{}
+	if n, err := newNotifier(fm.filePath); err == nil {
+		fm.notifier = n
+		changes = n.Changes()
+	}
+
 	// 監視goroutineの開始
 	go func() {
 
 		// Ticker のクリーンアップ
 		defer ticker.Stop()
 
+		// 開始時のファイルの内容を集約
+		dirty := fm.check()
+
 		// 監視ループ
 		for {
 			select {
@@ -75,10 +152,23 @@ func (fm *FileMonitor) Start() error {
 				// 停止完了を通知
 				close(fm.done)
 				return
-			case <-ticker.C:
-				// ファイルの変更をチェックして更新
-				if err := fm.checkAndUpdate(); err != nil {
-					fmt.Printf("監視エラー: %v\n", err)
+			case _, ok := <-changes:
+				if !ok {
+					// 変更通知を続けられなくなった場合は監視間隔ごとの確認に切り替える
+					changes = nil
+					continue
+				}
+				// ファイルの変更を直ちに反映
+				dirty = fm.check()
+			case now := <-ticker.C:
+				// 失敗した後は再確認の時刻まで待ち、変更通知がある場合は未確認の変更がなければ確認を省く
+				if fm.health.due(now) && (changes == nil || dirty) {
+					dirty = fm.check()
+				}
+
+				// 異常検知器の時間バケットを進める (読み込んだエントリを先に反映する)
+				if fm.detector != nil {
+					fm.detector.Tick(now)
 				}
 			}
 		}
@@ -87,58 +177,174 @@ func (fm *FileMonitor) Start() error {
 	return nil
 }
 
+// check はファイルの変更をチェックして更新し、失敗した場合は次の監視で再確認するため true を返します。
+func (fm *FileMonitor) check() bool {
+	err := fm.checkAndUpdate()
+	recordHealth(&fm.mutex, fm.health, err, time.Now())
+	return err != nil
+}
+
 // Stop はファイル監視を停止します。
+// 状態ファイルを設定していない場合は、改行で終わっていない末尾の行を1行として集約してから停止します。
 func (fm *FileMonitor) Stop() error {
 	// 監視の停止を通知
 	fm.cancel()
 	// 停止完了を待機
 	<-fm.done
 
-	return nil
+	// 保留していた末尾の行と処理段のエントリの集約 (状態ファイルがある場合、末尾の行は保留したまま保存し、再開後に続きを読み込む)
+	err := fm.finish(fm.checkpointPath == "")
+
+	// 変更通知と購読の終了
+	if fm.notifier != nil {
+		fm.notifier.Close()
+	}
+	fm.broadcaster.close()
+
+	// 状態の保存
+	err = errors.Join(err, fm.save())
+
+	// 読み込み中のファイルを閉じる
+	fm.tail.Close()
+
+	return err
 }
 
-// GetStats は現在の監視統計情報を取得します。
+// GetStats は現在の監視統計情報を取得します。Health には監視の状態 (最後のエラー、連続した失敗の回数など) が含まれます。
 func (fm *FileMonitor) GetStats() (models.Stats, error) {
 	// ミューテックスのロック
 	fm.mutex.Lock()
 	defer fm.mutex.Unlock()
 
-	return fm.stats, nil
+	stats := fm.stats
+	stats.Health = fm.health.health()
+	return stats, nil
 }
 
-// checkAndUpdate はファイルの変更をチェックし、必要に応じて更新を行います。
+// checkAndUpdate は前回の読み込み以降に追記された行を集約し、統計情報を更新します。
+// 改行で終わっていない末尾の行は書き込みの途中とみなし、改行が追記されるまで集約しません。
 func (fm *FileMonitor) checkAndUpdate() error {
-	// ファイルの情報を取得
-	fileInfo, err := os.Stat(fm.filePath)
-	if err != nil {
+	stats, changed, err := follow(fm.tail, fm.processor)
+	if !changed {
 		return err
 	}
 
-	// 変更時刻を取得
-	modTime := fileInfo.ModTime()
+	// 統計情報の更新
+	fm.mutex.Lock()
+	fm.stats = stats
+	fm.mutex.Unlock()
+
+	// 統計情報の更新を購読者に配信
+	fm.feed.update(stats)
+
+	// 状態の保存 (間隔をあけて保存する)
+	if time.Since(fm.lastSaved) >= checkpointInterval {
+		if saveErr := fm.save(); saveErr != nil {
+			err = errors.Join(err, saveErr)
+		}
+	}
+
+	return err
+}
 
-	if fm.lastModTime.IsZero() {
-		// 初回実行時は最終更新時刻を設定
-		fm.lastModTime = modTime
-	} else if !modTime.After(fm.lastModTime) {
-		// 変更がない場合は終了
+// restore は状態ファイルから読み込み位置と統計情報を復元します。状態ファイルが未設定の場合は何もしません。
+func (fm *FileMonitor) restore() error {
+	if fm.checkpointPath == "" {
 		return nil
 	}
 
-	// ファイルの読み込み
-	stats, err := fm.processor.ProcessFiles([]string{fm.filePath})
+	state, err := checkpoint.Load(fm.checkpointPath)
 	if err != nil {
 		return err
 	}
+	file, ok := state.File(fm.filePath)
+	if !ok {
+		return nil
+	}
+	if err := restoreFile(file, fm.tail, fm.processor); err != nil {
+		return err
+	}
 
-	// ミューテックスのロック
-	fm.mutex.Lock()
-	defer fm.mutex.Unlock()
+	// 統計情報の復元
+	stats := fm.processor.GetStats()
+	stats.Rotations = fm.tail.Events()
 
-	// 統計情報の更新
+	fm.mutex.Lock()
 	fm.stats = stats
-	// 最終更新時刻の更新
-	fm.lastModTime = modTime
+	fm.mutex.Unlock()
 
 	return nil
 }
+
+// save は読み込み位置と統計情報を状態ファイルに保存します。状態ファイルが未設定の場合は何もしません。
+// 処理段に保留しているエントリは保存する読み込み位置より前の行から作られているため、集約してから保存します。
+func (fm *FileMonitor) save() error {
+	if fm.checkpointPath == "" {
+		return nil
+	}
+	fm.finish(false)
+
+	fm.lastSaved = time.Now()
+	return checkpoint.Save(fm.checkpointPath, checkpoint.State{
+		Files: []checkpoint.FileState{saveFile(fm.filePath, fm.tail, fm.processor)},
+	})
+}
+
+// finish は保留しているものを集約し、統計情報を更新します。partial が true の場合は改行で終わっていない末尾の行も集約します。
+func (fm *FileMonitor) finish(partial bool) error {
+	stats, changed, err := finish(fm.tail, fm.processor, partial)
+	if changed {
+		fm.mutex.Lock()
+		fm.stats = stats
+		fm.mutex.Unlock()
+		fm.feed.update(stats)
+	}
+	return err
+}
+
+// follow は追記された行をプロセッサで集約し、統計情報と、確定した行またはローテーションがあったかどうかを返します。
+// ローテーションされた場合は以前のファイルを読み終えてから新しいファイルを読み込みます。
+// 解析できない行で中断した場合も、それまでに集約した行は統計情報に反映します。
+func follow(tail *reader.TailReader, ip *processor.IncrementalProcessor) (models.Stats, bool, error) {
+	// 追記された行の集約
+	processed := 0
+	events := len(tail.Events())
+	err := tail.ReadNewLines(func(lineNumber int, line string) error {
+		processed++
+		return ip.ProcessLine(lineNumber, line)
+	})
+	rotations := tail.Events()
+	if processed == 0 && len(rotations) == events {
+		// 確定した行もローテーションもない
+		return models.Stats{}, false, err
+	}
+
+	// 統計情報の作成
+	stats := ip.GetStats()
+	stats.Rotations = rotations
+
+	return stats, true, err
+}
+
+// finish は処理段に保留しているエントリ (重複抑制でまとめている途中のエントリなど) を集約し、統計情報と、集約したものがあったかどうかを返します。
+// partial が true の場合は、以降の追記を読み込まないものとして改行で終わっていない末尾の行も1行として集約します。
+// 監視の停止、状態の保存、追跡の終了で使用します。
+func finish(tail *reader.TailReader, ip *processor.IncrementalProcessor, partial bool) (models.Stats, bool, error) {
+	var err error
+	changed := false
+	if partial && tail.Pending() {
+		err = tail.Flush(ip.ProcessLine)
+		changed = true
+	}
+	if ip.Flush() > 0 {
+		changed = true
+	}
+	if !changed {
+		return models.Stats{}, false, err
+	}
+
+	stats := ip.GetStats()
+	stats.Rotations = tail.Events()
+
+	return stats, true, err
+}
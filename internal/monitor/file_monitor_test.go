@@ -2,8 +2,14 @@ package monitor
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/checkpoint"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
 
 // TestFileMonitor_Start_Stop は FileMonitor の Start と Stop メソッドのテストを行います。
@@ -161,7 +167,8 @@ func fileCreator() (string, error) {
 	// テスト用のログデータを書き込む
 	logData := `2024-01-01 12:00:00 [INFO] アプリケーションが起動しました。
 2024-01-01 12:05:00 [WARN] メモリ使用量が高くなっています。
-2024-01-01 12:10:00 [ERROR] データベース接続に失敗しました。`
+2024-01-01 12:10:00 [ERROR] データベース接続に失敗しました。
+`
 
 	// ファイルにログデータを書き込む
 	_, err = tempFile.WriteString(logData)
@@ -182,9 +189,9 @@ func fileModifier(filePath string) error {
 	}
 
 	// 追加するログデータ
-	additionalLogData := `
-2024-01-01 12:15:00 [INFO] ユーザーがログインしました。
-2024-01-01 12:20:00 [ERROR] ファイルの読み込みに失敗しました。`
+	additionalLogData := `2024-01-01 12:15:00 [INFO] ユーザーがログインしました。
+2024-01-01 12:20:00 [ERROR] ファイルの読み込みに失敗しました。
+`
 
 	// ファイルに追加のログデータを書き込む
 	_, err = file.WriteString(additionalLogData)
@@ -194,3 +201,300 @@ func fileModifier(filePath string) error {
 
 	return nil
 }
+
+// TestFileMonitor_AnomalyDetector は FileMonitor に付加した異常検知器に読み込んだエントリが渡されることをテストします。
+func TestFileMonitor_AnomalyDetector(t *testing.T) {
+	// テスト用の一時ファイルを作成
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+
+	// FileMonitor の初期化
+	monitoringInterval := 10 * time.Millisecond
+	fileMonitor := NewFileMonitor(filePath, monitoringInterval)
+
+	// 異常検知器の付加
+	detector := aggregator.NewAnomalyDetector(aggregator.DefaultAnomalyConfig())
+	fileMonitor.AttachAnomalyDetector(detector)
+
+	// 監視の開始
+	if err := fileMonitor.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+
+	// 一定時間待機する
+	time.Sleep(50 * time.Millisecond)
+
+	// 監視の停止
+	if err := fileMonitor.Stop(); err != nil {
+		t.Fatalf("FileMonitor の Stop に失敗: %v", err)
+	}
+
+	// 異常検知器にもファイルの内容が渡されていることの検証 (再読み込みでも重複しない)
+	stats := detector.GetStats()
+
+	t.Logf("異常検知器の統計情報: %+v", stats)
+
+	if stats.TotalCount != 3 {
+		t.Errorf("異常検知器の TotalCount が期待値と異なる: 期待値=%d, 実際=%d", 3, stats.TotalCount)
+	}
+
+	// 3件では異常は検知されない
+	if len(fileMonitor.Anomalies()) != 0 {
+		t.Errorf("異常は検知されないはずです: %+v", fileMonitor.Anomalies())
+	}
+}
+
+// TestFileMonitor_GetStats_Incremental は FileMonitor が追記された行だけを集約し、既に集約した行を再度数えないことをテストします。
+func TestFileMonitor_GetStats_Incremental(t *testing.T) {
+	// テスト用の一時ファイルを作成
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	// 監視ループを介さずに checkAndUpdate を直接呼び出す
+	fileMonitor := NewFileMonitor(filePath, time.Hour)
+
+	if err := fileMonitor.checkAndUpdate(); err != nil {
+		t.Fatalf("checkAndUpdate に失敗: %v", err)
+	}
+	if stats, _ := fileMonitor.GetStats(); stats.TotalCount != 3 {
+		t.Fatalf("TotalCount が期待値と異なる: 期待値=%d, 実際=%d", 3, stats.TotalCount)
+	}
+
+	// 既に集約した部分を書き換えても再度読み込まない
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("ファイルを開けませんでした: %v", err)
+	}
+	if _, err := file.WriteAt([]byte("2024-01-01 12:00:00 [WARN]"), 0); err != nil {
+		t.Fatalf("ファイルの書き換えに失敗: %v", err)
+	}
+	file.Close()
+
+	// 追記
+	if err := fileModifier(filePath); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+	if err := fileMonitor.checkAndUpdate(); err != nil {
+		t.Fatalf("checkAndUpdate に失敗: %v", err)
+	}
+
+	stats, _ := fileMonitor.GetStats()
+
+	t.Logf("取得した統計情報: %+v", stats)
+
+	if stats.TotalCount != 5 || stats.InfoCount != 2 || stats.WarnCount != 1 || stats.ErrorCount != 2 {
+		t.Errorf("統計情報が期待値と異なる: %+v", stats)
+	}
+}
+
+// TestFileMonitor_PartialLine は FileMonitor が改行で終わっていない末尾の行を書き込みが止まっても集約せず、停止時に集約することをテストします。
+func TestFileMonitor_PartialLine(t *testing.T) {
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	// 書き込み途中の末尾の行
+	if err := appendLine(filePath, "2024-01-01 12:15:00 [INFO] ユーザーが"); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+
+	fileMonitor := NewFileMonitor(filePath, 10*time.Millisecond)
+	if err := fileMonitor.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	waitTotal(t, fileMonitor, 3)
+
+	// 何回監視しても末尾の行は保留したまま
+	time.Sleep(50 * time.Millisecond)
+	if stats, _ := fileMonitor.GetStats(); stats.TotalCount != 3 || stats.Health.LastError != "" {
+		t.Errorf("書き込み途中の行が集約されました: %+v", stats)
+	}
+
+	// 停止時に末尾の行を集約する
+	if err := fileMonitor.Stop(); err != nil {
+		t.Fatalf("FileMonitor の Stop に失敗: %v", err)
+	}
+	if stats, _ := fileMonitor.GetStats(); stats.TotalCount != 4 || stats.InfoCount != 2 {
+		t.Errorf("停止時に末尾の行が集約されていません: %+v", stats)
+	}
+}
+
+// TestFileMonitor_GetStats_Rotation は FileMonitor がローテーションの前後のログを重複も欠落もなく集約し、ローテーションを記録することをテストします。
+func TestFileMonitor_GetStats_Rotation(t *testing.T) {
+	// テスト用の一時ファイルを作成
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".1")
+
+	fileMonitor := NewFileMonitor(filePath, time.Hour)
+	defer fileMonitor.tail.Close()
+
+	if err := fileMonitor.checkAndUpdate(); err != nil {
+		t.Fatalf("checkAndUpdate に失敗: %v", err)
+	}
+
+	// logrotate による名前の変更と再作成
+	if err := fileModifier(filePath); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+	if err := os.Rename(filePath, filePath+".1"); err != nil {
+		t.Fatalf("ファイル名の変更に失敗: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("2024-01-01 12:25:00 [WARN] 再作成されたファイルです。\n"), 0644); err != nil {
+		t.Fatalf("ファイルの再作成に失敗: %v", err)
+	}
+	if err := fileMonitor.checkAndUpdate(); err != nil {
+		t.Fatalf("checkAndUpdate に失敗: %v", err)
+	}
+
+	stats, _ := fileMonitor.GetStats()
+
+	t.Logf("取得した統計情報: %+v", stats)
+
+	// 以前のファイルの5行と新しいファイルの1行
+	if stats.TotalCount != 6 || stats.InfoCount != 2 || stats.WarnCount != 2 || stats.ErrorCount != 2 {
+		t.Errorf("統計情報が期待値と異なる: %+v", stats)
+	}
+	if len(stats.Rotations) != 1 || stats.Rotations[0].Kind != models.Rotated {
+		t.Errorf("ローテーションの記録が期待値と異なる: %+v", stats.Rotations)
+	}
+}
+
+// appendLine はファイルに文字列を追記します。
+func appendLine(path, data string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(data)
+	return err
+}
+
+// TestFileMonitor_Checkpoint は状態ファイルから再開した FileMonitor が集約済みの行を数え直さず、停止中の追記も欠落しないことをテストします。
+func TestFileMonitor_Checkpoint(t *testing.T) {
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	defer os.Remove(filePath)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	// 書き込み途中の末尾の行
+	if err := appendLine(filePath, "2024-01-01 12:15:00 [INFO] ユーザーが"); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+
+	// 1回目の監視 (末尾の行は保留中のまま停止する)
+	first := NewFileMonitor(filePath, time.Hour)
+	first.SetCheckpointFile(statePath)
+	if err := first.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	waitTotal(t, first, 3)
+	if err := first.Stop(); err != nil {
+		t.Fatalf("FileMonitor の Stop に失敗: %v", err)
+	}
+
+	// 停止中に末尾の行の続きを追記
+	if err := appendLine(filePath, "ログインしました。\n2024-01-01 12:20:00 [ERROR] ファイルの読み込みに失敗しました。\n"); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+
+	// 2回目の監視は状態ファイルから再開する
+	second := NewFileMonitor(filePath, time.Hour)
+	second.SetCheckpointFile(statePath)
+	if err := second.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	waitTotal(t, second, 5)
+	if err := second.Stop(); err != nil {
+		t.Fatalf("FileMonitor の Stop に失敗: %v", err)
+	}
+
+	stats, _ := second.GetStats()
+
+	t.Logf("取得した統計情報: %+v", stats)
+
+	if stats.TotalCount != 5 || stats.InfoCount != 2 || stats.WarnCount != 1 || stats.ErrorCount != 2 {
+		t.Errorf("統計情報が期待値と異なる: %+v", stats)
+	}
+}
+
+// TestFileMonitor_Checkpoint_PendingStages は処理段に保留しているエントリを集約してから状態ファイルを保存し、再開後に失わないことをテストします。
+func TestFileMonitor_Checkpoint_PendingStages(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "app.log")
+	logData := `2024-01-01 12:00:00 [ERROR] データベース接続に失敗しました。
+2024-01-01 12:00:01 [ERROR] データベース接続に失敗しました。
+2024-01-01 12:00:02 [ERROR] データベース接続に失敗しました。
+`
+	if err := os.WriteFile(filePath, []byte(logData), 0644); err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	// 重複抑制は入力の終わりまでまとめたエントリを保留する
+	newMonitor := func() *FileMonitor {
+		fm := NewFileMonitor(filePath, time.Hour)
+		fm.SetCheckpointFile(statePath)
+		fm.Processor().AddStage(func() pipeline.Stage { return pipeline.NewDedupStage(pipeline.DedupConfig{}) })
+		return fm
+	}
+
+	// 1回目の監視は停止時に保留していたエントリを集約して保存する
+	first := newMonitor()
+	if err := first.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	if err := first.Stop(); err != nil {
+		t.Fatalf("FileMonitor の Stop に失敗: %v", err)
+	}
+	if stats, _ := first.GetStats(); stats.TotalCount != 3 {
+		t.Errorf("停止時に保留していたエントリが集約されていません: %+v", stats)
+	}
+	state, err := checkpoint.Load(statePath)
+	if err != nil {
+		t.Fatalf("状態ファイルの読み込みに失敗: %v", err)
+	}
+	if file, ok := state.File(filePath); !ok || file.Stats.TotalCount != 3 || file.Stats.ErrorCount != 3 {
+		t.Errorf("保存した統計情報が期待値と異なる: %+v", file.Stats)
+	}
+
+	// 2回目の監視は保存した統計情報から再開する
+	second := newMonitor()
+	if err := second.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	defer second.Stop()
+	if stats, _ := second.GetStats(); stats.TotalCount != 3 {
+		t.Errorf("再開後の統計情報が期待値と異なる: %+v", stats)
+	}
+}
+
+// waitTotal は統計情報の TotalCount が期待値になるまで待ちます。
+func waitTotal(t *testing.T, m Monitor, expected int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats, _ := m.GetStats()
+		if stats.TotalCount == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("TotalCount が %d になりませんでした: %+v", expected, stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,91 @@
+package monitor
+
+/*
+ * errors パッケージはエラーの判定を提供します。
+ * io/fs パッケージはファイルシステムのエラーを提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * time パッケージは時間の測定と表示を提供します。
+ */
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// maxRetryBackoff は失敗した場合に再確認するまでの待ち時間の上限です (監視間隔の方が長い場合は監視間隔)。
+const maxRetryBackoff = time.Minute
+
+// healthTracker は監視の成功と失敗を記録し、失敗した場合の再確認の時刻を決める構造体です。
+// 失敗するたびに再確認までの待ち時間を監視間隔から2倍にしていき、成功すると元に戻します。
+type healthTracker struct {
+	// 現在の状態
+	status models.MonitorHealth
+	// 監視間隔 (最初の再確認までの待ち時間)
+	interval time.Duration
+}
+
+// newHealthTracker は新しい healthTracker を作成します。
+func newHealthTracker(interval time.Duration) *healthTracker {
+	return &healthTracker{
+		status:   models.MonitorHealth{State: models.HealthOK},
+		interval: interval,
+	}
+}
+
+// succeed は正常に読み込めたことを記録します。
+func (ht *healthTracker) succeed(now time.Time) {
+	ht.status.State = models.HealthOK
+	ht.status.ConsecutiveFailures = 0
+	ht.status.LastReadAt = now
+	ht.status.NextRetryAt = time.Time{}
+}
+
+// fail は失敗したことを記録して次に再確認する時刻を決めます。
+// ファイルが存在しない場合は、作成されるのを待っている状態として扱います。
+func (ht *healthTracker) fail(err error, now time.Time) {
+	ht.status.State = models.HealthFailing
+	if errors.Is(err, fs.ErrNotExist) {
+		ht.status.State = models.HealthWaiting
+	}
+	ht.status.LastError = err.Error()
+	ht.status.LastErrorAt = now
+	ht.status.ConsecutiveFailures++
+	ht.status.NextRetryAt = now.Add(retryBackoff(ht.interval, ht.status.ConsecutiveFailures))
+}
+
+// due は再確認の時刻になっているかどうかを返します。失敗していない場合は常に true を返します。
+func (ht *healthTracker) due(now time.Time) bool {
+	return !now.Before(ht.status.NextRetryAt)
+}
+
+// health は現在の状態のコピーを返します。
+func (ht *healthTracker) health() *models.MonitorHealth {
+	status := ht.status
+	return &status
+}
+
+// recordHealth は確認の結果を監視の状態に記録します。状態は mutex で保護します。
+// エラーと回復は出力せず、監視の状態 (GetStats の Health) からのみ参照できます。
+func recordHealth(mutex *sync.Mutex, ht *healthTracker, err error, now time.Time) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err != nil {
+		ht.fail(err, now)
+	} else {
+		ht.succeed(now)
+	}
+}
+
+// retryBackoff は連続した失敗の回数に応じた再確認までの待ち時間を返します。
+func retryBackoff(interval time.Duration, failures int) time.Duration {
+	limit := max(maxRetryBackoff, interval)
+	backoff := interval
+	for i := 1; i < failures && backoff < limit; i++ {
+		backoff *= 2
+	}
+	return min(backoff, limit)
+}
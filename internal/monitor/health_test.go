@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestHealthTracker は失敗と回復の記録と再確認の時刻のテストを行います。
+func TestHealthTracker(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	ht := newHealthTracker(time.Second)
+
+	// ファイルが存在しない場合は作成を待つ状態
+	notExist := fmt.Errorf("open app.log: %w", fs.ErrNotExist)
+	ht.fail(notExist, now)
+	if ht.status.State != models.HealthWaiting || ht.status.ConsecutiveFailures != 1 {
+		t.Errorf("監視の状態が期待値と異なります: %+v", ht.status)
+	}
+	if ht.due(now.Add(999*time.Millisecond)) || !ht.due(now.Add(time.Second)) {
+		t.Errorf("再確認の時刻が期待値と異なります: %v", ht.status.NextRetryAt)
+	}
+
+	// 続けて失敗すると待ち時間を2倍にする
+	ht.fail(notExist, now)
+	if ht.status.ConsecutiveFailures != 2 {
+		t.Errorf("連続した失敗の回数が期待値と異なります: %d", ht.status.ConsecutiveFailures)
+	}
+	if expected := now.Add(2 * time.Second); !ht.status.NextRetryAt.Equal(expected) {
+		t.Errorf("再確認の時刻が期待値と異なります: 期待値 %v, 実際 %v", expected, ht.status.NextRetryAt)
+	}
+
+	// 異なるエラーは最後のエラーとして記録する
+	ht.fail(errors.New("permission denied"), now)
+	if ht.status.State != models.HealthFailing || ht.status.LastError != "permission denied" {
+		t.Errorf("異なるエラーが記録されていません: %+v", ht.status)
+	}
+
+	// 回復すると失敗の回数と待ち時間を戻し、最後のエラーは保持する
+	ht.succeed(now)
+	if ht.status.State != models.HealthOK || ht.status.ConsecutiveFailures != 0 || !ht.due(now) ||
+		ht.status.LastError != "permission denied" || !ht.status.LastReadAt.Equal(now) {
+		t.Errorf("回復後の監視の状態が期待値と異なります: %+v", ht.status)
+	}
+}
+
+// TestRetryBackoff は再確認までの待ち時間のテストを行います。
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{time.Second, 1, time.Second},
+		{time.Second, 3, 4 * time.Second},
+		{time.Second, 10, time.Minute},
+		{time.Second, 1000, time.Minute},
+		{2 * time.Minute, 5, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if actual := retryBackoff(tt.interval, tt.failures); actual != tt.expected {
+			t.Errorf("retryBackoff(%v, %d): 期待値 %v, 実際 %v", tt.interval, tt.failures, tt.expected, actual)
+		}
+	}
+}
+
+// TestFileMonitor_Health はファイルが作成されるまでの待機と回復のテストを行います。
+func TestFileMonitor_Health(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fm := NewFileMonitor(path, 10*time.Millisecond)
+	if err := fm.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	defer fm.Stop()
+
+	// ファイルが作成されるのを待つ
+	waitHealth(t, fm, func(health *models.MonitorHealth) bool {
+		return health.State == models.HealthWaiting && health.ConsecutiveFailures > 0 && health.LastError != ""
+	})
+
+	// ファイルが作成されると回復して読み込む (書き込み途中の空のファイルを読まないように別名で書き込んでから置き換える)
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, []byte("2024-10-01 12:00:00 [INFO] 起動しました\n"), 0644); err != nil {
+		t.Fatalf("ファイルの作成に失敗: %v", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		t.Fatalf("ファイルの置き換えに失敗: %v", err)
+	}
+	waitTotal(t, fm, 1)
+	waitHealth(t, fm, func(health *models.MonitorHealth) bool {
+		return health.State == models.HealthOK && health.ConsecutiveFailures == 0 && !health.LastReadAt.IsZero()
+	})
+}
+
+// TestMultiFileMonitor_Health は一致するファイルがない場合の待機と、ファイルごとの監視の状態のテストを行います。
+func TestMultiFileMonitor_Health(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultMultiFileConfig()
+	config.Patterns = []string{filepath.Join(dir, "*.log")}
+	mm := NewMultiFileMonitor(config)
+	defer mm.Stop()
+
+	now := time.Now()
+	if err := mm.update(now); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if stats, _ := mm.GetStats(); stats.Health == nil || stats.Health.State != models.HealthWaiting {
+		t.Errorf("一致するファイルがない場合の監視の状態が期待値と異なります: %+v", stats.Health)
+	}
+
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("2024-10-01 12:00:00 [INFO] 起動しました\n"), 0644); err != nil {
+		t.Fatalf("ファイルの作成に失敗: %v", err)
+	}
+	if err := mm.update(now.Add(time.Second)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if stats, _ := mm.GetStats(); stats.Health == nil || stats.Health.State != models.HealthOK {
+		t.Errorf("ファイルを読み込んだ後の監視の状態が期待値と異なります: %+v", stats.Health)
+	}
+	if health := mm.FileStats()[path].Health; health == nil || !health.LastReadAt.Equal(now.Add(time.Second)) {
+		t.Errorf("ファイルごとの監視の状態が期待値と異なります: %+v", health)
+	}
+}
+
+// waitHealth は監視の状態が条件を満たすまで待ちます。
+func waitHealth(t *testing.T, m Monitor, condition func(*models.MonitorHealth) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats, _ := m.GetStats()
+		if stats.Health != nil && condition(stats.Health) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("監視の状態が期待値になりません: %+v", stats.Health)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -10,4 +10,6 @@ type Monitor interface {
 	Stop() error
 	// GetStats は現在の監視統計情報を取得します。
 	GetStats() (models.Stats, error)
+	// Subscribe は新しく集約したエントリと統計情報の更新を受け取る購読を開始します。
+	Subscribe(options SubscribeOptions) *Subscription
 }
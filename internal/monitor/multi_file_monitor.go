@@ -0,0 +1,647 @@
+package monitor
+
+/*
+ * context パッケージは、キャンセル可能なコンテキストを提供します。
+ * errors パッケージはエラーの判定を提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * io/fs パッケージはファイルシステムのエラーを提供します。
+ * os パッケージはプラットフォーム非依存のOS機能を提供します。
+ * path/filepath パッケージはファイルパスの操作を提供します。
+ * sort パッケージはスライスのソートを提供します。
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * time パッケージは時間の測定と表示を提供します。
+ */
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/checkpoint"
+	"github.com/Yamituki/go-review-logagg/internal/processor"
+	"github.com/Yamituki/go-review-logagg/internal/reader"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// maxConsumedFiles は読み終えたファイルとして記憶する最大数です (古いものから破棄)。
+const maxConsumedFiles = 1000
+
+// MultiFileConfig は複数ファイルの監視の設定を表す構造体です。
+type MultiFileConfig struct {
+	// 監視するファイルのパターン (filepath.Glob の形式) またはディレクトリ (直下のファイルを監視)
+	Patterns []string
+	// 監視間隔
+	Interval time.Duration
+	// 削除されたファイルの追跡をやめるまでの猶予 (この間に再作成された場合は追跡を続ける)
+	GracePeriod time.Duration
+}
+
+// DefaultMultiFileConfig は複数ファイルの監視の既定の設定を返します。
+func DefaultMultiFileConfig() MultiFileConfig {
+	return MultiFileConfig{
+		Interval:    time.Second,
+		GracePeriod: time.Minute,
+	}
+}
+
+// followedFile は追跡中の1つのファイルの状態です。
+type followedFile struct {
+	// 読み込み済みの位置を記憶するリーダー
+	tail *reader.TailReader
+	// 追記された行を集約するプロセッサ
+	processor *processor.IncrementalProcessor
+	// 購読者へのエントリと統計情報の更新の配信
+	feed *feed
+	// 統計情報
+	stats models.Stats
+	// 監視の状態
+	health *healthTracker
+	// パスのファイルがなくなった時刻 (存在する場合はゼロ値)
+	missingSince time.Time
+}
+
+// consumedFile は読み終えたファイルです。
+// ファイルを探索するたびにパターンに一致するファイルから探し、見つからなくなった (削除された) ものは忘れるため、
+// 削除されたファイルの inode を再利用した新しいファイルを読み終えたファイルとして扱いません。
+type consumedFile struct {
+	// 最後に確認したファイルの情報 (状態ファイルから復元した場合は確認するまで nil)
+	info os.FileInfo
+	// 状態ファイルから復元した識別子と大きさ、更新時刻
+	saved checkpoint.ConsumedFile
+}
+
+// matches は指定したファイルが読み終えたファイルと同一かどうかを返します。
+// 状態ファイルから復元した場合は、停止中に inode が再利用されていないよう大きさと更新時刻も一致する必要があります。
+func (cf consumedFile) matches(info os.FileInfo) bool {
+	if cf.info != nil {
+		return os.SameFile(cf.info, info)
+	}
+	id, ok := reader.IdentifyFile(info)
+	if !ok || id != cf.saved.FileID {
+		return false
+	}
+	return cf.saved.ModTime.IsZero() || (info.Size() == cf.saved.Size && info.ModTime().Equal(cf.saved.ModTime))
+}
+
+// MultiFileMonitor はパターンに一致する複数のファイルを監視し、新しく現れたファイルも追跡する構造体です。
+// 統計情報はファイルごとと、すべてのファイルを統合したものを取得できます。
+type MultiFileMonitor struct {
+	// 設定
+	config MultiFileConfig
+	// 監視するパターン (ディレクトリは直下のすべてのファイルのパターンに変換済み)
+	patterns []string
+	// 新しく追跡するファイルのプロセッサの設定
+	configure func(*processor.IncrementalProcessor)
+	// 追跡中のファイル (キーはパス)
+	files map[string]*followedFile
+	// 追跡をやめたファイルの統計情報 (キーはパス)
+	retired map[string]models.Stats
+	// ローテーションで読み終えたファイル (名前を変えてパターンに一致しても新しいファイルとして扱わない)
+	consumed []consumedFile
+	// 状態ファイルのパス (未設定の場合は保存しない)
+	checkpointPath string
+	// 状態ファイルを最後に保存した時刻
+	lastSaved time.Time
+	// ファイルの探索と状態の保存の状態 (ファイルごとの状態は followedFile が持つ)
+	health *healthTracker
+	// ファイルの変更通知 (使用できない場合は nil)
+	notifier notifier
+	// イベントの購読者への配信
+	broadcaster *broadcaster
+	// 統計情報と監視の状態を保護するミューテックス
+	mutex sync.Mutex
+	// コンテキスト
+	ctx context.Context
+	// コンテキストのキャンセル関数
+	cancel context.CancelFunc
+	// 監視の停止を通知するチャネル
+	done chan struct{}
+	// 監視を開始したかどうか
+	started bool
+}
+
+// NewMultiFileMonitor は指定した設定で MultiFileMonitor の新しいインスタンスを作成します。
+func NewMultiFileMonitor(config MultiFileConfig) *MultiFileMonitor {
+	// 不正な設定値は既定値で補う
+	defaults := DefaultMultiFileConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.GracePeriod < 0 {
+		config.GracePeriod = 0
+	}
+
+	// ディレクトリは直下のすべてのファイルを監視する
+	patterns := make([]string, 0, len(config.Patterns))
+	for _, pattern := range config.Patterns {
+		if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+			pattern = filepath.Join(pattern, "*")
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	// コンテキストとキャンセル関数の作成
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &MultiFileMonitor{
+		config:      config,
+		patterns:    patterns,
+		files:       make(map[string]*followedFile),
+		retired:     make(map[string]models.Stats),
+		health:      newHealthTracker(config.Interval),
+		broadcaster: newBroadcaster(ctx.Done()),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+}
+
+// ConfigureProcessor は新しく追跡するファイルごとのプロセッサの設定 (処理段や拡張の追加) を行う関数を設定します。Start の前に呼び出してください。
+func (mm *MultiFileMonitor) ConfigureProcessor(configure func(*processor.IncrementalProcessor)) {
+	mm.configure = configure
+}
+
+// Subscribe は新しく集約したエントリとファイルごとの統計情報の更新を受け取る購読を開始します。
+// 購読は Close または Stop で終了し、イベントのチャネルが閉じられます。
+func (mm *MultiFileMonitor) Subscribe(options SubscribeOptions) *Subscription {
+	return mm.broadcaster.subscribe(options)
+}
+
+// SetCheckpointFile は読み込み位置と統計情報を保存する状態ファイルを設定します。Start の前に呼び出してください。
+// Start は状態ファイルから追跡中のファイルと追跡をやめたファイルの統計情報を復元し、監視中は定期的に、Stop では必ず状態を保存します。
+func (mm *MultiFileMonitor) SetCheckpointFile(path string) {
+	mm.checkpointPath = path
+}
+
+// Start はファイル監視を開始します。
+// Linux ではパターンのディレクトリを inotify で監視し、ファイルの書き込みや作成を直ちに反映します。
+// 読み込みに失敗したファイルは、ファイルごとに再確認までの待ち時間を監視間隔から2倍にしていき (上限は1分)、その間は変更通知を受け取っても確認を省きます。
+func (mm *MultiFileMonitor) Start() error {
+	// パターンの検証
+	for _, pattern := range mm.patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("不正なパターンです: %s: %w", pattern, err)
+		}
+	}
+
+	// 状態ファイルからの再開
+	if err := mm.restore(); err != nil {
+		return err
+	}
+
+	// 監視間隔
+	ticker := time.NewTicker(mm.config.Interval)
+
+	// 変更通知の開始 (使用できない場合は nil のまま監視間隔ごとに確認する)
+	var changes <-chan struct{}
+	if n, err := newNotifier(mm.patterns...); err == nil {
+		mm.notifier = n
+		changes = n.Changes()
+	}
+
+	// 監視goroutineの開始
+	mm.started = true
+	go func() {
+
+		// Ticker のクリーンアップ
+		defer ticker.Stop()
+
+		// 開始時のファイルの内容を集約
+		dirty := mm.check()
+
+		// 監視ループ
+		for {
+			select {
+			case <-mm.ctx.Done():
+				// 停止完了を通知
+				close(mm.done)
+				return
+			case _, ok := <-changes:
+				if !ok {
+					// 変更通知を続けられなくなった場合は監視間隔ごとの確認に切り替える
+					changes = nil
+					continue
+				}
+				// ファイルの変更を直ちに反映
+				dirty = mm.check()
+			case <-ticker.C:
+				// 変更通知がある場合は、未確認の変更や猶予中の削除されたファイルがなければ確認を省く
+				if changes != nil && !dirty && !mm.waiting() {
+					continue
+				}
+				dirty = mm.check()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop はファイル監視を停止します。
+// 状態ファイルを設定していない場合は、各ファイルの改行で終わっていない末尾の行を1行として集約してから停止します。
+func (mm *MultiFileMonitor) Stop() error {
+	// 監視の停止を通知
+	mm.cancel()
+	// 停止完了を待機 (開始していない場合は待たずにファイルを閉じる)
+	if mm.started {
+		<-mm.done
+	}
+
+	// 保留していた末尾の行と処理段のエントリの集約 (状態ファイルがある場合、末尾の行は保留したまま保存し、再開後に続きを読み込む)
+	var errs []error
+	for path, file := range mm.files {
+		if err := mm.finish(file, mm.checkpointPath == ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	// 変更通知と購読の終了
+	if mm.notifier != nil {
+		mm.notifier.Close()
+	}
+	mm.broadcaster.close()
+
+	// 状態の保存
+	err := errors.Join(append(errs, mm.save())...)
+
+	// 読み込み中のファイルを閉じる
+	for _, file := range mm.files {
+		file.tail.Close()
+	}
+
+	return err
+}
+
+// GetStats はすべてのファイル (追跡をやめたファイルを含む) を統合した統計情報を取得します。
+// Health は最も深刻なファイルの状態です。一致するファイルがまだない場合は waiting_for_file になります。
+func (mm *MultiFileMonitor) GetStats() (models.Stats, error) {
+	var stats models.Stats
+	for _, fileStats := range mm.FileStats() {
+		stats = aggregator.MergeStats(stats, fileStats)
+	}
+
+	// ファイルの探索の状態を統合
+	mm.mutex.Lock()
+	health := mm.health.health()
+	if len(mm.files) == 0 && health.State == models.HealthOK {
+		health.State = models.HealthWaiting
+	}
+	mm.mutex.Unlock()
+	stats = aggregator.MergeStats(stats, models.Stats{Health: health})
+
+	// ローテーションの記録は時刻順に並べる
+	sort.SliceStable(stats.Rotations, func(i, j int) bool {
+		return stats.Rotations[i].DetectedAt.Before(stats.Rotations[j].DetectedAt)
+	})
+
+	return stats, nil
+}
+
+// FileStats はファイルごとの統計情報 (追跡をやめたファイルを含む) を取得します。キーはパスです。
+// 追跡中のファイルの Health にはファイルごとの監視の状態が含まれます。
+func (mm *MultiFileMonitor) FileStats() map[string]models.Stats {
+	// ミューテックスのロック
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	stats := make(map[string]models.Stats, len(mm.files)+len(mm.retired))
+	for path, retired := range mm.retired {
+		stats[path] = retired
+	}
+	for path, file := range mm.files {
+		fileStats := file.stats
+		fileStats.Health = file.health.health()
+		stats[path] = aggregator.MergeStats(stats[path], fileStats)
+	}
+
+	return stats
+}
+
+// Files は追跡中のファイルのパスを名前順に返します。
+func (mm *MultiFileMonitor) Files() []string {
+	// ミューテックスのロック
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	paths := make([]string, 0, len(mm.files))
+	for path := range mm.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// check はファイルの変更をチェックして更新し、失敗した場合は次の監視で再確認するため true を返します。
+// エラーは update でファイルごとに監視の状態に記録します。
+func (mm *MultiFileMonitor) check() bool {
+	err := mm.update(time.Now())
+
+	// 状態の保存 (間隔をあけて保存する)
+	if time.Since(mm.lastSaved) >= checkpointInterval {
+		if saveErr := mm.save(); saveErr != nil {
+			recordHealth(&mm.mutex, mm.health, saveErr, time.Now())
+			err = errors.Join(err, saveErr)
+		}
+	}
+
+	return err != nil
+}
+
+// waiting は次の監視で確認が必要な状態 (猶予中の削除されたファイル) があるかどうかを返します。
+func (mm *MultiFileMonitor) waiting() bool {
+	for _, file := range mm.files {
+		if !file.missingSince.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// restore は状態ファイルから追跡中のファイルと追跡をやめたファイルの統計情報を復元します。状態ファイルが未設定の場合は何もしません。
+func (mm *MultiFileMonitor) restore() error {
+	if mm.checkpointPath == "" {
+		return nil
+	}
+
+	state, err := checkpoint.Load(mm.checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	for _, saved := range state.Files {
+		// 追跡をやめたファイルは統計情報のみ
+		if saved.Retired {
+			mm.retired[saved.Path] = saved.Stats
+			continue
+		}
+
+		file := mm.newFile(saved.Path)
+		if err := restoreFile(saved, file.tail, file.processor); err != nil {
+			return fmt.Errorf("%s: %w", saved.Path, err)
+		}
+		file.stats = file.processor.GetStats()
+		file.stats.Rotations = file.tail.Events()
+		mm.files[saved.Path] = file
+	}
+	for _, saved := range state.Consumed {
+		mm.consumed = append(mm.consumed, consumedFile{saved: saved})
+	}
+
+	return nil
+}
+
+// save は追跡中のファイルの読み込み位置と、すべてのファイルの統計情報を状態ファイルに保存します。状態ファイルが未設定の場合は何もしません。
+func (mm *MultiFileMonitor) save() error {
+	if mm.checkpointPath == "" {
+		return nil
+	}
+
+	// 処理段に保留しているエントリは保存する読み込み位置より前の行から作られているため、集約してから保存する
+	var state checkpoint.State
+	for path, file := range mm.files {
+		mm.finish(file, false)
+		state.Files = append(state.Files, saveFile(path, file.tail, file.processor))
+	}
+	for path, stats := range mm.retired {
+		state.Files = append(state.Files, checkpoint.FileState{Path: path, Stats: stats, Retired: true})
+	}
+	for _, consumed := range mm.consumed {
+		if consumed.info == nil {
+			state.Consumed = append(state.Consumed, consumed.saved)
+			continue
+		}
+		if id, ok := reader.IdentifyFile(consumed.info); ok {
+			state.Consumed = append(state.Consumed, checkpoint.ConsumedFile{FileID: id, Size: consumed.info.Size(), ModTime: consumed.info.ModTime()})
+		}
+	}
+
+	mm.lastSaved = time.Now()
+	return checkpoint.Save(mm.checkpointPath, state)
+}
+
+// finish は1つのファイルの保留しているものを集約し、統計情報を更新します。partial が true の場合は改行で終わっていない末尾の行も集約します。
+func (mm *MultiFileMonitor) finish(file *followedFile, partial bool) error {
+	stats, changed, err := finish(file.tail, file.processor, partial)
+	if changed {
+		file.feed.update(stats)
+		mm.mutex.Lock()
+		file.stats = stats
+		mm.mutex.Unlock()
+	}
+	return err
+}
+
+// newFile は指定したパスのファイルを追跡する状態を作成します。
+func (mm *MultiFileMonitor) newFile(path string) *followedFile {
+	file := &followedFile{
+		tail:      reader.NewTailReader(path),
+		processor: processor.NewIncrementalProcessor(path),
+		feed:      newFeed(path, mm.broadcaster),
+		health:    newHealthTracker(mm.config.Interval),
+	}
+	if mm.configure != nil {
+		mm.configure(file.processor)
+	}
+	file.processor.AddSink(file.feed)
+	return file
+}
+
+// update は追跡中のファイルの追記を集約し、削除されたファイルの追跡をやめ、新しく現れたファイルの追跡を始めます。
+// 結果はファイルごとの監視の状態に記録し、失敗したファイルは再確認の時刻まで確認を省きます。
+func (mm *MultiFileMonitor) update(now time.Time) error {
+	var errs []error
+
+	// 追跡中のファイルの更新
+	for path, file := range mm.files {
+		if !file.health.due(now) {
+			continue
+		}
+		if err := mm.followAndRecord(path, file, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// 新しく現れたファイルの追跡を始める
+	if !mm.health.due(now) {
+		return errors.Join(errs...)
+	}
+	paths, err := mm.discover()
+	recordHealth(&mm.mutex, mm.health, err, now)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, path := range paths {
+		file := mm.newFile(path)
+
+		mm.mutex.Lock()
+		mm.files[path] = file
+		mm.mutex.Unlock()
+
+		if err := mm.followAndRecord(path, file, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// followAndRecord は1つのファイルの追記を集約し、結果をファイルの監視の状態に記録します。
+func (mm *MultiFileMonitor) followAndRecord(path string, file *followedFile, now time.Time) error {
+	err := mm.follow(path, file, now)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	recordHealth(&mm.mutex, file.health, err, now)
+	return err
+}
+
+// follow は1つのファイルの追記を集約します。パスのファイルが猶予を超えてなくなっている場合は追跡をやめます。
+func (mm *MultiFileMonitor) follow(path string, file *followedFile, now time.Time) error {
+	// 追記された行の集約 (ローテーションされた場合は以前のファイルを読み終えたものとして記憶する)
+	previous := file.tail.Info()
+	stats, changed, err := follow(file.tail, file.processor)
+	if previous == nil && file.tail.Info() == nil && errors.Is(err, fs.ErrNotExist) {
+		// 追跡を始める前に削除された
+		mm.mutex.Lock()
+		delete(mm.files, path)
+		mm.mutex.Unlock()
+		return nil
+	}
+	if current := file.tail.Info(); previous != nil && current != nil && !os.SameFile(previous, current) {
+		mm.consume(previous)
+	}
+
+	// パスのファイルの有無を確認
+	if _, statErr := os.Stat(path); errors.Is(statErr, fs.ErrNotExist) {
+		if file.missingSince.IsZero() {
+			file.missingSince = now
+		}
+	} else {
+		file.missingSince = time.Time{}
+	}
+
+	// 猶予を超えてなくなっている場合は追記がもうないため、保留していた末尾の行を確定する
+	retire := !file.missingSince.IsZero() && now.Sub(file.missingSince) >= mm.config.GracePeriod
+	if retire {
+		if flushed, ok, flushErr := finish(file.tail, file.processor, true); ok {
+			stats, changed = flushed, true
+			err = errors.Join(err, flushErr)
+		}
+	}
+
+	// 統計情報の更新を購読者に配信
+	if changed {
+		file.feed.update(stats)
+	}
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	if changed {
+		file.stats = stats
+	}
+
+	// 猶予を超えてなくなっている場合は追跡をやめる
+	if retire {
+		if info := file.tail.Info(); info != nil {
+			mm.consume(info)
+		}
+		file.tail.Close()
+		mm.retired[path] = aggregator.MergeStats(mm.retired[path], file.stats)
+		delete(mm.files, path)
+		return nil
+	}
+
+	// 削除されたファイルはなくなったことを記録済みのためエラーとしない
+	if errors.Is(err, fs.ErrNotExist) && previous != nil {
+		return nil
+	}
+	return err
+}
+
+// discover はパターンに一致するファイルのうち、まだ追跡していないファイルを名前順に返します。
+// 追跡中または読み終えたファイルと同一のファイル (ローテーションで名前が変わったファイル) は含めません。
+// 読み終えたファイルは見つかったファイルの情報に更新し、見つからなかったものは忘れます。
+func (mm *MultiFileMonitor) discover() ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	var found []os.FileInfo
+	for _, pattern := range mm.patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range matches {
+			if seen[path] || mm.files[path] != nil {
+				continue
+			}
+			seen[path] = true
+
+			// 通常のファイルのみ
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			found = append(found, info)
+			if mm.known(info) {
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	mm.refreshConsumed(found)
+
+	return paths, nil
+}
+
+// refreshConsumed は読み終えたファイルをパターンに一致したファイルの情報に更新し、一致したファイルにないもの (削除されたか、パターンから外れたもの) を忘れます。
+func (mm *MultiFileMonitor) refreshConsumed(found []os.FileInfo) {
+	kept := mm.consumed[:0]
+	for _, consumed := range mm.consumed {
+		for _, info := range found {
+			if consumed.matches(info) {
+				kept = append(kept, consumedFile{info: info})
+				break
+			}
+		}
+	}
+	clear(mm.consumed[len(kept):])
+	mm.consumed = kept
+}
+
+// known は指定したファイルが追跡中または読み終えたファイルと同一かどうかを返します。
+func (mm *MultiFileMonitor) known(info os.FileInfo) bool {
+	for _, file := range mm.files {
+		if current := file.tail.Info(); current != nil && os.SameFile(current, info) {
+			return true
+		}
+	}
+	for _, consumed := range mm.consumed {
+		if consumed.matches(info) {
+			return true
+		}
+	}
+	return false
+}
+
+// consume は読み終えたファイルを記憶します。
+func (mm *MultiFileMonitor) consume(info os.FileInfo) {
+	mm.consumed = append(mm.consumed, consumedFile{info: info})
+
+	// 上限を超えた場合は古いものから破棄
+	if len(mm.consumed) > maxConsumedFiles {
+		mm.consumed = mm.consumed[len(mm.consumed)-maxConsumedFiles:]
+	}
+}
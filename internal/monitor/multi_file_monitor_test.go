@@ -0,0 +1,295 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/checkpoint"
+	"github.com/Yamituki/go-review-logagg/internal/processor"
+	"github.com/Yamituki/go-review-logagg/internal/reader"
+)
+
+// TestMultiFileMonitor_Update はパターンに一致するファイルの追跡、新しいファイルの発見、削除されたファイルの猶予後の追跡終了をテストします。
+func TestMultiFileMonitor_Update(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "api.log"), "2024-01-01 12:00:00 [INFO] 起動しました。\n2024-01-01 12:01:00 [ERROR] 失敗しました。\n")
+	writeLog(t, filepath.Join(dir, "web.log"), "2024-01-01 12:02:00 [WARN] 遅延しています。\n")
+	writeLog(t, filepath.Join(dir, "notes.txt"), "パターンに一致しないファイル\n")
+
+	// 監視ループを介さずに update を直接呼び出す
+	mm := NewMultiFileMonitor(MultiFileConfig{
+		Patterns:    []string{filepath.Join(dir, "*.log")},
+		GracePeriod: time.Minute,
+	})
+	defer mm.Stop()
+	configured := 0
+	mm.ConfigureProcessor(func(ip *processor.IncrementalProcessor) {
+		configured++
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := mm.update(now); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+
+	// パターンに一致するファイルのみを追跡する
+	expectedFiles := []string{filepath.Join(dir, "api.log"), filepath.Join(dir, "web.log")}
+	if !reflect.DeepEqual(mm.Files(), expectedFiles) {
+		t.Fatalf("追跡中のファイルが期待値と異なる: %v", mm.Files())
+	}
+	if configured != 2 {
+		t.Errorf("プロセッサの設定の呼び出し回数が期待値と異なる: %d", configured)
+	}
+
+	// 新しく現れたファイルを追跡する
+	writeLog(t, filepath.Join(dir, "worker.log"), "2024-01-01 12:03:00 [ERROR] 停止しました。\n")
+	if err := mm.update(now.Add(time.Second)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if len(mm.Files()) != 3 {
+		t.Fatalf("新しいファイルが追跡されていない: %v", mm.Files())
+	}
+
+	// ファイルごとの統計情報と統合した統計情報
+	fileStats := mm.FileStats()
+	if fileStats[filepath.Join(dir, "api.log")].TotalCount != 2 || fileStats[filepath.Join(dir, "worker.log")].ErrorCount != 1 {
+		t.Errorf("ファイルごとの統計情報が期待値と異なる: %+v", fileStats)
+	}
+	stats, _ := mm.GetStats()
+	if stats.TotalCount != 4 || stats.InfoCount != 1 || stats.WarnCount != 1 || stats.ErrorCount != 2 {
+		t.Errorf("統合した統計情報が期待値と異なる: %+v", stats)
+	}
+
+	// 削除されたファイルは猶予の間は追跡を続ける
+	if err := os.Remove(filepath.Join(dir, "web.log")); err != nil {
+		t.Fatalf("ファイルの削除に失敗: %v", err)
+	}
+	if err := mm.update(now.Add(2 * time.Second)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if len(mm.Files()) != 3 {
+		t.Fatalf("猶予の間に追跡をやめている: %v", mm.Files())
+	}
+
+	// 猶予を超えると追跡をやめるが、統計情報は残る
+	if err := mm.update(now.Add(2*time.Second + time.Minute)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if len(mm.Files()) != 2 {
+		t.Fatalf("猶予を超えても追跡を続けている: %v", mm.Files())
+	}
+	stats, _ = mm.GetStats()
+	if stats.TotalCount != 4 || mm.FileStats()[filepath.Join(dir, "web.log")].WarnCount != 1 {
+		t.Errorf("追跡をやめたファイルの統計情報が残っていない: %+v", stats)
+	}
+}
+
+// TestMultiFileMonitor_Update_Rotation はローテーションで名前が変わったファイルを新しいファイルとして重複して数えないことをテストします。
+func TestMultiFileMonitor_Update_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeLog(t, path, "2024-01-01 12:00:00 [INFO] 1行目\n")
+
+	// ローテーションされたファイルもパターンに一致する
+	mm := NewMultiFileMonitor(MultiFileConfig{Patterns: []string{dir}})
+	defer mm.Stop()
+
+	now := time.Now()
+	if err := mm.update(now); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+
+	// 追記後に名前を変更し、新しいファイルを作成
+	if err := appendLine(path, "2024-01-01 12:01:00 [INFO] 2行目\n"); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("ファイル名の変更に失敗: %v", err)
+	}
+	writeLog(t, path, "2024-01-01 12:02:00 [INFO] 3行目\n")
+
+	for i := range 2 {
+		if err := mm.update(now.Add(time.Duration(i+1) * time.Second)); err != nil {
+			t.Fatalf("update に失敗: %v", err)
+		}
+	}
+
+	stats, _ := mm.GetStats()
+
+	t.Logf("統合した統計情報: %+v", stats)
+
+	if stats.TotalCount != 3 {
+		t.Errorf("TotalCount が期待値と異なる: 期待値=%d, 実際=%d", 3, stats.TotalCount)
+	}
+	if !reflect.DeepEqual(mm.Files(), []string{path}) {
+		t.Errorf("ローテーションされたファイルを追跡している: %v", mm.Files())
+	}
+	if len(stats.Rotations) != 1 {
+		t.Errorf("ローテーションの記録が期待値と異なる: %+v", stats.Rotations)
+	}
+}
+
+// TestMultiFileMonitor_Consumed は読み終えたファイルが削除されると忘れ、inode を再利用したファイルを読み終えたファイルとして扱わないことをテストします。
+func TestMultiFileMonitor_Consumed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeLog(t, path, "2024-01-01 12:00:00 [INFO] 1行目\n")
+
+	mm := NewMultiFileMonitor(MultiFileConfig{Patterns: []string{dir}})
+	defer mm.Stop()
+
+	// ローテーションで名前が変わったファイルを読み終えたファイルとして記憶する
+	now := time.Now()
+	if err := mm.update(now); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("ファイル名の変更に失敗: %v", err)
+	}
+	writeLog(t, path, "2024-01-01 12:01:00 [INFO] 2行目\n")
+	if err := mm.update(now.Add(time.Second)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if len(mm.consumed) != 1 {
+		t.Fatalf("読み終えたファイルの数が期待値と異なる: %d", len(mm.consumed))
+	}
+
+	// 削除されると忘れる
+	if err := os.Remove(path + ".1"); err != nil {
+		t.Fatalf("ファイルの削除に失敗: %v", err)
+	}
+	if err := mm.update(now.Add(2 * time.Second)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if len(mm.consumed) != 0 {
+		t.Errorf("削除されたファイルを記憶している: %d", len(mm.consumed))
+	}
+
+	// 状態ファイルから復元した識別子は、大きさと更新時刻も一致する場合のみ同じファイルとみなす
+	reused := filepath.Join(dir, "reused.log")
+	writeLog(t, reused, "2024-01-01 12:02:00 [INFO] 3行目\n")
+	info, err := os.Stat(reused)
+	if err != nil {
+		t.Fatalf("ファイルの情報の取得に失敗: %v", err)
+	}
+	id, ok := reader.IdentifyFile(info)
+	if !ok {
+		t.Skip("inode を取得できないプラットフォーム")
+	}
+	mm.consumed = []consumedFile{{saved: checkpoint.ConsumedFile{FileID: id, Size: info.Size(), ModTime: info.ModTime()}}}
+	if paths, _ := mm.discover(); len(paths) != 0 {
+		t.Errorf("読み終えたファイルが新しいファイルとして扱われた: %v", paths)
+	}
+	mm.consumed = []consumedFile{{saved: checkpoint.ConsumedFile{FileID: id, Size: info.Size() + 1, ModTime: info.ModTime().Add(-time.Hour)}}}
+	if paths, _ := mm.discover(); !reflect.DeepEqual(paths, []string{reused}) {
+		t.Errorf("inode を再利用したファイルが新しいファイルとして扱われない: %v", paths)
+	}
+}
+
+// TestMultiFileMonitor_Start_Stop は MultiFileMonitor の監視ループで新しいファイルが反映されることをテストします。
+func TestMultiFileMonitor_Start_Stop(t *testing.T) {
+	dir := t.TempDir()
+	mm := NewMultiFileMonitor(MultiFileConfig{
+		Patterns: []string{filepath.Join(dir, "*.log")},
+		Interval: 10 * time.Millisecond,
+	})
+	if err := mm.Start(); err != nil {
+		t.Fatalf("MultiFileMonitor の Start に失敗: %v", err)
+	}
+
+	writeLog(t, filepath.Join(dir, "app.log"), "2024-01-01 12:00:00 [INFO] 起動しました。\n")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats, _ := mm.GetStats()
+		if stats.TotalCount == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("新しいファイルが反映されませんでした: %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := mm.Stop(); err != nil {
+		t.Fatalf("MultiFileMonitor の Stop に失敗: %v", err)
+	}
+}
+
+// TestMultiFileMonitor_Checkpoint は状態ファイルから再開した MultiFileMonitor が追跡中と追跡をやめたファイルの統計情報を引き継ぎ、
+// 停止中にローテーションされたファイルを数え直さないことをテストします。
+func TestMultiFileMonitor_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	gone := filepath.Join(dir, "gone.log")
+	writeLog(t, path, "2024-01-01 12:00:00 [INFO] 1行目\n")
+	writeLog(t, gone, "2024-01-01 12:00:00 [WARN] 削除されるファイル\n")
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	config := MultiFileConfig{Patterns: []string{dir}}
+
+	// 1回目の監視で集約し、削除されたファイルの追跡をやめてから停止する
+	first := NewMultiFileMonitor(config)
+	first.SetCheckpointFile(statePath)
+	now := time.Now()
+	if err := first.update(now); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatalf("ファイルの削除に失敗: %v", err)
+	}
+	if err := first.update(now.Add(time.Second)); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	if err := first.Stop(); err != nil {
+		t.Fatalf("MultiFileMonitor の Stop に失敗: %v", err)
+	}
+
+	// 停止中の追記とローテーション
+	if err := appendLine(path, "2024-01-01 12:01:00 [INFO] 2行目\n"); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("ファイル名の変更に失敗: %v", err)
+	}
+	writeLog(t, path, "2024-01-01 12:02:00 [ERROR] 3行目\n")
+
+	// 2回目の監視は状態ファイルから再開する
+	second := NewMultiFileMonitor(config)
+	second.SetCheckpointFile(statePath)
+	if err := second.restore(); err != nil {
+		t.Fatalf("restore に失敗: %v", err)
+	}
+	defer second.Stop()
+	for i := range 2 {
+		if err := second.update(now.Add(time.Duration(i+2) * time.Second)); err != nil {
+			t.Fatalf("update に失敗: %v", err)
+		}
+	}
+
+	stats, _ := second.GetStats()
+
+	t.Logf("統合した統計情報: %+v", stats)
+
+	// 1行目、停止中の2行目、新しいファイルの3行目、削除されたファイルの1行
+	if stats.TotalCount != 4 || stats.InfoCount != 2 || stats.WarnCount != 1 || stats.ErrorCount != 1 {
+		t.Errorf("統計情報が期待値と異なる: %+v", stats)
+	}
+	if !reflect.DeepEqual(second.Files(), []string{path}) {
+		t.Errorf("追跡中のファイルが期待値と異なる: %v", second.Files())
+	}
+	if _, ok := second.FileStats()[gone]; !ok {
+		t.Error("追跡をやめたファイルの統計情報が引き継がれていない")
+	}
+}
+
+// writeLog はファイルにログを書き込みます。
+func writeLog(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("ファイルの書き込みに失敗: %v", err)
+	}
+}
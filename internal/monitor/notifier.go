@@ -0,0 +1,35 @@
+package monitor
+
+/*
+ * errors パッケージはエラーの生成を提供します。
+ * strings パッケージは文字列操作を提供します。
+ */
+import (
+	"errors"
+	"strings"
+)
+
+// errNotifyUnsupported はこのプラットフォームで変更通知を使用できないことを表すエラーです。
+var errNotifyUnsupported = errors.New("このプラットフォームではファイルの変更通知を使用できません")
+
+// notifier はファイルの変更を通知する仕組みのインターフェースです。
+type notifier interface {
+	// Changes は監視対象のファイルが変更されたときに値を受信できるチャネルを返します。
+	// 連続した変更は1つの通知にまとめられます。通知を続けられなくなった場合はチャネルが閉じられます。
+	Changes() <-chan struct{}
+	// Close は変更の通知を停止します。
+	Close() error
+}
+
+// signal は変更を通知します。受信されていない通知がある場合は新しい通知をまとめます。
+func signal(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
+
+// hasMeta はパスがワイルドカードを含むかどうかを返します。
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
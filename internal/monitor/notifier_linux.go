@@ -0,0 +1,133 @@
+package monitor
+
+/*
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * encoding/binary パッケージはバイト列と数値の変換を提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * os パッケージはファイル操作を提供します。
+ * path/filepath パッケージはファイルパスの操作を提供します。
+ * syscall パッケージは inotify のシステムコールを提供します。
+ */
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// inotifyMask は監視するディレクトリで通知を受け取るイベントです。
+// ファイルの書き込みと切り詰め、ローテーションによる名前の変更・作成・削除を対象にします。
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyNotifier は Linux の inotify でファイルの変更を通知する構造体です。
+// ローテーションで置き換えられたファイルも検知するため、ファイルではなく親ディレクトリを監視し、対象のファイル名で絞り込みます。
+type inotifyNotifier struct {
+	// inotify のファイルディスクリプタ (Close で読み込みを中断するため os.File として保持する)
+	file *os.File
+	// 監視ディスクリプタごとの対象のファイル名のパターン
+	patterns map[int32][]string
+	// 変更の通知
+	changes chan struct{}
+}
+
+// newNotifier は指定したファイルの変更を inotify で通知する notifier を作成します。
+// パスのファイル名にはワイルドカード (filepath.Match の形式) を使用でき、一致するファイルの作成も通知します。
+func newNotifier(paths ...string) (notifier, error) {
+	// ノンブロッキングで作成し、ランタイムのポーラーで読み込む
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	in := &inotifyNotifier{
+		patterns: make(map[int32][]string),
+		changes:  make(chan struct{}, 1),
+	}
+
+	// 親ディレクトリごとに監視を追加
+	for _, path := range paths {
+		dir, name := filepath.Dir(path), filepath.Base(path)
+		if hasMeta(dir) {
+			syscall.Close(fd)
+			return nil, fmt.Errorf("ワイルドカードを含むディレクトリは変更通知で監視できません: %s", path)
+		}
+		wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			syscall.Close(fd)
+			return nil, os.NewSyscallError("inotify_add_watch", err)
+		}
+		in.patterns[int32(wd)] = append(in.patterns[int32(wd)], name)
+	}
+
+	in.file = os.NewFile(uintptr(fd), "inotify")
+	go in.run()
+
+	return in, nil
+}
+
+// Changes は監視対象のファイルが変更されたときに値を受信できるチャネルを返します。
+func (in *inotifyNotifier) Changes() <-chan struct{} {
+	return in.changes
+}
+
+// Close は inotify を閉じ、変更の通知を停止します。
+func (in *inotifyNotifier) Close() error {
+	return in.file.Close()
+}
+
+// run はイベントを読み込み、対象のファイルのイベントを通知します。読み込めなくなった場合は通知のチャネルを閉じます。
+func (in *inotifyNotifier) run() {
+	defer close(in.changes)
+
+	// 1回の読み込みで複数のイベントをまとめて受け取る
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		changed := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			// struct inotify_event { int32 wd; uint32 mask; uint32 cookie; uint32 len; char name[]; }
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			start := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[start:start+nameLen], "\x00"))
+			offset = start + nameLen
+
+			switch {
+			case mask&syscall.IN_Q_OVERFLOW != 0:
+				// イベントが溢れた場合は変更があったものとみなす
+				changed = true
+			case mask&(syscall.IN_IGNORED|syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+				// 監視しているディレクトリがなくなった場合は通知を続けられない
+				return
+			case in.match(wd, name):
+				changed = true
+			}
+		}
+
+		if changed {
+			signal(in.changes)
+		}
+	}
+}
+
+// match はイベントのファイル名が監視ディスクリプタの対象のパターンに一致するかどうかを返します。
+func (in *inotifyNotifier) match(wd int32, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pattern := range in.patterns[wd] {
+		if matched, err := filepath.Match(pattern, name); name == pattern || (err == nil && matched) {
+			return true
+		}
+	}
+	return false
+}
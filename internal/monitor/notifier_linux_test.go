@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestInotifyNotifier_Changes は inotify の変更通知が対象のファイルの書き込みとローテーションのみを通知することをテストします。
+func TestInotifyNotifier_Changes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+
+	n, err := newNotifier(path)
+	if err != nil {
+		t.Fatalf("newNotifier に失敗: %v", err)
+	}
+
+	// 対象外のファイルの変更は通知しない
+	if err := os.WriteFile(filepath.Join(dir, "other.log"), []byte("x\n"), 0644); err != nil {
+		t.Fatalf("ファイルの書き込みに失敗: %v", err)
+	}
+	select {
+	case <-n.Changes():
+		t.Fatal("対象外のファイルの変更が通知されました")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 連続した書き込みは1つの通知にまとめられる
+	for range 10 {
+		if err := fileModifier(path); err != nil {
+			t.Fatalf("ファイルの変更に失敗: %v", err)
+		}
+	}
+	waitChange(t, n)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-n.Changes():
+	default:
+	}
+	select {
+	case <-n.Changes():
+		t.Fatal("まとめられた通知が重複して届きました")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 名前の変更と再作成を通知する
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("ファイル名の変更に失敗: %v", err)
+	}
+	waitChange(t, n)
+
+	// 停止するとチャネルが閉じられる
+	if err := n.Close(); err != nil {
+		t.Fatalf("Close に失敗: %v", err)
+	}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-n.Changes():
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("停止後にチャネルが閉じられませんでした")
+		}
+	}
+}
+
+// TestFileMonitor_Notify は変更通知により監視間隔を待たずに追記が反映されることをテストします。
+func TestFileMonitor_Notify(t *testing.T) {
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	// 監視間隔は十分に長くする
+	fileMonitor := NewFileMonitor(filePath, time.Hour)
+	if err := fileMonitor.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+	defer fileMonitor.Stop()
+
+	// 改行で終わる行を追記
+	if err := fileModifier(filePath); err != nil {
+		t.Fatalf("ファイルの変更に失敗: %v", err)
+	}
+
+	// 開始時の3行と、追記した2行
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats, _ := fileMonitor.GetStats()
+		if stats.TotalCount == 5 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("追記が反映されませんでした: %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitChange は変更の通知を待ちます。
+func waitChange(t *testing.T, n notifier) {
+	t.Helper()
+
+	select {
+	case <-n.Changes():
+	case <-time.After(time.Second):
+		t.Fatal("変更が通知されませんでした")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitor
+
+// newNotifier は変更通知を使用できないため、常に errNotifyUnsupported を返します。
+func newNotifier(paths ...string) (notifier, error) {
+	return nil, errNotifyUnsupported
+}
@@ -0,0 +1,265 @@
+package monitor
+
+/*
+ * sync パッケージは基本的な同期プリミティブを提供します。
+ * sync/atomic パッケージはアトミックな操作を提供します。
+ * time パッケージは時間の測定と表示を提供します。
+ */
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// defaultSubscriptionBuffer は購読者ごとの既定のバッファの大きさです。
+const defaultSubscriptionBuffer = 256
+
+// EventKind は購読者に配信するイベントの種類です。
+type EventKind int
+
+const (
+	// EntryEvent は新しく集約したエントリを表します。
+	EntryEvent EventKind = iota
+	// StatsEvent は統計情報の更新を表します。
+	StatsEvent
+)
+
+// OverflowPolicy は購読者のバッファが一杯の場合の扱いです。
+type OverflowPolicy int
+
+const (
+	// DropOnFull はバッファが一杯の場合にイベントを破棄します。監視は遅れません。
+	DropOnFull OverflowPolicy = iota
+	// BlockOnFull はバッファが空くまで監視を止めて待ちます。イベントは失われませんが、受信が遅い購読者は監視全体を遅らせます。
+	BlockOnFull
+)
+
+// Event は購読者に配信するイベントを表す構造体です。
+type Event struct {
+	// イベントの種類
+	Kind EventKind
+	// ログファイルのパス
+	File string
+	// 新しく集約したエントリ (EntryEvent の場合)
+	Entry models.LogEntry
+	// 更新後のファイルの統計情報 (StatsEvent の場合)
+	Stats models.Stats
+	// 前回の更新から増えた件数とタイムスタンプの範囲 (StatsEvent の場合)
+	Delta models.Stats
+	// イベントの発生時刻
+	Time time.Time
+}
+
+// SubscribeOptions は購読の設定を表す構造体です。
+type SubscribeOptions struct {
+	// バッファの大きさ (0 以下の場合は既定値)
+	Buffer int
+	// バッファが一杯の場合の扱い
+	Policy OverflowPolicy
+	// エントリのイベントを受け取らない (統計情報の更新のみを受け取る)
+	StatsOnly bool
+}
+
+// Subscription はモニターのイベントの購読を表す構造体です。
+type Subscription struct {
+	// 設定
+	options SubscribeOptions
+	// イベントのチャネル
+	events chan Event
+	// 購読の終了を通知するチャネル
+	closed chan struct{}
+	// 購読の終了を一度だけ行うための Once
+	closeOnce sync.Once
+	// 破棄したイベント数
+	dropped atomic.Uint64
+	// 購読しているブロードキャスター
+	broadcaster *broadcaster
+}
+
+// Events はイベントを受信するチャネルを返します。購読の終了またはモニターの停止でチャネルは閉じられます。
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped はバッファが一杯だったため破棄したイベント数を返します。
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close は購読を終了します。
+func (s *Subscription) Close() {
+	s.broadcaster.unsubscribe(s)
+}
+
+// finish は購読の終了を通知し、チャネルを閉じます。
+func (s *Subscription) finish() {
+	s.closeOnce.Do(func() { close(s.closed) })
+	close(s.events)
+}
+
+// broadcaster はイベントを購読者に配信する構造体です。
+type broadcaster struct {
+	// 購読者一覧
+	subscribers map[*Subscription]bool
+	// 購読者一覧を保護するミューテックス (配信中は読み取りロックを保持する)
+	mutex sync.RWMutex
+	// モニターの停止を通知するチャネル (待機中の配信を中断する)
+	stopped <-chan struct{}
+	// モニターが停止したかどうか
+	closed bool
+}
+
+// newBroadcaster は新しい broadcaster を作成します。
+func newBroadcaster(stopped <-chan struct{}) *broadcaster {
+	return &broadcaster{
+		subscribers: make(map[*Subscription]bool),
+		stopped:     stopped,
+	}
+}
+
+// subscribe は新しい購読者を追加します。モニターが停止している場合は閉じたチャネルの購読を返します。
+func (b *broadcaster) subscribe(options SubscribeOptions) *Subscription {
+	if options.Buffer <= 0 {
+		options.Buffer = defaultSubscriptionBuffer
+	}
+
+	s := &Subscription{
+		options:     options,
+		events:      make(chan Event, options.Buffer),
+		closed:      make(chan struct{}),
+		broadcaster: b,
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		s.finish()
+		return s
+	}
+	b.subscribers[s] = true
+
+	return s
+}
+
+// unsubscribe は購読者を取り除き、チャネルを閉じます。
+func (b *broadcaster) unsubscribe(s *Subscription) {
+	// 配信を待機している場合は中断させる
+	s.closeOnce.Do(func() { close(s.closed) })
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscribers[s] {
+		delete(b.subscribers, s)
+		s.finish()
+	}
+}
+
+// active は購読者がいるかどうかを返します。
+func (b *broadcaster) active() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return len(b.subscribers) > 0
+}
+
+// publish はイベントをすべての購読者に配信します。
+func (b *broadcaster) publish(event Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for s := range b.subscribers {
+		if event.Kind == EntryEvent && s.options.StatsOnly {
+			continue
+		}
+
+		// バッファに空きがあればそのまま送る
+		select {
+		case s.events <- event:
+			continue
+		default:
+		}
+
+		if s.options.Policy == DropOnFull {
+			s.dropped.Add(1)
+			continue
+		}
+
+		// 空くまで待つ (購読の終了またはモニターの停止で中断する)
+		select {
+		case s.events <- event:
+		case <-s.closed:
+		case <-b.stopped:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// close はすべての購読を終了し、以降の購読を閉じた状態にします。モニターの停止時に呼び出します。
+func (b *broadcaster) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for s := range b.subscribers {
+		s.finish()
+		delete(b.subscribers, s)
+	}
+	b.closed = true
+}
+
+// feed は1つのファイルの集約したエントリを購読者に配信し、次の統計情報の更新までの差分を数える集約器です。
+// プロセッサの転送先として追加します。
+type feed struct {
+	// ログファイルのパス
+	file string
+	// 配信先
+	broadcaster *broadcaster
+	// 前回の更新からの差分
+	delta *aggregator.LogAggregator
+}
+
+// newFeed は指定したファイルのエントリを配信する feed を作成します。
+func newFeed(file string, b *broadcaster) *feed {
+	return &feed{
+		file:        file,
+		broadcaster: b,
+		delta:       aggregator.NewLogAggregator(),
+	}
+}
+
+// Add はエントリを差分に加え、購読者に配信します。
+func (f *feed) Add(entry models.LogEntry) error {
+	if !f.broadcaster.active() {
+		return nil
+	}
+
+	f.delta.Add(entry)
+	f.broadcaster.publish(Event{Kind: EntryEvent, File: f.file, Entry: entry, Time: time.Now()})
+	return nil
+}
+
+// GetStats は前回の更新からの差分を返します。
+func (f *feed) GetStats() models.Stats {
+	return f.delta.GetStats()
+}
+
+// Reset は差分をリセットします。
+func (f *feed) Reset() {
+	f.delta.Reset()
+}
+
+// update は更新後の統計情報と前回の更新からの差分を購読者に配信し、差分をリセットします。
+func (f *feed) update(stats models.Stats) {
+	if !f.broadcaster.active() {
+		f.delta.Reset()
+		return
+	}
+
+	delta := f.delta.GetStats()
+	f.delta.Reset()
+	f.broadcaster.publish(Event{Kind: StatsEvent, File: f.file, Stats: stats, Delta: delta, Time: time.Now()})
+}
@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestBroadcaster_Publish_Drop はバッファが一杯の購読者のイベントが破棄され、監視が止まらないことをテストします。
+func TestBroadcaster_Publish_Drop(t *testing.T) {
+	stopped := make(chan struct{})
+	b := newBroadcaster(stopped)
+	s := b.subscribe(SubscribeOptions{Buffer: 2, Policy: DropOnFull})
+
+	for i := range 5 {
+		b.publish(Event{Kind: EntryEvent, Entry: models.LogEntry{Message: string(rune('a' + i))}})
+	}
+
+	// 先に送った2件が届き、残りは破棄される
+	if got := (<-s.Events()).Entry.Message; got != "a" {
+		t.Errorf("最初のイベントが期待値と異なります: %s", got)
+	}
+	if got := (<-s.Events()).Entry.Message; got != "b" {
+		t.Errorf("2件目のイベントが期待値と異なります: %s", got)
+	}
+	if s.Dropped() != 3 {
+		t.Errorf("破棄したイベント数が期待値と異なります: 期待値=%d, 実際=%d", 3, s.Dropped())
+	}
+
+	// 購読の終了でチャネルが閉じられる
+	s.Close()
+	if _, ok := <-s.Events(); ok {
+		t.Error("購読の終了後にチャネルが閉じられていません")
+	}
+}
+
+// TestBroadcaster_Publish_Block はバッファが一杯の場合に受信されるまで配信を待ち、購読の終了で待機が解除されることをテストします。
+func TestBroadcaster_Publish_Block(t *testing.T) {
+	stopped := make(chan struct{})
+	b := newBroadcaster(stopped)
+	s := b.subscribe(SubscribeOptions{Buffer: 1, Policy: BlockOnFull})
+	statsOnly := b.subscribe(SubscribeOptions{Buffer: 1, StatsOnly: true})
+
+	// 2件目はバッファが空くまで待つ
+	published := make(chan struct{})
+	go func() {
+		b.publish(Event{Kind: EntryEvent, Entry: models.LogEntry{Message: "1"}})
+		b.publish(Event{Kind: EntryEvent, Entry: models.LogEntry{Message: "2"}})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("バッファが一杯なのに配信が完了しました")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 受信すると配信が完了し、イベントは失われない
+	for _, expected := range []string{"1", "2"} {
+		if got := (<-s.Events()).Entry.Message; got != expected {
+			t.Errorf("イベントが期待値と異なります: 期待値=%s, 実際=%s", expected, got)
+		}
+	}
+	<-published
+	if s.Dropped() != 0 {
+		t.Errorf("イベントが破棄されています: %d", s.Dropped())
+	}
+
+	// 統計情報のみの購読者にはエントリのイベントが届かない
+	select {
+	case event := <-statsOnly.Events():
+		t.Errorf("統計情報のみの購読者にエントリのイベントが届きました: %+v", event)
+	default:
+	}
+
+	// 待機中の配信は購読の終了で解除される
+	b.publish(Event{Kind: EntryEvent})
+	done := make(chan struct{})
+	go func() {
+		b.publish(Event{Kind: EntryEvent})
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	s.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("購読の終了で配信の待機が解除されませんでした")
+	}
+
+	// 停止するとすべての購読が終了し、以降の購読は閉じた状態になる
+	b.close()
+	if _, ok := <-statsOnly.Events(); ok {
+		t.Error("停止後にチャネルが閉じられていません")
+	}
+	if _, ok := <-b.subscribe(SubscribeOptions{}).Events(); ok {
+		t.Error("停止後の購読のチャネルが閉じられていません")
+	}
+}
+
+// TestFileMonitor_Subscribe は FileMonitor の購読者に新しく集約したエントリと統計情報の差分が配信されることをテストします。
+func TestFileMonitor_Subscribe(t *testing.T) {
+	filePath, err := fileCreator()
+	if err != nil {
+		t.Fatalf("一時ファイルの作成に失敗: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	fileMonitor := NewFileMonitor(filePath, 10*time.Millisecond)
+	sub := fileMonitor.Subscribe(SubscribeOptions{Policy: BlockOnFull})
+	if err := fileMonitor.Start(); err != nil {
+		t.Fatalf("FileMonitor の Start に失敗: %v", err)
+	}
+
+	// 3件のエントリと、差分の合計が3件になる統計情報の更新が届く
+	entries, deltaTotal := 0, 0
+	var last models.Stats
+	timeout := time.After(2 * time.Second)
+	for entries < 3 || deltaTotal < 3 {
+		select {
+		case event := <-sub.Events():
+			switch event.Kind {
+			case EntryEvent:
+				entries++
+				if event.File != filePath {
+					t.Errorf("イベントのファイルが期待値と異なる: %s", event.File)
+				}
+			case StatsEvent:
+				deltaTotal += event.Delta.TotalCount
+				last = event.Stats
+			}
+		case <-timeout:
+			t.Fatalf("イベントが届きませんでした: エントリ=%d, 差分の合計=%d", entries, deltaTotal)
+		}
+	}
+	if entries != 3 || deltaTotal != 3 || last.TotalCount != 3 {
+		t.Errorf("イベントが期待値と異なる: エントリ=%d, 差分の合計=%d, 統計情報=%+v", entries, deltaTotal, last)
+	}
+
+	// 停止すると購読のチャネルが閉じられる
+	if err := fileMonitor.Stop(); err != nil {
+		t.Fatalf("FileMonitor の Stop に失敗: %v", err)
+	}
+	for range sub.Events() {
+	}
+}
+
+// TestMultiFileMonitor_Subscribe は MultiFileMonitor の購読者にファイルごとのイベントが配信されることをテストします。
+func TestMultiFileMonitor_Subscribe(t *testing.T) {
+	dir := t.TempDir()
+	apiLog := filepath.Join(dir, "api.log")
+	webLog := filepath.Join(dir, "web.log")
+	writeLog(t, apiLog, "2024-01-01 12:00:00 [INFO] 起動しました。\n2024-01-01 12:01:00 [ERROR] 失敗しました。\n")
+	writeLog(t, webLog, "2024-01-01 12:02:00 [WARN] 遅延しています。\n")
+
+	// 監視ループを介さずに update を直接呼び出す
+	mm := NewMultiFileMonitor(MultiFileConfig{Patterns: []string{filepath.Join(dir, "*.log")}})
+	defer mm.Stop()
+	sub := mm.Subscribe(SubscribeOptions{StatsOnly: true})
+
+	if err := mm.update(time.Now()); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+
+	// ファイルごとに統計情報の更新が届く
+	deltas := make(map[string]int)
+	for range 2 {
+		select {
+		case event := <-sub.Events():
+			if event.Kind != StatsEvent {
+				t.Fatalf("統計情報のみの購読者にエントリのイベントが届きました: %+v", event)
+			}
+			deltas[event.File] = event.Delta.TotalCount
+		case <-time.After(time.Second):
+			t.Fatalf("イベントが届きませんでした: %v", deltas)
+		}
+	}
+	if deltas[apiLog] != 2 || deltas[webLog] != 1 {
+		t.Errorf("ファイルごとの差分が期待値と異なる: %v", deltas)
+	}
+
+	// 追記がなければイベントは届かない
+	if err := mm.update(time.Now()); err != nil {
+		t.Fatalf("update に失敗: %v", err)
+	}
+	select {
+	case event := <-sub.Events():
+		t.Errorf("追記がないのにイベントが届きました: %+v", event)
+	default:
+	}
+}
@@ -3,20 +3,68 @@ package parser
 /*
  * time パッケージは時間の操作を提供します。
  * fmt パッケージはフォーマットされたI/Oを提供します。
+ * strings パッケージは文字列操作を提供します。
  */
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
 
+// DefaultLayouts はタイムスタンプの解析に順に試す既定の書式です。
+// 秒の直後の小数 (".000" や ",000") はどの書式でも受け付けます。
+var DefaultLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// StandardParserConfig は StandardParser の設定を表す構造体です。
+type StandardParserConfig struct {
+	// タイムゾーンを含まないタイムスタンプを解釈するタイムゾーン (nil の場合は UTC)
+	Location *time.Location
+	// 既定の書式で解析できない場合に順に試す書式一覧
+	Layouts []string
+	// 解析したエントリに設定する発生源 (例: サービス名やファイル名、空の場合は設定しない)
+	Source string
+}
+
 // StandardParser は標準的なログ解析を行う構造体です。
-type StandardParser struct{}
+type StandardParser struct {
+	// タイムゾーンを含まないタイムスタンプを解釈するタイムゾーン
+	location *time.Location
+	// 順に試す書式一覧
+	layouts []string
+	// 解析したエントリに設定する発生源
+	source string
+}
 
 // NewStandardParser は StandardParser の新しいインスタンスを作成します。
+// タイムゾーンを含まないタイムスタンプは UTC として解釈します。
 func NewStandardParser() *StandardParser {
-	return &StandardParser{}
+	return NewStandardParserWithConfig(StandardParserConfig{})
+}
+
+// NewStandardParserWithConfig は指定した設定で StandardParser の新しいインスタンスを作成します。
+func NewStandardParserWithConfig(config StandardParserConfig) *StandardParser {
+	location := config.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	layouts := make([]string, 0, len(DefaultLayouts)+len(config.Layouts))
+	layouts = append(layouts, DefaultLayouts...)
+	layouts = append(layouts, config.Layouts...)
+
+	return &StandardParser{
+		location: location,
+		layouts:  layouts,
+		source:   config.Source,
+	}
 }
 
 // Parse はログ行を解析し、LogEntry 構造体に変換します。
@@ -25,33 +73,38 @@ func (sp *StandardParser) Parse(line string) (models.LogEntry, error) {
 	var entry models.LogEntry
 
 	// ログの基本フォーマット: "YYYY-MM-DD HH:MM:SS [LEVEL] Message"
+	// タイムスタンプは小数の秒や RFC3339 形式のタイムゾーンを含められます。
 
 	// 空行のチェック
 	if len(line) == 0 {
 		return entry, fmt.Errorf("空のログ行は解析できません")
 	}
 
+	// タイムスタンプとレベルの区切りの検索 (見つからない場合は行全体をタイムスタンプとみなす)
+	levelStart := strings.Index(line, " [")
+	timestampStr := line
+	if levelStart >= 0 {
+		timestampStr = line[:levelStart]
+	}
+
 	// 日付と時間の解析
-	timestampStr := line[0:19]
-	timestamp, err := time.Parse("2006-01-02 15:04:05", timestampStr)
+	timestamp, err := sp.parseTimestamp(timestampStr)
 	if err != nil {
 		return entry, err
 	}
 	entry.Timestamp = timestamp
 
 	// レベルの解析 [INFO], [ERROR], [WARN]
-	levelStart := 20
-	levelEnd := 0
-
-	// 残りの部分からレベルを抽出
-	for i := levelStart; i < len(line); i++ {
-		if line[i] == ']' {
-			levelEnd = i
-			break
-		}
+	if levelStart < 0 {
+		return entry, fmt.Errorf("ログ行のレベルが存在しません: %s", line)
 	}
+	levelEnd := strings.IndexByte(line[levelStart:], ']')
+	if levelEnd < 0 {
+		return entry, fmt.Errorf("ログ行のレベルが閉じられていません: %s", line)
+	}
+	levelEnd += levelStart
 
-	switch line[levelStart+1 : levelEnd] {
+	switch line[levelStart+2 : levelEnd] {
 	case "INFO":
 		entry.Level = "INFO"
 	case "ERROR":
@@ -59,7 +112,7 @@ func (sp *StandardParser) Parse(line string) (models.LogEntry, error) {
 	case "WARN":
 		entry.Level = "WARN"
 	default:
-		return entry, fmt.Errorf("不明なログレベル: %s", line[levelStart+1:levelEnd])
+		return entry, fmt.Errorf("不明なログレベル: %s", line[levelStart+2:levelEnd])
 	}
 
 	// メッセージの解析
@@ -72,5 +125,110 @@ func (sp *StandardParser) Parse(line string) (models.LogEntry, error) {
 
 	entry.Message = line[messageStart:]
 
+	// メッセージ中の key=value 形式のフィールドの抽出
+	entry.Fields = parseFields(entry.Message)
+
+	// 発生源の設定
+	entry.Source = sp.source
+
 	return entry, nil
 }
+
+// parseTimestamp は書式を順に試してタイムスタンプを解析し、UTC で返します。
+// すべての書式で失敗した場合は最初の書式のエラーを返します。
+func (sp *StandardParser) parseTimestamp(s string) (time.Time, error) {
+	var firstErr error
+	for _, layout := range sp.layouts {
+		timestamp, err := time.ParseInLocation(layout, s, sp.location)
+		if err == nil {
+			// 異なるタイムゾーンのログを統合しても表示が揃うよう UTC に変換する
+			return timestamp.UTC(), nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return time.Time{}, firstErr
+}
+
+// ParseLocation はタイムゾーンの指定を解析します。
+// "UTC"、"Local"、IANA のタイムゾーン名 (例: "Asia/Tokyo")、または "+09:00" 形式の固定オフセットを受け付けます。
+func ParseLocation(name string) (*time.Location, error) {
+	// 固定オフセット
+	if len(name) == 6 && (name[0] == '+' || name[0] == '-') && name[3] == ':' {
+		offset, err := time.Parse("-07:00", name)
+		if err != nil {
+			return nil, fmt.Errorf("タイムゾーンの指定が不正です: %s", name)
+		}
+		_, seconds := offset.Zone()
+		return time.FixedZone(name, seconds), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("タイムゾーンの指定が不正です: %s", name)
+	}
+	return location, nil
+}
+
+// parseFields はメッセージから key=value 形式のフィールドを抽出します。
+// 値はダブルクォートで囲むことで空白を含められます。フィールドがない場合は nil を返します。
+func parseFields(message string) map[string]string {
+	var fields map[string]string
+
+	for i := 0; i < len(message); {
+		// 空白の読み飛ばし
+		if message[i] == ' ' || message[i] == '\t' {
+			i++
+			continue
+		}
+
+		// キーの読み取り
+		start := i
+		for i < len(message) && isFieldKeyChar(message[i]) {
+			i++
+		}
+		key := message[start:i]
+
+		// キーの直後が '=' でなければ次のトークンへ
+		if key == "" || i >= len(message) || message[i] != '=' {
+			for i < len(message) && message[i] != ' ' && message[i] != '\t' {
+				i++
+			}
+			continue
+		}
+		i++
+
+		// 値の読み取り
+		var value string
+		if i < len(message) && message[i] == '"' {
+			end := strings.IndexByte(message[i+1:], '"')
+			if end < 0 {
+				// 閉じクォートがない場合は末尾までを値とする
+				value = message[i+1:]
+				i = len(message)
+			} else {
+				value = message[i+1 : i+1+end]
+				i += end + 2
+			}
+		} else {
+			start = i
+			for i < len(message) && message[i] != ' ' && message[i] != '\t' {
+				i++
+			}
+			value = message[start:i]
+		}
+
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[key] = value
+	}
+
+	return fields
+}
+
+// isFieldKeyChar はフィールドのキーに使用できる文字かどうかを判定します。
+func isFieldKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-'
+}
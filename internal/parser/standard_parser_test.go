@@ -115,3 +115,115 @@ func TestStandardParser_Parse_DifferentLevels(t *testing.T) {
 		t.Errorf("予期しないエラーメッセージが発生しました。期待: %s, 実際: %s", expectedErrMsg, err.Error())
 	}
 }
+
+// TestStandardParser_Parse_Fields は StandardParser がメッセージ中の key=value を抽出することを確認します。
+func TestStandardParser_Parse_Fields(t *testing.T) {
+	// テスト用のログ行
+	logLine := `2024-06-15 14:23:45 [INFO] request done host=web-1 user="alice smith" duration_ms=123 note: ok`
+
+	// StandardParser のインスタンスを作成
+	parser := NewStandardParser()
+
+	// Parse メソッドを呼び出し
+	entry, err := parser.Parse(logLine)
+	if err != nil {
+		t.Fatalf("Parse メソッドでエラーが発生しました: %v", err)
+	}
+
+	t.Logf("解析結果: %+v", entry)
+
+	// 期待される結果と比較
+	expectedFields := map[string]string{
+		"host":        "web-1",
+		"user":        "alice smith",
+		"duration_ms": "123",
+	}
+
+	if len(entry.Fields) != len(expectedFields) {
+		t.Errorf("Fields の数が期待値と異なります。期待: %d, 実際: %d", len(expectedFields), len(entry.Fields))
+	}
+
+	for key, value := range expectedFields {
+		if entry.Fields[key] != value {
+			t.Errorf("Fields[%s] が期待値と異なります。期待: %s, 実際: %s", key, value, entry.Fields[key])
+		}
+	}
+}
+
+// TestStandardParser_Parse_Timestamps は StandardParser がタイムゾーン、小数の秒、RFC3339、追加の書式を解析できることを確認します。
+func TestStandardParser_Parse_Timestamps(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	expected := time.Date(2024, 6, 15, 5, 23, 45, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		config   StandardParserConfig
+		line     string
+		expected time.Time
+	}{
+		{name: "タイムゾーンの指定", config: StandardParserConfig{Location: jst}, line: "2024-06-15 14:23:45 [INFO] started", expected: expected},
+		{name: "小数の秒", line: "2024-06-15 05:23:45.250 [INFO] started", expected: expected.Add(250 * time.Millisecond)},
+		{name: "カンマ区切りの小数の秒", line: "2024-06-15 05:23:45,5 [INFO] started", expected: expected.Add(500 * time.Millisecond)},
+		{name: "RFC3339 のオフセットはタイムゾーンの指定より優先", config: StandardParserConfig{Location: jst}, line: "2024-06-15T14:23:45+09:00 [INFO] started", expected: expected},
+		{name: "RFC3339 の UTC と小数の秒", line: "2024-06-15T05:23:45.123456Z [INFO] started", expected: expected.Add(123456 * time.Microsecond)},
+		{name: "オフセット付きの標準形式", line: "2024-06-15 14:23:45+09:00 [INFO] started", expected: expected},
+		{name: "追加の書式", config: StandardParserConfig{Layouts: []string{"02/Jan/2006:15:04:05 -0700"}}, line: "15/Jun/2024:14:23:45 +0900 [INFO] started", expected: expected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewStandardParserWithConfig(tt.config)
+
+			entry, err := parser.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse メソッドでエラーが発生しました: %v", err)
+			}
+
+			if !entry.Timestamp.Equal(tt.expected) || entry.Timestamp.Location() != time.UTC {
+				t.Errorf("Timestamp が期待値と異なります。期待: %v, 実際: %v", tt.expected, entry.Timestamp)
+			}
+			if entry.Level != "INFO" || entry.Message != "started" {
+				t.Errorf("Level または Message が期待値と異なります: %+v", entry)
+			}
+		})
+	}
+}
+
+// TestStandardParser_Parse_ShortLines は StandardParser が短い行や不完全な行に対してパニックせずエラーを返すことを確認します。
+func TestStandardParser_Parse_ShortLines(t *testing.T) {
+	lines := []string{
+		"x",
+		"2024-06-15",
+		"2024-06-15 14:23:45",
+		"2024-06-15 14:23:45 [",
+		"2024-06-15 14:23:45 [INFO",
+		"2024-06-15 14:23:45 [INFO]",
+		"2024-06-15 14:23:45 [INFO] ",
+	}
+
+	parser := NewStandardParser()
+	for _, line := range lines {
+		if _, err := parser.Parse(line); err == nil {
+			t.Errorf("%q に対してエラーが発生することを期待しましたが、エラーはありませんでした", line)
+		}
+	}
+}
+
+// TestParseLocation は ParseLocation がタイムゾーン名と固定オフセットを解析できることを確認します。
+func TestParseLocation(t *testing.T) {
+	reference := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	for name, expected := range map[string]int{"UTC": 0, "+09:00": 9 * 60 * 60, "-05:30": -(5*60 + 30) * 60} {
+		location, err := ParseLocation(name)
+		if err != nil {
+			t.Fatalf("%s の解析でエラーが発生しました: %v", name, err)
+		}
+		if _, offset := reference.In(location).Zone(); offset != expected {
+			t.Errorf("%s のオフセットが期待値と異なります。期待: %d, 実際: %d", name, expected, offset)
+		}
+	}
+
+	if _, err := ParseLocation("Mars/Olympus"); err == nil {
+		t.Error("不明なタイムゾーンに対してエラーが発生することを期待しましたが、エラーはありませんでした")
+	}
+}
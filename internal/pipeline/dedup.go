@@ -0,0 +1,263 @@
+package pipeline
+
+/*
+ * container/heap パッケージはヒープ操作を提供します。
+ * container/list パッケージは双方向連結リストを提供します。
+ * sort パッケージはスライスのソートを提供します。
+ * strconv パッケージは文字列と基本データ型の変換を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"container/heap"
+	"container/list"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// DedupKey は重複とみなす基準を表す型です。
+type DedupKey int
+
+const (
+	// DedupByMessage はレベル、発生源、メッセージが完全に一致するエントリを重複とみなします。
+	DedupByMessage DedupKey = iota
+	// DedupByTemplate はレベル、発生源、メッセージのテンプレートが一致するエントリを重複とみなします。
+	DedupByTemplate
+)
+
+// DefaultDedupMaxGroups はまとめている途中で保持するグループ数の既定の上限です。
+const DefaultDedupMaxGroups = 10000
+
+// DedupConfig は重複抑制の設定を表す構造体です。
+type DedupConfig struct {
+	// 重複とみなす基準
+	By DedupKey
+	// 直前の重複からこの時間以内のエントリをまとめる (0 の場合は時間で区切らない)
+	Window time.Duration
+	// true の場合は連続する重複のみをまとめ、false の場合は間に別のエントリがあってもまとめる
+	Consecutive bool
+	// まとめている途中で保持するグループ数の上限 (超えた場合は最も古いグループを確定する、0 以下の場合は DefaultDedupMaxGroups)
+	MaxGroups int
+}
+
+// dedupGroup はまとめている途中の重複エントリの集まりです。
+type dedupGroup struct {
+	// 最初のエントリ (繰り返し回数と最後のタイムスタンプ、共通のフィールドを更新して出力する)
+	entry models.LogEntry
+	// 最初に観測した順序
+	order int
+	// 重複判定のキー
+	key string
+	// 観測順のリスト上の要素
+	element *list.Element
+	// 最後のタイムスタンプ順のヒープ上の位置 (Window を指定した場合のみ)
+	index int
+}
+
+// dedupHeap は最後のタイムスタンプが最も古いグループを先頭にするヒープです。
+type dedupHeap []*dedupGroup
+
+func (h dedupHeap) Len() int { return len(h) }
+
+func (h dedupHeap) Less(i, j int) bool {
+	return h[i].entry.LastTimestamp().Before(h[j].entry.LastTimestamp())
+}
+
+func (h dedupHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *dedupHeap) Push(x any) {
+	group := x.(*dedupGroup)
+	group.index = len(*h)
+	*h = append(*h, group)
+}
+
+func (h *dedupHeap) Pop() any {
+	old := *h
+	group := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return group
+}
+
+// DedupStage は同一または同じテンプレートのエントリを繰り返し回数付きの1件にまとめる処理段です。
+// まとめたエントリの件数は LogEntry.Count で集約器に反映されます。
+// まとめたエントリにはすべての重複で値が同じフィールドのみを残すため、数値統計は値が異なるフィールドを繰り返し回数倍に偏って数えません。
+type DedupStage struct {
+	// 設定
+	config DedupConfig
+	// テンプレートの分類器 (DedupByTemplate の場合のみ)
+	miner *aggregator.TemplateMiner
+	// まとめている途中のグループ (キーは重複判定のキー)
+	groups map[string]*dedupGroup
+	// 観測順のグループのリスト (先頭が最も古い)
+	order *list.List
+	// 最後のタイムスタンプ順のグループのヒープ (Window を指定した場合のみ)
+	expiry dedupHeap
+	// 直前のエントリのキー (Consecutive の場合)
+	lastKey string
+	// 観測順の連番
+	sequence int
+	// まとめて減ったエントリ数
+	collapsed int
+}
+
+// NewDedupStage は指定した設定で DedupStage の新しいインスタンスを作成します。
+func NewDedupStage(config DedupConfig) *DedupStage {
+	if config.Window < 0 {
+		config.Window = 0
+	}
+	if config.MaxGroups <= 0 {
+		config.MaxGroups = DefaultDedupMaxGroups
+	}
+
+	ds := &DedupStage{
+		config: config,
+		groups: make(map[string]*dedupGroup),
+		order:  list.New(),
+	}
+	if config.By == DedupByTemplate {
+		ds.miner = aggregator.NewTemplateMiner()
+	}
+
+	return ds
+}
+
+// Process はエントリを重複のグループにまとめ、確定したグループを返します。
+func (ds *DedupStage) Process(entry models.LogEntry) []models.LogEntry {
+	key := ds.key(entry)
+
+	// 時間切れのグループ、または連続モードで途切れたグループを確定する
+	var out []models.LogEntry
+	if ds.config.Consecutive {
+		if key != ds.lastKey {
+			out = ds.flushAll()
+		}
+		ds.lastKey = key
+	}
+	out = append(out, ds.expire(entry.Timestamp)...)
+
+	// 既存のグループにまとめる
+	if group, ok := ds.groups[key]; ok {
+		group.entry.Repeat = group.entry.Count() + entry.Count()
+		if entry.LastTimestamp().After(group.entry.LastTimestamp()) {
+			group.entry.EndTimestamp = entry.LastTimestamp()
+			if ds.config.Window > 0 {
+				heap.Fix(&ds.expiry, group.index)
+			}
+		}
+		for name, value := range group.entry.Fields {
+			if other, ok := entry.Fields[name]; !ok || other != value {
+				delete(group.entry.Fields, name)
+			}
+		}
+		ds.collapsed += entry.Count()
+		return out
+	}
+
+	// グループ数が上限に達している場合は最も古いグループを確定する
+	if len(ds.groups) >= ds.config.MaxGroups {
+		out = append(out, ds.evictOldest())
+	}
+
+	// 新しいグループを作る (共通のフィールドを残すため、フィールドはコピーする)
+	if entry.Fields != nil {
+		fields := make(map[string]string, len(entry.Fields))
+		for name, value := range entry.Fields {
+			fields[name] = value
+		}
+		entry.Fields = fields
+	}
+	ds.sequence++
+	group := &dedupGroup{entry: entry, order: ds.sequence, key: key}
+	group.element = ds.order.PushBack(group)
+	if ds.config.Window > 0 {
+		heap.Push(&ds.expiry, group)
+	}
+	ds.groups[key] = group
+
+	return out
+}
+
+// Flush はまとめている途中のすべてのグループを確定して返します。
+func (ds *DedupStage) Flush() []models.LogEntry {
+	ds.lastKey = ""
+	return ds.flushAll()
+}
+
+// Collapsed はまとめたことで出力されなかったエントリ数を返します。
+func (ds *DedupStage) Collapsed() int {
+	return ds.collapsed
+}
+
+// key は重複判定のキーを返します。
+func (ds *DedupStage) key(entry models.LogEntry) string {
+	body := entry.Message
+	if ds.miner != nil {
+		body = "#" + strconv.Itoa(ds.miner.Classify(entry))
+	}
+
+	return entry.Level + "\x00" + entry.Source + "\x00" + body
+}
+
+// expire は最後の重複から Window を超えて経過したグループを確定して返します。
+// ヒープの先頭から時間切れのグループのみを取り出すため、グループ数によらず時間切れの件数に比例した時間で済みます。
+func (ds *DedupStage) expire(now time.Time) []models.LogEntry {
+	if ds.config.Window <= 0 {
+		return nil
+	}
+
+	var expired []*dedupGroup
+	for len(ds.expiry) > 0 && now.Sub(ds.expiry[0].entry.LastTimestamp()) > ds.config.Window {
+		group := heap.Pop(&ds.expiry).(*dedupGroup)
+		ds.order.Remove(group.element)
+		delete(ds.groups, group.key)
+		expired = append(expired, group)
+	}
+
+	return ordered(expired)
+}
+
+// evictOldest は最も古いグループを確定して返します。
+func (ds *DedupStage) evictOldest() models.LogEntry {
+	group := ds.order.Remove(ds.order.Front()).(*dedupGroup)
+	if ds.config.Window > 0 {
+		heap.Remove(&ds.expiry, group.index)
+	}
+	delete(ds.groups, group.key)
+
+	return group.entry
+}
+
+// flushAll はすべてのグループを観測順に確定して返します。
+func (ds *DedupStage) flushAll() []models.LogEntry {
+	entries := make([]models.LogEntry, 0, len(ds.groups))
+	for element := ds.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, element.Value.(*dedupGroup).entry)
+	}
+	ds.groups = make(map[string]*dedupGroup)
+	ds.order.Init()
+	ds.expiry = nil
+
+	return entries
+}
+
+// ordered はグループを観測順に並べてエントリとして返します。
+func ordered(groups []*dedupGroup) []models.LogEntry {
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].order < groups[j].order
+	})
+
+	entries := make([]models.LogEntry, 0, len(groups))
+	for _, group := range groups {
+		entries = append(entries, group.entry)
+	}
+
+	return entries
+}
@@ -0,0 +1,182 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestDedupStage_Consecutive は連続する同一エントリが1件にまとめられることをテストします。
+func TestDedupStage_Consecutive(t *testing.T) {
+	// DedupStage のインスタンスを作成
+	stage := NewDedupStage(DedupConfig{By: DedupByMessage, Consecutive: true})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 同じ ERROR が100回続いた後に INFO が1件
+	var out []models.LogEntry
+	for i := 0; i < 100; i++ {
+		out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Second), Level: "ERROR", Message: "panic: nil pointer"})...)
+	}
+	out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(200 * time.Second), Level: "INFO", Message: "restarted"})...)
+	out = append(out, stage.Flush()...)
+
+	t.Logf("出力されたエントリ: %+v", out)
+
+	// 出力の検証
+	if len(out) != 2 {
+		t.Fatalf("期待される出力数は 2 ですが、実際の値は %d です", len(out))
+	}
+	if out[0].Repeat != 100 || !out[0].Timestamp.Equal(base) || !out[0].EndTimestamp.Equal(base.Add(99*time.Second)) {
+		t.Errorf("まとめたエントリが期待値と異なります: %+v", out[0])
+	}
+	if out[1].Count() != 1 {
+		t.Errorf("単独のエントリの件数は 1 であるべきですが、実際の値は %d です", out[1].Count())
+	}
+	if stage.Collapsed() != 99 {
+		t.Errorf("期待されるまとめた件数は 99 ですが、実際の値は %d です", stage.Collapsed())
+	}
+
+	// 集約器の統計情報に繰り返し回数が反映される
+	ag := aggregator.NewLogAggregator()
+	for _, entry := range out {
+		ag.Add(entry)
+	}
+	stats := ag.GetStats()
+	if stats.TotalCount != 101 || stats.ErrorCount != 100 {
+		t.Errorf("統計情報が期待値と異なります: %+v", stats)
+	}
+}
+
+// TestDedupStage_TemplateWindow は同じテンプレートのエントリが時間内であれば間に別のエントリがあってもまとめられることをテストします。
+func TestDedupStage_TemplateWindow(t *testing.T) {
+	// DedupStage のインスタンスを作成
+	stage := NewDedupStage(DedupConfig{By: DedupByTemplate, Window: 10 * time.Second})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 0〜4秒に同じテンプレートの ERROR と INFO が交互に出力される
+	var out []models.LogEntry
+	for i := 0; i < 5; i++ {
+		out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Second), Level: "ERROR", Message: fmt.Sprintf("worker %d crashed", i)})...)
+		out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Second), Level: "INFO", Message: "health check ok"})...)
+	}
+
+	// 時間内の間は出力されない
+	if len(out) != 0 {
+		t.Fatalf("時間内のエントリは保留されるはずですが、%d 件出力されました", len(out))
+	}
+
+	// 30秒後のエントリで時間切れのグループが確定する
+	out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(30 * time.Second), Level: "ERROR", Message: "worker 9 crashed"})...)
+
+	t.Logf("出力されたエントリ: %+v", out)
+
+	if len(out) != 2 {
+		t.Fatalf("期待される出力数は 2 ですが、実際の値は %d です", len(out))
+	}
+	if out[0].Level != "ERROR" || out[0].Repeat != 5 {
+		t.Errorf("ERROR のまとめたエントリが期待値と異なります: %+v", out[0])
+	}
+	if out[1].Level != "INFO" || out[1].Repeat != 5 {
+		t.Errorf("INFO のまとめたエントリが期待値と異なります: %+v", out[1])
+	}
+
+	// 残りは Flush で確定する
+	if flushed := stage.Flush(); len(flushed) != 1 || flushed[0].Count() != 1 {
+		t.Errorf("Flush の出力が期待値と異なります: %+v", flushed)
+	}
+}
+
+// TestDedupStage_Fields はまとめたエントリに値が共通のフィールドのみが残り、数値統計が偏らないことをテストします。
+func TestDedupStage_Fields(t *testing.T) {
+	stage := NewDedupStage(DedupConfig{By: DedupByTemplate})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 同じテンプレートで duration_ms だけが異なる
+	first := models.LogEntry{Timestamp: base, Level: "INFO", Message: "request host=web-1 duration_ms=1000", Fields: map[string]string{"host": "web-1", "duration_ms": "1000"}}
+	stage.Process(first)
+	for i := 1; i < 10; i++ {
+		stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Second), Level: "INFO", Message: fmt.Sprintf("request host=web-1 duration_ms=%d", i), Fields: map[string]string{"host": "web-1", "duration_ms": fmt.Sprint(i)}})
+	}
+	out := stage.Flush()
+
+	if len(out) != 1 || out[0].Repeat != 10 {
+		t.Fatalf("まとめたエントリが期待値と異なります: %+v", out)
+	}
+	if _, ok := out[0].Fields["duration_ms"]; ok || out[0].Fields["host"] != "web-1" {
+		t.Errorf("まとめたエントリのフィールドが期待値と異なります: %+v", out[0].Fields)
+	}
+	if first.Fields["duration_ms"] != "1000" {
+		t.Errorf("元のエントリのフィールドが変更されました: %+v", first.Fields)
+	}
+
+	// 最初のエントリの値を繰り返し回数倍に数えない
+	ag := aggregator.NewLogAggregator()
+	ag.Attach(aggregator.NewNumericCounter("duration_ms", aggregator.NumericOptions{}))
+	for _, entry := range out {
+		ag.Add(entry)
+	}
+	if stats := ag.GetStats(); stats.TotalCount != 10 || stats.Numeric["duration_ms"].Overall.Count != 0 {
+		t.Errorf("統計情報が期待値と異なります: %+v", stats)
+	}
+}
+
+// TestDedupStage_MaxGroups は保持するグループ数が上限を超えた場合に最も古いグループを確定することをテストします。
+func TestDedupStage_MaxGroups(t *testing.T) {
+	stage := NewDedupStage(DedupConfig{By: DedupByMessage, MaxGroups: 2})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var out []models.LogEntry
+	for i, message := range []string{"a", "b", "a", "c", "b"} {
+		out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Second), Level: "INFO", Message: message})...)
+	}
+
+	// c を追加したときに最も古い a のグループを確定する
+	if len(out) != 1 || out[0].Message != "a" || out[0].Repeat != 2 {
+		t.Fatalf("確定したエントリが期待値と異なります: %+v", out)
+	}
+	if flushed := stage.Flush(); len(flushed) != 2 || flushed[0].Message != "b" || flushed[0].Repeat != 2 || flushed[1].Message != "c" {
+		t.Errorf("Flush の出力が期待値と異なります: %+v", flushed)
+	}
+}
+
+// TestDedupStage_WindowUpdate は重複が続いているグループは時間切れにならず、古いグループのみが観測順に確定することをテストします。
+func TestDedupStage_WindowUpdate(t *testing.T) {
+	stage := NewDedupStage(DedupConfig{By: DedupByMessage, Window: 10 * time.Second})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var out []models.LogEntry
+	for _, tt := range []struct {
+		second  int
+		message string
+	}{{0, "a"}, {1, "b"}, {2, "c"}, {8, "a"}, {12, "d"}, {15, "e"}} {
+		out = append(out, stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(tt.second) * time.Second), Level: "INFO", Message: tt.message})...)
+	}
+
+	// 12秒で b、15秒で c が時間切れになり、8秒に重複した a は残る
+	if len(out) != 2 || out[0].Message != "b" || out[1].Message != "c" {
+		t.Fatalf("確定したエントリが期待値と異なります: %+v", out)
+	}
+	if flushed := stage.Flush(); len(flushed) != 3 || flushed[0].Message != "a" || flushed[0].Repeat != 2 || flushed[1].Message != "d" || flushed[2].Message != "e" {
+		t.Errorf("Flush の出力が期待値と異なります: %+v", flushed)
+	}
+}
+
+// BenchmarkDedupStage_Process は保持するグループ数が上限に近い状態で時間切れと上限による確定を行う性能を計測します。
+func BenchmarkDedupStage_Process(b *testing.B) {
+	stage := NewDedupStage(DedupConfig{By: DedupByMessage, Window: time.Minute})
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stage.Process(models.LogEntry{Timestamp: base.Add(time.Duration(i) * time.Millisecond), Level: "INFO", Message: strconv.Itoa(i % 20000)})
+	}
+}
@@ -0,0 +1,372 @@
+package pipeline
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * regexp パッケージは正規表現を提供します。
+ * strconv パッケージは文字列と基本データ型の変換を提供します。
+ * strings パッケージは文字列操作を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// FieldPredicate はフィールドの値に対する条件を表す構造体です。
+type FieldPredicate struct {
+	// フィールド名
+	Field string
+	// 比較演算子 ("=", "!=", ">", ">=", "<", "<=", "~")
+	Op string
+	// 比較する値 ("~" の場合は正規表現)
+	Value string
+	// 数値として比較する場合の値
+	number float64
+	// "~" の場合の正規表現
+	pattern *regexp.Regexp
+}
+
+// predicateOps は解析時に優先して照合する順に並べた比較演算子です。
+var predicateOps = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+// ParseFieldPredicate は "status>=500" や "host=web-1" のような文字列を条件に変換します。
+func ParseFieldPredicate(s string) (FieldPredicate, error) {
+	for i := 0; i < len(s); i++ {
+		if !strings.ContainsRune("=!<>~", rune(s[i])) {
+			continue
+		}
+		for _, op := range predicateOps {
+			if strings.HasPrefix(s[i:], op) {
+				return NewFieldPredicate(strings.TrimSpace(s[:i]), op, strings.TrimSpace(s[i+len(op):]))
+			}
+		}
+		break
+	}
+
+	return FieldPredicate{}, fmt.Errorf("フィールドの条件が不正です: %s", s)
+}
+
+// NewFieldPredicate はフィールドの条件を検証して作成します。
+func NewFieldPredicate(field, op, value string) (FieldPredicate, error) {
+	fp := FieldPredicate{Field: field, Op: op, Value: value}
+	if field == "" {
+		return fp, fmt.Errorf("フィールドの条件にフィールド名がありません: %s%s%s", field, op, value)
+	}
+
+	switch op {
+	case "=", "!=":
+	case ">", ">=", "<", "<=":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fp, fmt.Errorf("フィールド %s の比較には数値が必要です: %s", field, value)
+		}
+		fp.number = number
+	case "~":
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return fp, fmt.Errorf("フィールド %s の正規表現が不正です: %v", field, err)
+		}
+		fp.pattern = pattern
+	default:
+		return fp, fmt.Errorf("フィールド %s の比較演算子が不正です: %s", field, op)
+	}
+
+	return fp, nil
+}
+
+// Matches はエントリが条件を満たすかどうかを返します。"!=" 以外はフィールドがない場合に満たしません。
+func (fp FieldPredicate) Matches(entry models.LogEntry) bool {
+	value, ok := entry.Field(fp.Field)
+
+	switch fp.Op {
+	case "=":
+		return ok && value == fp.Value
+	case "!=":
+		return !ok || value != fp.Value
+	case "~":
+		return ok && fp.pattern.MatchString(value)
+	}
+
+	// 数値の比較
+	if !ok {
+		return false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	switch fp.Op {
+	case ">":
+		return number > fp.number
+	case ">=":
+		return number >= fp.number
+	case "<":
+		return number < fp.number
+	default:
+		return number <= fp.number
+	}
+}
+
+// FilterConfig はフィルターの条件を表す構造体です。指定したすべての条件を満たすエントリを通します。
+type FilterConfig struct {
+	// このレベル以上の重大度のエントリを通す (例: "WARN" は WARN と ERROR)
+	MinLevel string
+	// この時刻以降のエントリを通す
+	Since time.Time
+	// この時刻より前のエントリを通す
+	Until time.Time
+	// 基準時刻からさかのぼってこの時間以内のエントリを通す (例: 直近2時間)
+	Last time.Duration
+	// Last の基準時刻 (ゼロ値の場合はフィルターの作成時刻)
+	Now time.Time
+	// 通す発生源一覧 (空の場合はすべて)
+	Sources []string
+	// 除外する発生源一覧
+	ExcludeSources []string
+	// メッセージに含まれる文字列
+	Contains string
+	// メッセージに一致する正規表現
+	Match string
+	// フィールドの条件一覧
+	Fields []FieldPredicate
+}
+
+// FilterOptions は設定ファイル、HTTP API、コマンドライン引数で指定する文字列形式の絞り込みの条件です。
+type FilterOptions struct {
+	// このレベル以上の重大度のログに絞り込む (例: "WARN")
+	Level string `json:"level,omitempty"`
+	// この時刻以降のログに絞り込む (RFC3339 または "YYYY-MM-DD HH:MM:SS")
+	Since string `json:"since,omitempty"`
+	// この時刻より前のログに絞り込む
+	Until string `json:"until,omitempty"`
+	// 現在からさかのぼってこの時間以内のログに絞り込む (例: "2h")
+	Last string `json:"last,omitempty"`
+	// 含める発生源一覧
+	Sources []string `json:"sources,omitempty"`
+	// 除外する発生源一覧
+	ExcludeSources []string `json:"exclude_sources,omitempty"`
+	// メッセージに含まれる文字列
+	Contains string `json:"contains,omitempty"`
+	// メッセージに一致する正規表現
+	Match string `json:"match,omitempty"`
+	// フィールドの条件一覧 (例: "status>=500", "host=web-1", "path~^/api")
+	Where []string `json:"where,omitempty"`
+}
+
+// Config は文字列形式の条件を解析して FilterConfig に変換します。
+func (fo FilterOptions) Config() (FilterConfig, error) {
+	config := FilterConfig{
+		MinLevel:       fo.Level,
+		Sources:        fo.Sources,
+		ExcludeSources: fo.ExcludeSources,
+		Contains:       fo.Contains,
+		Match:          fo.Match,
+	}
+
+	// 時刻の範囲
+	var err error
+	if fo.Since != "" {
+		if config.Since, err = ParseTime(fo.Since); err != nil {
+			return config, err
+		}
+	}
+	if fo.Until != "" {
+		if config.Until, err = ParseTime(fo.Until); err != nil {
+			return config, err
+		}
+	}
+	if fo.Last != "" {
+		if config.Last, err = time.ParseDuration(fo.Last); err != nil {
+			return config, fmt.Errorf("時間の範囲の指定が不正です: %s", fo.Last)
+		}
+	}
+
+	// フィールドの条件
+	for _, where := range fo.Where {
+		predicate, err := ParseFieldPredicate(where)
+		if err != nil {
+			return config, err
+		}
+		config.Fields = append(config.Fields, predicate)
+	}
+
+	return config, nil
+}
+
+// Filter は検証済みの絞り込みの条件です。状態を持たないため、複数の処理段から同時に使用できます。
+type Filter struct {
+	// 条件
+	config FilterConfig
+	// 最小の重大度
+	severity int
+	// 時刻の範囲の下限 (Since と Last から決定)
+	since time.Time
+	// 通す発生源の集合
+	sources map[string]bool
+	// 除外する発生源の集合
+	excluded map[string]bool
+	// メッセージの正規表現
+	pattern *regexp.Regexp
+}
+
+// NewFilter は条件を検証して Filter を作成します。条件が不正な場合はエラーを返します。
+// 直近の時間の条件 (Last) は作成時の時刻を基準に絶対時刻に変換するため、あとから作成した処理段でも範囲は変わりません。
+func NewFilter(config FilterConfig) (*Filter, error) {
+	fs := &Filter{
+		config:   config,
+		since:    config.Since,
+		sources:  toSet(config.Sources),
+		excluded: toSet(config.ExcludeSources),
+	}
+
+	// レベルの検証
+	if config.MinLevel != "" {
+		fs.severity = models.LevelSeverity(config.MinLevel)
+		if fs.severity <= 0 {
+			return nil, fmt.Errorf("レベルの指定が不正です: %s", config.MinLevel)
+		}
+	}
+
+	// 相対的な時刻の範囲は作成時に絶対時刻に変換する
+	if config.Last < 0 {
+		return nil, fmt.Errorf("時間の範囲の指定が不正です: %s", config.Last)
+	}
+	if config.Last > 0 {
+		now := config.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if last := now.Add(-config.Last); last.After(fs.since) {
+			fs.since = last
+		}
+	}
+	if !fs.since.IsZero() && !config.Until.IsZero() && !fs.since.Before(config.Until) {
+		return nil, fmt.Errorf("時刻の範囲が空です: %s 〜 %s", fs.since.Format(time.RFC3339), config.Until.Format(time.RFC3339))
+	}
+
+	// 正規表現の検証
+	if config.Match != "" {
+		pattern, err := regexp.Compile(config.Match)
+		if err != nil {
+			return nil, fmt.Errorf("メッセージの正規表現が不正です: %v", err)
+		}
+		fs.pattern = pattern
+	}
+
+	return fs, nil
+}
+
+// FilterStage は条件を満たさないエントリを取り除き、取り除いたログ数を数える処理段です。
+// 件数を保持するため、ファイルごとに作成します。
+type FilterStage struct {
+	// 絞り込みの条件
+	filter *Filter
+	// 取り除いたログ数
+	dropped int
+}
+
+// NewFilterStage は指定した条件で絞り込む FilterStage を作成します。
+func NewFilterStage(filter *Filter) *FilterStage {
+	return &FilterStage{filter: filter}
+}
+
+// Process はエントリが条件を満たす場合のみ返します。
+func (fs *FilterStage) Process(entry models.LogEntry) []models.LogEntry {
+	if !fs.filter.Matches(entry) {
+		fs.dropped += entry.Count()
+		return nil
+	}
+	return []models.LogEntry{entry}
+}
+
+// Flush は何も保留しないため空を返します。
+func (fs *FilterStage) Flush() []models.LogEntry {
+	return nil
+}
+
+// Dropped は条件を満たさず取り除いたログ数を返します。
+func (fs *FilterStage) Dropped() int {
+	return fs.dropped
+}
+
+// Report は条件を満たさず取り除いたログ数を統計情報の Filtered に加算します。
+func (fs *FilterStage) Report(stats *models.Stats) {
+	stats.Filtered += fs.dropped
+}
+
+// Matches はエントリがすべての条件を満たすかどうかを返します。
+func (fs *Filter) Matches(entry models.LogEntry) bool {
+	// レベルの条件
+	if fs.severity > 0 && models.LevelSeverity(entry.Level) < fs.severity {
+		return false
+	}
+
+	// 時刻の範囲の条件
+	if !fs.since.IsZero() && entry.Timestamp.Before(fs.since) {
+		return false
+	}
+	if !fs.config.Until.IsZero() && !entry.Timestamp.Before(fs.config.Until) {
+		return false
+	}
+
+	// 発生源の条件
+	if len(fs.sources) > 0 && !fs.sources[entry.Source] {
+		return false
+	}
+	if fs.excluded[entry.Source] {
+		return false
+	}
+
+	// メッセージの条件
+	if fs.config.Contains != "" && !strings.Contains(entry.Message, fs.config.Contains) {
+		return false
+	}
+	if fs.pattern != nil && !fs.pattern.MatchString(entry.Message) {
+		return false
+	}
+
+	// フィールドの条件
+	for _, predicate := range fs.config.Fields {
+		if !predicate.Matches(entry) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// timeLayouts は時刻の範囲の指定として受け付ける書式です。
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime は RFC3339、ログと同じ "YYYY-MM-DD HH:MM:SS"、または日付のみの文字列を時刻に変換します。
+// タイムゾーンのない書式はログの解析と同じく UTC とみなします。
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("時刻の指定が不正です: %s", s)
+}
+
+// toSet は文字列の一覧を集合に変換します。
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
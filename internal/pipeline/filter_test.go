@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestFilter_Matches はフィルターの各条件をテストします。
+func TestFilter_Matches(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := models.LogEntry{
+		Timestamp: base,
+		Level:     "WARN",
+		Source:    "api",
+		Message:   "GET /api/users status=503 duration_ms=1200",
+		Fields:    map[string]string{"status": "503", "duration_ms": "1200"},
+	}
+
+	tests := []struct {
+		name     string
+		options  FilterOptions
+		config   FilterConfig
+		expected bool
+	}{
+		{name: "条件なし", expected: true},
+		{name: "レベルの下限を満たす", options: FilterOptions{Level: "WARN"}, expected: true},
+		{name: "レベルの下限を満たさない", options: FilterOptions{Level: "ERROR"}, expected: false},
+		{name: "時刻の範囲内", options: FilterOptions{Since: "2024-01-01 11:00:00", Until: "2024-01-01T13:00:00Z"}, expected: true},
+		{name: "時刻の範囲の終端は含まない", options: FilterOptions{Until: "2024-01-01 12:00:00"}, expected: false},
+		{name: "直近の範囲内", config: FilterConfig{Last: 2 * time.Hour, Now: base.Add(time.Hour)}, expected: true},
+		{name: "直近の範囲外", config: FilterConfig{Last: 30 * time.Minute, Now: base.Add(time.Hour)}, expected: false},
+		{name: "発生源を含める", options: FilterOptions{Sources: []string{"api", "db"}}, expected: true},
+		{name: "発生源を除外する", options: FilterOptions{ExcludeSources: []string{"api"}}, expected: false},
+		{name: "部分文字列", options: FilterOptions{Contains: "/api/users"}, expected: true},
+		{name: "正規表現", options: FilterOptions{Match: `^POST `}, expected: false},
+		{name: "数値の比較", options: FilterOptions{Where: []string{"status>=500", "duration_ms<2000"}}, expected: true},
+		{name: "数値の比較を満たさない", options: FilterOptions{Where: []string{"status<500"}}, expected: false},
+		{name: "ないフィールドの否定", options: FilterOptions{Where: []string{"host!=web-1"}}, expected: true},
+		{name: "ないフィールドの一致", options: FilterOptions{Where: []string{"host=web-1"}}, expected: false},
+		{name: "フィールドの正規表現", options: FilterOptions{Where: []string{"status~^5"}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if tt.config.Last == 0 {
+				var err error
+				if config, err = tt.options.Config(); err != nil {
+					t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+				}
+			}
+
+			filter, err := NewFilter(config)
+			if err != nil {
+				t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+			}
+
+			if actual := filter.Matches(entry); actual != tt.expected {
+				t.Errorf("期待される結果は %v ですが、実際の値は %v です", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// TestFilterStage_Dropped は取り除いたログ数が繰り返し回数を含めて数えられ、統計情報に書き込まれることをテストします。
+func TestFilterStage_Dropped(t *testing.T) {
+	filter, err := NewFilter(FilterConfig{MinLevel: "ERROR"})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	stage := NewFilterStage(filter)
+
+	out := stage.Process(models.LogEntry{Level: "INFO", Message: "ok", Repeat: 5})
+	out = append(out, stage.Process(models.LogEntry{Level: "ERROR", Message: "failed"})...)
+
+	if len(out) != 1 || out[0].Level != "ERROR" {
+		t.Errorf("出力が期待値と異なります: %+v", out)
+	}
+	if stage.Dropped() != 5 {
+		t.Errorf("期待される取り除いたログ数は 5 ですが、実際の値は %d です", stage.Dropped())
+	}
+
+	var stats models.Stats
+	stage.Report(&stats)
+	if stats.Filtered != 5 {
+		t.Errorf("統計情報の取り除いたログ数が期待値と異なります: %d", stats.Filtered)
+	}
+}
+
+// TestFilterOptions_Invalid は不正な条件がエラーになることをテストします。
+func TestFilterOptions_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		options FilterOptions
+	}{
+		{name: "不正な時刻", options: FilterOptions{Since: "yesterday"}},
+		{name: "不正な時間", options: FilterOptions{Last: "2 hours"}},
+		{name: "演算子のない条件", options: FilterOptions{Where: []string{"status"}}},
+		{name: "数値でない比較", options: FilterOptions{Where: []string{"status>high"}}},
+		{name: "フィールド名のない条件", options: FilterOptions{Where: []string{"=500"}}},
+		{name: "不明なレベル", options: FilterOptions{Level: "DEBUG"}},
+		{name: "不正な正規表現", options: FilterOptions{Match: "("}},
+		{name: "空の時刻の範囲", options: FilterOptions{Since: "2024-01-02", Until: "2024-01-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := tt.options.Config()
+			if err == nil {
+				_, err = NewFilter(config)
+			}
+			if err == nil {
+				t.Error("エラーが発生するはずですが、エラーが発生しませんでした")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package pipeline
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * net/netip パッケージは IP アドレスの解析を提供します。
+ * regexp パッケージは正規表現を提供します。
+ * strings パッケージは文字列操作を提供します。
+ */
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// detector は機密情報の検出器です。
+type detector struct {
+	// 検出器の名前 (置換後の文字列と件数のキーになる)
+	name string
+	// 候補の正規表現
+	pattern *regexp.Regexp
+	// 候補が本当に機密情報かどうかの検証 (nil の場合は候補をすべて置換する)
+	validate func(match string) bool
+}
+
+// builtinDetectors は組み込みの検出器を、置換を適用する順に並べたものです。
+// トークンの一部を別の検出器が置換しないよう、長い形式のものから順に適用します。
+var builtinDetectors = []detector{
+	{name: "bearer", pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+`)},
+	{name: "aws_key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b|(?i)\baws_secret_access_key\s*[=:]\s*[A-Za-z0-9/+=]{40}`)},
+	{name: "email", pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{name: "credit_card", pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), validate: isCardNumber},
+	{name: "ipv6", pattern: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`), validate: isIPv6},
+	{name: "ipv4", pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), validate: isIPv4},
+}
+
+// BuiltinDetectors は組み込みの検出器の名前一覧を返します。
+func BuiltinDetectors() []string {
+	names := make([]string, 0, len(builtinDetectors))
+	for _, d := range builtinDetectors {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// CustomPattern は利用者が定義する検出器を表す構造体です。
+type CustomPattern struct {
+	// 検出器の名前
+	Name string `json:"name"`
+	// 置換する正規表現
+	Pattern string `json:"pattern"`
+}
+
+// RedactConfig は機密情報の置換の設定を表す構造体です。
+type RedactConfig struct {
+	// 使用する組み込みの検出器の名前一覧 (空の場合はすべて)
+	Detectors []string `json:"detectors,omitempty"`
+	// 利用者が定義する検出器一覧 (組み込みの検出器の後に適用)
+	Custom []CustomPattern `json:"custom,omitempty"`
+}
+
+// Redactor は検証済みの検出器で文字列中の機密情報を置換します。複数の処理段から同時に使用できます。
+type Redactor struct {
+	// 適用順の検出器一覧
+	detectors []detector
+}
+
+// NewRedactor は設定を検証して Redactor を作成します。設定が不正な場合はエラーを返します。
+func NewRedactor(config RedactConfig) (*Redactor, error) {
+	r := &Redactor{}
+	names := make(map[string]bool)
+
+	// 組み込みの検出器の選択
+	if len(config.Detectors) == 0 {
+		r.detectors = append(r.detectors, builtinDetectors...)
+	} else {
+		selected := toSet(config.Detectors)
+		for _, d := range builtinDetectors {
+			if selected[d.name] {
+				r.detectors = append(r.detectors, d)
+				delete(selected, d.name)
+			}
+		}
+		for name := range selected {
+			return nil, fmt.Errorf("不明な検出器です: %s", name)
+		}
+	}
+	for _, d := range r.detectors {
+		names[d.name] = true
+	}
+
+	// 利用者が定義する検出器の検証
+	for _, custom := range config.Custom {
+		if custom.Name == "" || custom.Pattern == "" {
+			return nil, fmt.Errorf("検出器の名前と正規表現を指定してください: %+v", custom)
+		}
+		if names[custom.Name] {
+			return nil, fmt.Errorf("検出器の名前が重複しています: %s", custom.Name)
+		}
+		names[custom.Name] = true
+
+		pattern, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("検出器 %s の正規表現が不正です: %v", custom.Name, err)
+		}
+		r.detectors = append(r.detectors, detector{name: custom.Name, pattern: pattern})
+	}
+
+	return r, nil
+}
+
+// Redact は文字列中の機密情報を "[REDACTED:検出器名]" に置換し、検出器ごとの置換数を counts に加算します。
+func (r *Redactor) Redact(s string, counts map[string]int) string {
+	for _, d := range r.detectors {
+		s = d.pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if d.validate != nil && !d.validate(match) {
+				return match
+			}
+			if counts != nil {
+				counts[d.name]++
+			}
+			return "[REDACTED:" + d.name + "]"
+		})
+	}
+	return s
+}
+
+// RedactStage はメッセージとフィールドの機密情報を置換する処理段です。
+// 置換数を保持するため、ファイルごとに作成します。
+type RedactStage struct {
+	// 置換に使用する Redactor
+	redactor *Redactor
+	// 検出器ごとの置換数
+	counts map[string]int
+}
+
+// NewRedactStage は指定した Redactor で置換を行う RedactStage を作成します。
+func NewRedactStage(redactor *Redactor) *RedactStage {
+	return &RedactStage{
+		redactor: redactor,
+		counts:   make(map[string]int),
+	}
+}
+
+// Process はメッセージとフィールドの値の機密情報を置換したエントリを返します。
+func (rs *RedactStage) Process(entry models.LogEntry) []models.LogEntry {
+	found := make(map[string]int)
+	original := entry.Message
+	entry.Message = rs.redactor.Redact(entry.Message, found)
+
+	// フィールドの値も置換する (メッセージに含まれていた値は二重に数えない)
+	if len(entry.Fields) > 0 {
+		fields := make(map[string]string, len(entry.Fields))
+		for key, value := range entry.Fields {
+			fieldCounts := found
+			if strings.Contains(original, value) {
+				fieldCounts = nil
+			}
+			fields[key] = rs.redactor.Redact(value, fieldCounts)
+		}
+		entry.Fields = fields
+	}
+
+	// 重複をまとめたエントリは繰り返し回数ぶん数える
+	for name, count := range found {
+		rs.counts[name] += count * entry.Count()
+	}
+
+	return []models.LogEntry{entry}
+}
+
+// Flush は何も保留しないため空を返します。
+func (rs *RedactStage) Flush() []models.LogEntry {
+	return nil
+}
+
+// Counts は検出器ごとの置換数のコピーを返します。
+func (rs *RedactStage) Counts() map[string]int {
+	counts := make(map[string]int, len(rs.counts))
+	for name, count := range rs.counts {
+		counts[name] = count
+	}
+	return counts
+}
+
+// Report は検出器ごとの置換数を統計情報に加算します。
+func (rs *RedactStage) Report(stats *models.Stats) {
+	if len(rs.counts) == 0 {
+		return
+	}
+	if stats.Redactions == nil {
+		stats.Redactions = make(map[string]int, len(rs.counts))
+	}
+	for name, count := range rs.counts {
+		stats.Redactions[name] += count
+	}
+}
+
+// isCardNumber は数字列がクレジットカード番号の桁数で Luhn のチェックディジットを満たすかどうかを返します。
+func isCardNumber(match string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, match)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	// 右端から1つおきに2倍して合計する
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+
+	return sum%10 == 0
+}
+
+// isIPv4 は文字列が IPv4 アドレスかどうかを返します。
+func isIPv4(match string) bool {
+	addr, err := netip.ParseAddr(match)
+	return err == nil && addr.Is4()
+}
+
+// isIPv6 は文字列が IPv6 アドレスかどうかを返します。
+func isIPv6(match string) bool {
+	addr, err := netip.ParseAddr(match)
+	return err == nil && addr.Is6()
+}
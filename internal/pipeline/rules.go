@@ -0,0 +1,209 @@
+package pipeline
+
+/*
+ * encoding/json パッケージは JSON エンコードとデコードを提供します。
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * os パッケージはファイル操作を提供します。
+ * regexp パッケージは正規表現を提供します。
+ * strconv パッケージは文字列と基本データ型の変換を提供します。
+ */
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// Rule は名前付きのルールを表す構造体です。指定した条件をすべて満たすエントリに一致します。
+type Rule struct {
+	// ルール名 (一致したエントリのタグと統計情報のキーになる)
+	Name string `json:"name"`
+	// メッセージに一致する正規表現
+	Message string `json:"message,omitempty"`
+	// 条件に使うフィールド名 (Equals、Min、Max を指定しない場合はフィールドの存在を条件とする)
+	Field string `json:"field,omitempty"`
+	// フィールドの値と一致する文字列
+	Equals string `json:"equals,omitempty"`
+	// フィールドの数値の下限 (この値を含む)
+	Min *float64 `json:"min,omitempty"`
+	// フィールドの数値の上限 (この値を含む)
+	Max *float64 `json:"max,omitempty"`
+	// このレベル以上の重大度のエントリに一致する (例: "WARN" は WARN と ERROR に一致)
+	Level string `json:"level,omitempty"`
+}
+
+// RuleConfig はルールの設定ファイルの構造を表します。
+type RuleConfig struct {
+	// ルール一覧
+	Rules []Rule `json:"rules"`
+}
+
+// LoadRuleFile は JSON 形式のルールの設定ファイルを読み込みます。
+func LoadRuleFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ルールの設定ファイルの読み込みに失敗しました: %v", err)
+	}
+
+	var config RuleConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("ルールの設定ファイルの解析に失敗しました: %v", err)
+	}
+
+	return config.Rules, nil
+}
+
+// compiledRule は検証済みのルールです。
+type compiledRule struct {
+	// 元のルール
+	Rule
+	// メッセージの正規表現 (指定がない場合は nil)
+	pattern *regexp.Regexp
+	// レベルの重大度の下限
+	severity int
+}
+
+// RuleSet は検証済みのルールの集まりです。複数の処理段から同時に使用できます。
+type RuleSet struct {
+	// ルール一覧
+	rules []compiledRule
+}
+
+// NewRuleSet はルールを検証して RuleSet を作成します。ルールが不正な場合はエラーを返します。
+func NewRuleSet(rules []Rule) (*RuleSet, error) {
+	rs := &RuleSet{rules: make([]compiledRule, 0, len(rules))}
+	names := make(map[string]bool, len(rules))
+
+	for _, rule := range rules {
+		// 名前の検証
+		if rule.Name == "" {
+			return nil, fmt.Errorf("ルール名が指定されていません")
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("ルール名が重複しています: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		// 条件の検証
+		if rule.Message == "" && rule.Field == "" && rule.Level == "" {
+			return nil, fmt.Errorf("ルール %s に条件が指定されていません", rule.Name)
+		}
+		if rule.Field == "" && (rule.Equals != "" || rule.Min != nil || rule.Max != nil) {
+			return nil, fmt.Errorf("ルール %s の値の条件にはフィールド名が必要です", rule.Name)
+		}
+
+		compiled := compiledRule{Rule: rule}
+		if rule.Message != "" {
+			pattern, err := regexp.Compile(rule.Message)
+			if err != nil {
+				return nil, fmt.Errorf("ルール %s の正規表現が不正です: %v", rule.Name, err)
+			}
+			compiled.pattern = pattern
+		}
+		if rule.Level != "" {
+			compiled.severity = models.LevelSeverity(rule.Level)
+			if compiled.severity <= 0 {
+				return nil, fmt.Errorf("ルール %s のレベルが不正です: %s", rule.Name, rule.Level)
+			}
+		}
+
+		rs.rules = append(rs.rules, compiled)
+	}
+
+	return rs, nil
+}
+
+// Names はルール名を定義順に返します。
+func (rs *RuleSet) Names() []string {
+	names := make([]string, 0, len(rs.rules))
+	for _, rule := range rs.rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// Match はエントリに一致するルール名を定義順に返します。
+func (rs *RuleSet) Match(entry models.LogEntry) []string {
+	var matched []string
+	for _, rule := range rs.rules {
+		if rule.matches(entry) {
+			matched = append(matched, rule.Name)
+		}
+	}
+	return matched
+}
+
+// matches はエントリがルールのすべての条件を満たすかどうかを返します。
+func (cr compiledRule) matches(entry models.LogEntry) bool {
+	// レベルの条件
+	if cr.severity > 0 && models.LevelSeverity(entry.Level) < cr.severity {
+		return false
+	}
+
+	// メッセージの条件
+	if cr.pattern != nil && !cr.pattern.MatchString(entry.Message) {
+		return false
+	}
+
+	// フィールドの条件
+	if cr.Field == "" {
+		return true
+	}
+	value, ok := entry.Field(cr.Field)
+	if !ok {
+		return false
+	}
+	if cr.Equals != "" && value != cr.Equals {
+		return false
+	}
+	if cr.Min != nil || cr.Max != nil {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		if cr.Min != nil && number < *cr.Min {
+			return false
+		}
+		if cr.Max != nil && number > *cr.Max {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RuleStage は一致したルールの名前をエントリのタグに付加する処理段です。
+type RuleStage struct {
+	// ルール一覧
+	rules *RuleSet
+}
+
+// NewRuleStage は指定したルールでエントリにタグを付加する RuleStage を作成します。
+func NewRuleStage(rules *RuleSet) *RuleStage {
+	return &RuleStage{rules: rules}
+}
+
+// Process は一致したルールの名前をタグに付加したエントリを返します。
+func (rs *RuleStage) Process(entry models.LogEntry) []models.LogEntry {
+	if matched := rs.rules.Match(entry); len(matched) > 0 {
+		// 元のエントリのタグを共有しないようにコピーする
+		tags := make([]string, 0, len(entry.Tags)+len(matched))
+		tags = append(tags, entry.Tags...)
+		for _, name := range matched {
+			if !entry.HasTag(name) {
+				tags = append(tags, name)
+			}
+		}
+		entry.Tags = tags
+	}
+
+	return []models.LogEntry{entry}
+}
+
+// Flush は何も保留しないため空を返します。
+func (rs *RuleStage) Flush() []models.LogEntry {
+	return nil
+}
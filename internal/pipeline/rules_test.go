@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestRuleStage_Process はルールの各条件とタグの付加をテストします。
+func TestRuleStage_Process(t *testing.T) {
+	lower, upper := 1000.0, 5000.0
+	rules, err := NewRuleSet([]Rule{
+		{Name: "payment_failures", Message: `payment (failed|declined)`},
+		{Name: "large_amount", Field: "amount", Min: &lower, Max: &upper},
+		{Name: "checkout", Field: "service", Equals: "checkout"},
+		{Name: "severe", Level: "WARN"},
+	})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	stage := NewRuleStage(rules)
+
+	tests := []struct {
+		name     string
+		entry    models.LogEntry
+		expected []string
+	}{
+		{
+			name:     "メッセージと数値の範囲",
+			entry:    models.LogEntry{Level: "INFO", Message: "payment declined amount=2500", Fields: map[string]string{"amount": "2500"}},
+			expected: []string{"payment_failures", "large_amount"},
+		},
+		{
+			name:     "範囲外の数値",
+			entry:    models.LogEntry{Level: "INFO", Message: "refund amount=9000", Fields: map[string]string{"amount": "9000"}},
+			expected: nil,
+		},
+		{
+			name:     "フィールドの一致とレベルの閾値",
+			entry:    models.LogEntry{Level: "ERROR", Message: "timeout service=checkout", Fields: map[string]string{"service": "checkout"}},
+			expected: []string{"checkout", "severe"},
+		},
+		{
+			name:     "数値でない値",
+			entry:    models.LogEntry{Level: "INFO", Message: "amount=unknown", Fields: map[string]string{"amount": "unknown"}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := stage.Process(tt.entry)
+			if len(out) != 1 {
+				t.Fatalf("期待される出力数は 1 ですが、実際の値は %d です", len(out))
+			}
+
+			tags := out[0].Tags
+			if len(tags) != len(tt.expected) {
+				t.Fatalf("期待されるタグは %v ですが、実際の値は %v です", tt.expected, tags)
+			}
+			for i, tag := range tt.expected {
+				if tags[i] != tag {
+					t.Errorf("期待されるタグは %v ですが、実際の値は %v です", tt.expected, tags)
+				}
+			}
+		})
+	}
+}
+
+// TestNewRuleSet_Invalid は不正なルールがエラーになることをテストします。
+func TestNewRuleSet_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+	}{
+		{name: "名前なし", rules: []Rule{{Message: "error"}}},
+		{name: "名前の重複", rules: []Rule{{Name: "a", Message: "x"}, {Name: "a", Message: "y"}}},
+		{name: "条件なし", rules: []Rule{{Name: "a"}}},
+		{name: "不正な正規表現", rules: []Rule{{Name: "a", Message: "("}}},
+		{name: "フィールド名なしの値の条件", rules: []Rule{{Name: "a", Equals: "x"}}},
+		{name: "不明なレベル", rules: []Rule{{Name: "a", Level: "FATAL"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRuleSet(tt.rules); err == nil {
+				t.Error("エラーが発生するはずですが、エラーが発生しませんでした")
+			}
+		})
+	}
+}
+
+// TestLoadRuleFile はルールの設定ファイルの読み込みをテストします。
+func TestLoadRuleFile(t *testing.T) {
+	path := t.TempDir() + "/rules.json"
+	content := `{"rules": [{"name": "oom_kills", "message": "OOMKilled"}, {"name": "slow", "field": "duration_ms", "min": 1000}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("一時的な設定ファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	rules, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	if len(rules) != 2 || rules[0].Name != "oom_kills" || rules[1].Min == nil || *rules[1].Min != 1000 {
+		t.Errorf("読み込んだルールが期待値と異なります: %+v", rules)
+	}
+}
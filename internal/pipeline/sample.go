@@ -0,0 +1,140 @@
+package pipeline
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します。
+ * math パッケージは基本的な数学関数を提供します。
+ * math/rand/v2 パッケージは擬似乱数の生成を提供します。
+ */
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// SampleConfig はサンプリングの設定を表す構造体です。
+type SampleConfig struct {
+	// 既定の抽出率 (0.0 より大きく 1.0 以下、0 の場合はすべて残す)
+	Rate float64 `json:"rate,omitempty"`
+	// レベルごとの抽出率 (例: {"ERROR": 1, "INFO": 0.01})
+	LevelRates map[string]float64 `json:"level_rates,omitempty"`
+	// 指定した場合はこのフィールドの値のハッシュで抽出し、同じ値のエントリをまとめて残すか捨てる
+	By string `json:"by,omitempty"`
+	// 乱数の種 (0 の場合は処理段ごと、つまりファイルごとに無作為に選ぶ)
+	// 指定した場合はすべてのファイルで同じ乱数列を使うため、同じ種と入力からは同じ結果になる
+	Seed uint64 `json:"seed,omitempty"`
+}
+
+// Sampler は検証済みのサンプリングの設定です。複数の処理段から同時に使用できます。
+type Sampler struct {
+	// 設定
+	config SampleConfig
+}
+
+// NewSampler は設定を検証して Sampler を作成します。抽出率が範囲外の場合はエラーを返します。
+func NewSampler(config SampleConfig) (*Sampler, error) {
+	if config.Rate == 0 {
+		config.Rate = 1
+	}
+	if !validRate(config.Rate) {
+		return nil, fmt.Errorf("抽出率は 0 より大きく 1 以下で指定してください: %g", config.Rate)
+	}
+	for level, rate := range config.LevelRates {
+		if !validRate(rate) {
+			return nil, fmt.Errorf("レベル %s の抽出率は 0 より大きく 1 以下で指定してください: %g", level, rate)
+		}
+	}
+
+	return &Sampler{config: config}, nil
+}
+
+// rate はエントリのレベルに対する抽出率を返します。
+func (s *Sampler) rate(level string) float64 {
+	if rate, ok := s.config.LevelRates[level]; ok {
+		return rate
+	}
+	return s.config.Rate
+}
+
+// SampleStage はエントリを抽出率に従って間引き、残したエントリの件数を抽出率の逆数倍に補正する処理段です。
+// 補正後の件数は LogEntry.Count で集約器に反映されるため、統計情報は全体の推定値になります。
+// 乱数と件数を保持するため、ファイルごとに作成します。
+type SampleStage struct {
+	// 抽出の設定
+	sampler *Sampler
+	// 乱数生成器
+	rng *rand.Rand
+	// 入力されたログ数
+	observed int
+	// 残したログ数 (補正前)
+	kept int
+}
+
+// NewSampleStage は指定した設定でサンプリングを行う SampleStage を作成します。
+// 乱数の種が指定されていない場合は、ファイルやリクエストごとに異なる乱数列になるよう無作為に選びます。
+func NewSampleStage(sampler *Sampler) *SampleStage {
+	seed := sampler.config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &SampleStage{
+		sampler: sampler,
+		rng:     rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+	}
+}
+
+// Process はエントリを残す場合のみ、件数を補正して返します。
+func (ss *SampleStage) Process(entry models.LogEntry) []models.LogEntry {
+	count := entry.Count()
+	ss.observed += count
+
+	rate := ss.sampler.rate(entry.Level)
+	if rate >= 1 {
+		ss.kept += count
+		return []models.LogEntry{entry}
+	}
+
+	// フィールドの値のハッシュ、またはフィールドがない場合は乱数で抽出を判定する
+	var draw float64
+	if value, ok := entry.Field(ss.sampler.config.By); ss.sampler.config.By != "" && ok {
+		draw = float64(aggregator.HashString(value)>>11) / (1 << 53)
+	} else {
+		draw = ss.rng.Float64()
+	}
+	if draw >= rate {
+		return nil
+	}
+	ss.kept += count
+
+	// 抽出率の逆数倍に補正する (端数は期待値が一致するよう確率的に切り上げる)
+	weight := float64(count) / rate
+	scaled := math.Floor(weight)
+	if ss.rng.Float64() < weight-scaled {
+		scaled++
+	}
+	entry.Repeat = max(int(scaled), 1)
+
+	return []models.LogEntry{entry}
+}
+
+// Flush は何も保留しないため空を返します。
+func (ss *SampleStage) Flush() []models.LogEntry {
+	return nil
+}
+
+// Report は統計情報がサンプリングによる推定値であることと、入力されたログ数と残したログ数を書き込みます。
+func (ss *SampleStage) Report(stats *models.Stats) {
+	if stats.Sampling == nil {
+		stats.Sampling = &models.SamplingInfo{}
+	}
+	stats.Sampling.Estimated = true
+	stats.Sampling.Observed += ss.observed
+	stats.Sampling.Kept += ss.kept
+}
+
+// validRate は抽出率が 0 より大きく 1 以下かどうかを返します。
+func validRate(rate float64) bool {
+	return rate > 0 && rate <= 1
+}
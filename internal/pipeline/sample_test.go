@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestSampleStage_LevelRates はレベルごとの抽出率で間引いた統計情報が全体の推定値になることをテストします。
+func TestSampleStage_LevelRates(t *testing.T) {
+	sampler, err := NewSampler(SampleConfig{Rate: 0.01, LevelRates: map[string]float64{"ERROR": 1}})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	stage := NewSampleStage(sampler)
+	ag := aggregator.NewLogAggregator()
+
+	// INFO 100000件と ERROR 37件
+	kept := 0
+	for i := 0; i < 100000; i++ {
+		for _, entry := range stage.Process(models.LogEntry{Level: "INFO", Message: "ok"}) {
+			ag.Add(entry)
+			kept++
+		}
+	}
+	for i := 0; i < 37; i++ {
+		for _, entry := range stage.Process(models.LogEntry{Level: "ERROR", Message: "failed"}) {
+			ag.Add(entry)
+			kept++
+		}
+	}
+
+	stats := ag.GetStats()
+	stage.Report(&stats)
+
+	t.Logf("残したログ数: %d, 統計情報: %+v, 抽出の情報: %+v", kept, stats, stats.Sampling)
+
+	// ERROR はすべて残り、補正されない
+	if stats.ErrorCount != 37 {
+		t.Errorf("期待される ERROR の件数は 37 ですが、実際の値は %d です", stats.ErrorCount)
+	}
+
+	// INFO はおよそ1%を残し、推定値は誤差10%以内
+	if kept > 2000 {
+		t.Errorf("残したログ数が多すぎます: %d", kept)
+	}
+	if math.Abs(float64(stats.InfoCount)-100000)/100000 > 0.1 {
+		t.Errorf("INFO の推定値の誤差が大きすぎます: %d", stats.InfoCount)
+	}
+
+	// 推定値であることが示される
+	if stats.Sampling == nil || !stats.Sampling.Estimated || stats.Sampling.Observed != 100037 || stats.Sampling.Kept != kept {
+		t.Errorf("抽出の情報が期待値と異なります: %+v", stats.Sampling)
+	}
+}
+
+// TestSampleStage_ConsistentByField はフィールドの値のハッシュによる抽出で、同じ値のエントリがまとめて残るか捨てられることをテストします。
+func TestSampleStage_ConsistentByField(t *testing.T) {
+	sampler, err := NewSampler(SampleConfig{Rate: 0.3, By: "request_id"})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+
+	// 2つのファイルを別々の処理段で処理しても、同じ ID の判定は一致する
+	first := NewSampleStage(sampler)
+	second := NewSampleStage(sampler)
+
+	keptIDs := 0
+	for i := 0; i < 1000; i++ {
+		entry := models.LogEntry{Level: "INFO", Message: "step", Fields: map[string]string{"request_id": fmt.Sprintf("req-%d", i)}}
+		a := len(first.Process(entry)) > 0
+		b := len(second.Process(entry)) > 0
+		if a != b {
+			t.Fatalf("同じ ID の抽出の判定が一致しません: req-%d", i)
+		}
+		if a {
+			keptIDs++
+		}
+	}
+
+	// およそ30%の ID が残る
+	if keptIDs < 250 || keptIDs > 350 {
+		t.Errorf("残した ID の数が期待される範囲外です: %d", keptIDs)
+	}
+}
+
+// TestSampleStage_Seed は乱数の種を指定した場合は処理段ごとに同じ結果になり、指定しない場合は異なる結果になることをテストします。
+func TestSampleStage_Seed(t *testing.T) {
+	// 処理段ごとの残したエントリの並びを返す
+	pattern := func(stage *SampleStage) string {
+		kept := make([]byte, 0, 256)
+		for i := 0; i < 256; i++ {
+			if len(stage.Process(models.LogEntry{Level: "INFO", Message: "ok"})) > 0 {
+				kept = append(kept, '1')
+			} else {
+				kept = append(kept, '0')
+			}
+		}
+		return string(kept)
+	}
+
+	seeded, err := NewSampler(SampleConfig{Rate: 0.5, Seed: 42})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	if pattern(NewSampleStage(seeded)) != pattern(NewSampleStage(seeded)) {
+		t.Errorf("同じ種の処理段の結果が一致しません")
+	}
+
+	unseeded, err := NewSampler(SampleConfig{Rate: 0.5})
+	if err != nil {
+		t.Fatalf("エラーは発生しないはずですが、エラーが発生しました: %v", err)
+	}
+	if pattern(NewSampleStage(unseeded)) == pattern(NewSampleStage(unseeded)) {
+		t.Errorf("種を指定しない処理段の結果が一致しました")
+	}
+}
+
+// TestNewSampler_Invalid は範囲外の抽出率がエラーになることをテストします。
+func TestNewSampler_Invalid(t *testing.T) {
+	configs := []SampleConfig{
+		{Rate: 1.5},
+		{Rate: -0.1},
+		{LevelRates: map[string]float64{"INFO": 0}},
+	}
+
+	for _, config := range configs {
+		if _, err := NewSampler(config); err == nil {
+			t.Errorf("エラーが発生するはずですが、エラーが発生しませんでした: %+v", config)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package pipeline
+
+import "github.com/Yamituki/go-review-logagg/pkg/models"
+
+// Stage は解析済みのエントリを集約前に変換・選別するための処理段のインターフェースです。
+type Stage interface {
+	// Process はエントリを処理し、後段に渡すエントリを返します。保留する場合は空を返します。
+	Process(entry models.LogEntry) []models.LogEntry
+	// Flush は保留中のエントリをすべて返します。入力の終わりで呼び出します。
+	Flush() []models.LogEntry
+}
+
+// Reporter は処理の結果を統計情報に書き込む処理段が実装するインターフェースです。
+type Reporter interface {
+	// Report は処理の結果を統計情報に書き込みます。
+	Report(stats *models.Stats)
+}
+
+// Chain は複数の処理段を順に適用する処理段です。
+type Chain struct {
+	// 処理段一覧
+	stages []Stage
+}
+
+// NewChain は指定した処理段を順に適用する Chain を作成します。
+func NewChain(stages ...Stage) *Chain {
+	return &Chain{stages: stages}
+}
+
+// Process はエントリをすべての処理段に順に通します。
+func (c *Chain) Process(entry models.LogEntry) []models.LogEntry {
+	return c.run(0, []models.LogEntry{entry})
+}
+
+// Flush は前段から順に保留中のエントリを吐き出し、後段に通します。
+func (c *Chain) Flush() []models.LogEntry {
+	var flushed []models.LogEntry
+	for i, stage := range c.stages {
+		flushed = append(flushed, c.run(i+1, stage.Flush())...)
+	}
+	return flushed
+}
+
+// Report は Reporter を実装する処理段の結果を統計情報に書き込みます。
+func (c *Chain) Report(stats *models.Stats) {
+	for _, stage := range c.stages {
+		if reporter, ok := stage.(Reporter); ok {
+			reporter.Report(stats)
+		}
+	}
+}
+
+// run は start 番目以降の処理段にエントリを通します。
+func (c *Chain) run(start int, entries []models.LogEntry) []models.LogEntry {
+	for _, stage := range c.stages[start:] {
+		var next []models.LogEntry
+		for _, entry := range entries {
+			next = append(next, stage.Process(entry)...)
+		}
+		entries = next
+	}
+	return entries
+}
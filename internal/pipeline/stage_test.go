@@ -0,0 +1,35 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestChain_Flush は前段の Flush で出力されたエントリが後段を通ることをテストします。
+func TestChain_Flush(t *testing.T) {
+	// 2段の重複抑制を連結
+	first := NewDedupStage(DedupConfig{By: DedupByMessage, Consecutive: true})
+	second := NewDedupStage(DedupConfig{By: DedupByMessage})
+	chain := NewChain(first, second)
+
+	// A, A, B, A の順に処理
+	var out []models.LogEntry
+	for _, message := range []string{"A", "A", "B", "A"} {
+		out = append(out, chain.Process(models.LogEntry{Level: "INFO", Message: message})...)
+	}
+	out = append(out, chain.Flush()...)
+
+	t.Logf("出力されたエントリ: %+v", out)
+
+	// 後段で A がまとめられ A(3), B(1) になる
+	if len(out) != 2 {
+		t.Fatalf("期待される出力数は 2 ですが、実際の値は %d です", len(out))
+	}
+	if out[0].Message != "A" || out[0].Count() != 3 {
+		t.Errorf("A のエントリが期待値と異なります: %+v", out[0])
+	}
+	if out[1].Message != "B" || out[1].Count() != 1 {
+		t.Errorf("B のエントリが期待値と異なります: %+v", out[1])
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"sync"
 
 	"github.com/Yamituki/go-review-logagg/internal/aggregator"
-	"github.com/Yamituki/go-review-logagg/internal/parser"
 	"github.com/Yamituki/go-review-logagg/internal/reader"
 	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
@@ -17,6 +16,8 @@ import (
 // ConcurrentProcessor は並行処理を行うプロセッサの構造体です。
 type ConcurrentProcessor struct {
 	workers int
+	// 集約器に付加する拡張の設定
+	pipelineConfig
 }
 
 // NewConcurrentProcessor は ConcurrentProcessor の新しいインスタンスを作成します。
@@ -74,16 +75,24 @@ func (cp *ConcurrentProcessor) ProcessFiles(filePaths []string) (models.Stats, e
 				}
 
 				// パーサーの初期化
-				parser := parser.NewStandardParser()
+				parser := cp.newParser(filepath)
 
 				// 集約器の初期化
-				aggregator := aggregator.NewLogAggregator()
+				ag := cp.newAggregator()
 
-				// 各行をパースして集計
+				// 処理段の初期化
+				chain := cp.newChain()
+
+				// 各行をパースして集計 (解析できない行はデッドレターに書き込む)
 				var entry models.LogEntry
-				for _, line := range lines {
+				rejected := 0
+				for i, line := range lines {
 					entry, err = parser.Parse(line)
 					if err != nil {
+						if err = cp.reject(filepath, i+1, line, err); err == nil {
+							rejected++
+							continue
+						}
 						errorMutex.Lock()
 						if firstError == nil {
 							firstError = fmt.Errorf("ログのパースに失敗しました: %v", err)
@@ -92,13 +101,23 @@ func (cp *ConcurrentProcessor) ProcessFiles(filePaths []string) (models.Stats, e
 						continue
 					}
 
-					// 集約器に追加
-					aggregator.Add(entry)
+					// 処理段を通して集約器に追加
+					for _, processed := range chain.Process(entry) {
+						ag.Add(processed)
+					}
+
+				}
 
+				// 処理段に保留されているエントリの集約
+				for _, processed := range chain.Flush() {
+					ag.Add(processed)
 				}
 
-				// 結果をチャネルに送信
-				resultChan <- aggregator.GetStats()
+				// 処理段の結果を書き込んでチャネルに送信
+				result := ag.GetStats()
+				result.Rejected = rejected
+				chain.Report(&result)
+				resultChan <- result
 
 			}
 
@@ -122,35 +141,13 @@ func (cp *ConcurrentProcessor) ProcessFiles(filePaths []string) (models.Stats, e
 	for range filePaths {
 		result := <-resultChan
 
-		// フィルター: 結果が空の場合はスキップ
-		if result.InfoCount == 0 && result.WarnCount == 0 && result.ErrorCount == 0 {
+		// フィルター: 結果が空の場合はスキップ (入力数や解析できなかった行数、取り除いた行数を数えている場合は統合する)
+		if result.InfoCount == 0 && result.WarnCount == 0 && result.ErrorCount == 0 && result.Sampling == nil && result.Rejected == 0 && result.Filtered == 0 {
 			continue
 		}
 
 		// 統計情報の集約
-		stats.TotalCount += result.TotalCount
-		stats.ErrorCount += result.ErrorCount
-		stats.WarnCount += result.WarnCount
-		stats.InfoCount += result.InfoCount
-
-		// タイムスタンプの初期化
-		if stats.FirstTimestamp.IsZero() && !result.FirstTimestamp.IsZero() {
-			stats.FirstTimestamp = result.FirstTimestamp
-		}
-
-		if stats.LastTimestamp.IsZero() && !result.LastTimestamp.IsZero() {
-			stats.LastTimestamp = result.LastTimestamp
-		}
-
-		// 最小タイムスタンプの更新
-		if result.FirstTimestamp.Before(stats.FirstTimestamp) {
-			stats.FirstTimestamp = result.FirstTimestamp
-		}
-
-		// 最大タイムスタンプの更新
-		if result.LastTimestamp.After(stats.LastTimestamp) {
-			stats.LastTimestamp = result.LastTimestamp
-		}
+		stats = aggregator.MergeStats(stats, result)
 	}
 
 	return stats, firstError
@@ -5,6 +5,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
 )
 
 // TestConcurrentProcessor_ProcessFiles_Success は ConcurrentProcessor の ProcessFiles メソッドの成功ケースをテストします。
@@ -227,3 +229,176 @@ func TestConcurrentProcessor_ProcessFiles_PartialErrors(t *testing.T) {
 	}
 
 }
+
+// TestConcurrentProcessor_ProcessFiles_Distinct は複数ファイルにまたがる異なり数がワーカー間で統合されることをテストします。
+func TestConcurrentProcessor_ProcessFiles_Distinct(t *testing.T) {
+	// 一部のホストが重複する一時的ログファイルを作成
+	tmpDir := t.TempDir()
+	var filePaths []string
+	for i := 0; i < 3; i++ {
+		filePath := fmt.Sprintf("%s/logfile_%d.log", tmpDir, i)
+		content := fmt.Sprintf(`2024-01-01 12:00:00 [INFO] request host=web-%d
+2024-01-01 12:01:00 [INFO] request host=web-%d
+2024-01-01 12:02:00 [ERROR] request host=web-shared
+`, i*2, i*2+1)
+
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("一時ログファイルの作成に失敗しました: %v", err)
+		}
+		filePaths = append(filePaths, filePath)
+	}
+
+	// ConcurrentProcessor の初期化
+	cp := NewConcurrentProcessor(2)
+	cp.SetDistinctFields("host")
+
+	// ファイルの処理
+	stats, err := cp.ProcessFiles(filePaths)
+	if err != nil {
+		t.Fatalf("ProcessFiles メソッドがエラーを返しました: %v", err)
+	}
+
+	t.Logf("集約結果: %+v", stats.Distinct["host"])
+
+	// 結果の検証 (web-0〜web-5 と web-shared の7種類)
+	if stats.Distinct["host"].Estimate != 7 {
+		t.Errorf("host の異なり数が期待値と異なります。期待値: 7, 実際: %d", stats.Distinct["host"].Estimate)
+	}
+}
+
+// TestConcurrentProcessor_ProcessFiles_Rules は複数ファイルにまたがるルールの件数がワーカー間で合計されることをテストします。
+func TestConcurrentProcessor_ProcessFiles_Rules(t *testing.T) {
+	// 決済の失敗を含む一時的ログファイルを作成
+	tmpDir := t.TempDir()
+	var filePaths []string
+	for i := 0; i < 3; i++ {
+		filePath := fmt.Sprintf("%s/logfile_%d.log", tmpDir, i)
+		content := `2024-01-01 12:00:00 [INFO] payment accepted amount=100
+2024-01-01 12:01:00 [ERROR] payment failed amount=2500
+2024-01-01 12:02:00 [WARN] container killed reason=OOMKilled
+`
+
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("一時ログファイルの作成に失敗しました: %v", err)
+		}
+		filePaths = append(filePaths, filePath)
+	}
+
+	// ConcurrentProcessor の初期化
+	cp := NewConcurrentProcessor(2)
+	err := cp.SetRules([]pipeline.Rule{
+		{Name: "payment_failures", Message: `^payment failed`},
+		{Name: "oom_kills", Field: "reason", Equals: "OOMKilled"},
+		{Name: "unused", Level: "ERROR", Message: "disk full"},
+	})
+	if err != nil {
+		t.Fatalf("SetRules メソッドがエラーを返しました: %v", err)
+	}
+
+	// ファイルの処理
+	stats, err := cp.ProcessFiles(filePaths)
+	if err != nil {
+		t.Fatalf("ProcessFiles メソッドがエラーを返しました: %v", err)
+	}
+
+	t.Logf("集約結果: %+v", stats.Rules)
+
+	// 結果の検証 (一致しないルールも 0 件として含む)
+	expected := map[string]int{"payment_failures": 3, "oom_kills": 3, "unused": 0}
+	for name, count := range expected {
+		actual, ok := stats.Rules[name]
+		if !ok || actual != count {
+			t.Errorf("ルール %s の件数が期待値と異なります。期待値: %d, 実際: %d", name, count, actual)
+		}
+	}
+}
+
+// TestConcurrentProcessor_ProcessFiles_FileLocations はタイムゾーンの異なるホストのログの時刻が統合後に揃うことをテストします。
+func TestConcurrentProcessor_ProcessFiles_FileLocations(t *testing.T) {
+	// JST のホストと UTC のホストの同じ瞬間のログ
+	tmpDir := t.TempDir()
+	jstPath := tmpDir + "/jst.log"
+	utcPath := tmpDir + "/utc.log"
+	if err := os.WriteFile(jstPath, []byte("2024-01-01 21:00:00 [INFO] started\n"), 0644); err != nil {
+		t.Fatalf("一時ログファイルの作成に失敗しました: %v", err)
+	}
+	if err := os.WriteFile(utcPath, []byte("2024-01-01 12:00:00 [INFO] started\n2024-01-01 12:30:00.500 [ERROR] failed\n"), 0644); err != nil {
+		t.Fatalf("一時ログファイルの作成に失敗しました: %v", err)
+	}
+
+	// ConcurrentProcessor の初期化
+	cp := NewConcurrentProcessor(2)
+	cp.SetFileLocation(jstPath, time.FixedZone("JST", 9*60*60))
+
+	// ファイルの処理
+	stats, err := cp.ProcessFiles([]string{jstPath, utcPath})
+	if err != nil {
+		t.Fatalf("ProcessFiles メソッドがエラーを返しました: %v", err)
+	}
+
+	t.Logf("集約結果: %+v", stats)
+
+	// 結果の検証
+	expectedFirst := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	expectedLast := time.Date(2024, 1, 1, 12, 30, 0, 500*int(time.Millisecond), time.UTC)
+	if !stats.FirstTimestamp.Equal(expectedFirst) || !stats.LastTimestamp.Equal(expectedLast) {
+		t.Errorf("タイムスタンプの範囲が期待値と異なります: %v 〜 %v", stats.FirstTimestamp, stats.LastTimestamp)
+	}
+}
+
+// TestConcurrentProcessor_ProcessFiles_Sources はファイルごとの発生源で絞り込めることをテストします。
+func TestConcurrentProcessor_ProcessFiles_Sources(t *testing.T) {
+	// サービスごとの一時的ログファイルを作成
+	tmpDir := t.TempDir()
+	var filePaths []string
+	for _, name := range []string{"api", "web", "worker"} {
+		filePath := fmt.Sprintf("%s/%s.log", tmpDir, name)
+		content := `2024-01-01 12:00:00 [INFO] request accepted
+2024-01-01 12:01:00 [ERROR] request failed
+`
+
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("一時ログファイルの作成に失敗しました: %v", err)
+		}
+		filePaths = append(filePaths, filePath)
+	}
+
+	tests := []struct {
+		name     string
+		filter   pipeline.FilterConfig
+		expected int
+	}{
+		// 既定の発生源はファイル名から拡張子を除いた名前、worker.log は jobs を設定
+		{"含める発生源", pipeline.FilterConfig{Sources: []string{"api", "jobs"}}, 4},
+		{"除外する発生源", pipeline.FilterConfig{ExcludeSources: []string{"api"}}, 4},
+		{"設定で置き換えた発生源", pipeline.FilterConfig{Sources: []string{"worker"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ConcurrentProcessor の初期化
+			cp := NewConcurrentProcessor(2)
+			cp.SetFileSource(filePaths[2], "jobs")
+			cp.SetDistinctFields("source")
+			if err := cp.AddFilter(tt.filter); err != nil {
+				t.Fatalf("AddFilter メソッドがエラーを返しました: %v", err)
+			}
+
+			// ファイルの処理
+			stats, err := cp.ProcessFiles(filePaths)
+			if err != nil {
+				t.Fatalf("ProcessFiles メソッドがエラーを返しました: %v", err)
+			}
+
+			// 結果の検証
+			if stats.TotalCount != tt.expected {
+				t.Errorf("総ログ数が期待値と異なります。期待値: %d, 実際: %d", tt.expected, stats.TotalCount)
+			}
+			if expected := 6 - tt.expected; stats.Filtered != expected {
+				t.Errorf("取り除いたログ数が期待値と異なります。期待値: %d, 実際: %d", expected, stats.Filtered)
+			}
+			if expected := uint64(tt.expected / 2); stats.Distinct["source"].Estimate != expected {
+				t.Errorf("発生源の異なり数が期待値と異なります。期待値: %d, 実際: %d", expected, stats.Distinct["source"].Estimate)
+			}
+		})
+	}
+}
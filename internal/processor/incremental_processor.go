@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/parser"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// IncrementalProcessor は1つのファイルに追記された行を順に集約し続けるプロセッサの構造体です。
+// 追記のたびにファイル全体を読み直さずに統計情報を更新するために使用します。
+type IncrementalProcessor struct {
+	// 集約するファイルのパス
+	filePath string
+	// パーサー (最初の行を処理するときに作成する)
+	parser *parser.StandardParser
+	// 処理段
+	chain *pipeline.Chain
+	// 集約器
+	aggregator *aggregator.LogAggregator
+	// 解析できなかった行数
+	rejected int
+	// 再開前に集約した統計情報
+	restored models.Stats
+	// 集約器に付加する拡張の設定
+	pipelineConfig
+}
+
+// NewIncrementalProcessor は指定したファイルの行を集約する IncrementalProcessor の新しいインスタンスを作成します。
+// 設定は最初の行を処理する前に行ってください。
+func NewIncrementalProcessor(filePath string) *IncrementalProcessor {
+	return &IncrementalProcessor{filePath: filePath}
+}
+
+// ProcessLine は1行を解析し、処理段を通して集約器に追加します。
+// 解析できない行は拒否した行として数え、デッドレターの書き込み先がある場合は書き込みます。
+// 監視を止めないように解析のエラーは返さず、デッドレターへの書き込みのエラーのみを返します。
+func (ip *IncrementalProcessor) ProcessLine(lineNumber int, line string) error {
+	ip.init()
+
+	// ログ行の解析
+	entry, err := ip.parser.Parse(line)
+	if err != nil {
+		if ip.deadLetter != nil {
+			if err = ip.reject(ip.filePath, lineNumber, line, err); err != nil {
+				return err
+			}
+		}
+		ip.rejected++
+		return nil
+	}
+
+	// 処理段を通して統計情報を更新
+	for _, processed := range ip.chain.Process(entry) {
+		ip.aggregator.Add(processed)
+	}
+
+	return nil
+}
+
+// GetStats はこれまでに処理した行の統計情報を返します。処理段に保留されているエントリは含まれません。
+func (ip *IncrementalProcessor) GetStats() models.Stats {
+	ip.init()
+
+	stats := ip.aggregator.GetStats()
+	stats.Rejected = ip.rejected
+	ip.chain.Report(&stats)
+
+	return aggregator.MergeStats(ip.restored, stats)
+}
+
+// Flush は処理段に保留されているエントリを集約器に追加し、追加したエントリ数を返します。
+func (ip *IncrementalProcessor) Flush() int {
+	ip.init()
+
+	flushed := ip.chain.Flush()
+	for _, processed := range flushed {
+		ip.aggregator.Add(processed)
+	}
+
+	return len(flushed)
+}
+
+// Restore は再開前に集約した統計情報を設定します。以降の GetStats はこの統計情報に新しく処理した行を統合して返します。
+// 直近の一定時間の統計情報は再開後のエントリから計算し直すため、引き継ぎません。
+func (ip *IncrementalProcessor) Restore(stats models.Stats) {
+	stats.Window = nil
+	ip.restored = stats
+}
+
+// Reset は集約した統計情報と処理段の状態を破棄します。次の行から集約をやり直します。
+func (ip *IncrementalProcessor) Reset() {
+	ip.restored = models.Stats{}
+	ip.parser = nil
+	ip.chain = nil
+	ip.aggregator = nil
+	ip.rejected = 0
+}
+
+// init はパーサー、処理段、集約器を作成します。作成済みの場合は何もしません。
+func (ip *IncrementalProcessor) init() {
+	if ip.aggregator != nil {
+		return
+	}
+
+	ip.parser = ip.newParser(ip.filePath)
+	ip.chain = ip.newChain()
+	ip.aggregator = ip.newAggregator()
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
+)
+
+// TestIncrementalProcessor_ProcessLine は IncrementalProcessor が処理した行を累積して集約することをテストします。
+func TestIncrementalProcessor_ProcessLine(t *testing.T) {
+	ip := NewIncrementalProcessor("app.log")
+
+	// 1回目の追記
+	lines := []string{
+		"2024-06-01 12:00:00 [INFO] アプリケーションが起動しました。",
+		"2024-06-01 12:05:00 [ERROR] データベース接続に失敗しました。",
+	}
+	for i, line := range lines {
+		if err := ip.ProcessLine(i+1, line); err != nil {
+			t.Fatalf("ProcessLine メソッドがエラーを返しました: %v", err)
+		}
+	}
+	if stats := ip.GetStats(); stats.TotalCount != 2 || stats.ErrorCount != 1 {
+		t.Fatalf("統計情報が期待値と異なります: %+v", stats)
+	}
+
+	// 2回目の追記は前回までの統計情報に加算される
+	if err := ip.ProcessLine(3, "2024-06-01 12:10:00 [WARN] メモリ使用量が高くなっています。"); err != nil {
+		t.Fatalf("ProcessLine メソッドがエラーを返しました: %v", err)
+	}
+	stats := ip.GetStats()
+
+	t.Logf("集約結果: %+v", stats)
+
+	if stats.TotalCount != 3 || stats.InfoCount != 1 || stats.WarnCount != 1 || stats.ErrorCount != 1 {
+		t.Errorf("統計情報が期待値と異なります: %+v", stats)
+	}
+
+	// リセット後は最初から集約する
+	ip.Reset()
+	if stats := ip.GetStats(); stats.TotalCount != 0 {
+		t.Errorf("リセット後の TotalCount が0ではありません: %d", stats.TotalCount)
+	}
+}
+
+// TestIncrementalProcessor_ProcessLine_DeadLetter は解析できない行がデッドレターに書き込まれ、書き込み先がない場合も拒否した行として数えられることをテストします。
+func TestIncrementalProcessor_ProcessLine_DeadLetter(t *testing.T) {
+	// 書き込み先がない場合もエラーにせず数える
+	ip := NewIncrementalProcessor("app.log")
+	if err := ip.ProcessLine(1, "壊れた行"); err != nil {
+		t.Fatalf("ProcessLine メソッドがエラーを返しました: %v", err)
+	}
+	if stats := ip.GetStats(); stats.Rejected != 1 {
+		t.Errorf("書き込み先がない場合の Rejected が期待値と異なります: %d", stats.Rejected)
+	}
+
+	// 書き込み先がある場合は行番号とともに書き込む
+	buffer := deadletter.NewBuffer(10)
+	ip = NewIncrementalProcessor("app.log")
+	ip.SetDeadLetter(buffer)
+	if err := ip.ProcessLine(7, "壊れた行"); err != nil {
+		t.Fatalf("ProcessLine メソッドがエラーを返しました: %v", err)
+	}
+
+	rejected := buffer.Lines("app.log")
+	if len(rejected) != 1 || rejected[0].Line != 7 {
+		t.Fatalf("デッドレターの内容が期待値と異なります: %+v", rejected)
+	}
+	if stats := ip.GetStats(); stats.Rejected != 1 {
+		t.Errorf("Rejected が期待値と異なります: %d", stats.Rejected)
+	}
+
+}
@@ -2,55 +2,94 @@ package processor
 
 import (
 	"github.com/Yamituki/go-review-logagg/internal/aggregator"
-	"github.com/Yamituki/go-review-logagg/internal/parser"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
 	"github.com/Yamituki/go-review-logagg/internal/reader"
 	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
 
 // LogProcessor はログを処理するための構造体です。
-type LogProcessor struct{}
+type LogProcessor struct {
+	// 集約器に付加する拡張の設定
+	pipelineConfig
+}
 
 // NewLogProcessor は新しい LogProcessor インスタンスを作成します。
 func NewLogProcessor() *LogProcessor {
 	return &LogProcessor{}
 }
 
+// ProcessFile はログファイルを処理し、統計情報を返します。
 func (lp *LogProcessor) ProcessFile(filePath string) (models.Stats, error) {
+	var stats models.Stats
+
+	// アグリゲーターの初期化
+	ag := lp.newAggregator()
+
+	// ファイルの集約
+	chain, rejected, err := lp.aggregate(filePath, ag)
+	if err != nil {
+		return stats, err
+	}
+
+	// 最終的な統計情報を取得し、処理段の結果を書き込む
+	stats = ag.GetStats()
+	stats.Rejected = rejected
+	chain.Report(&stats)
+
+	return stats, nil
+}
+
+// Aggregate はログファイルの各行を解析し、指定された集約器に追加します。
+func (lp *LogProcessor) Aggregate(filePath string, ag aggregator.Aggregator) error {
+	_, _, err := lp.aggregate(filePath, ag)
+	return err
+}
+
+// aggregate はログファイルの各行を処理段に通して集約器に追加し、使用した処理段と解析できなかった行数を返します。
+func (lp *LogProcessor) aggregate(filePath string, ag aggregator.Aggregator) (*pipeline.Chain, int, error) {
 	// ファイルリーダーの初期化
 	fr := reader.NewFileReader(filePath)
 
-	var stats models.Stats
 	var le models.LogEntry
 	var line string
 	var err error
 
-	// パーサーの初期化
-	ps := parser.NewStandardParser()
-
-	// アグリゲーターの初期化
-	ag := aggregator.NewLogAggregator()
+	// パーサーと処理段の初期化
+	ps := lp.newParser(filePath)
+	chain := lp.newChain()
 
 	// すべての行を読み込む
 	var lines []string
 	lines, err = fr.ReadAllLines()
 	if err != nil {
-		return stats, err
+		return nil, 0, err
 	}
 
 	// 各行を処理
-	for _, line = range lines {
-		// ログ行の解析
+	rejected := 0
+	for i := range lines {
+		line = lines[i]
+
+		// ログ行の解析 (解析できない行はデッドレターに書き込む)
 		le, err = ps.Parse(line)
 		if err != nil {
-			return stats, err
+			if err = lp.reject(filePath, i+1, line, err); err != nil {
+				return nil, rejected, err
+			}
+			rejected++
+			continue
 		}
 
-		// 統計情報の更新
-		ag.Add(le)
+		// 処理段を通して統計情報を更新
+		for _, processed := range chain.Process(le) {
+			ag.Add(processed)
+		}
 	}
 
-	// 最終的な統計情報を取得
-	stats = ag.GetStats()
+	// 処理段に保留されているエントリの集約
+	for _, processed := range chain.Flush() {
+		ag.Add(processed)
+	}
 
-	return stats, nil
+	return chain, rejected, nil
 }
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
 )
 
 // TestLogProcessor_ProcessFile_Success は LogProcessor の ProcessFile メソッドの正常系をテストします。
@@ -116,3 +118,45 @@ func TestLogProcessor_ProcessFile_EmptyFile(t *testing.T) {
 		t.Errorf("期待される警告エントリ数 0, 実際の警告エントリ数 %d", stats.WarnCount)
 	}
 }
+
+// TestLogProcessor_ProcessFile_DeadLetter は解析できない行がデッドレターに書き込まれ、処理が続くことをテストします。
+func TestLogProcessor_ProcessFile_DeadLetter(t *testing.T) {
+	// 解析できない行を含む一時的なログファイルを作成
+	tmpFile := filepath.Join(t.TempDir(), "test_dead_letter.log")
+	logContent := `2024-06-01 12:00:00 [INFO] アプリケーションが起動しました。
+2024-06-01 12:05:00 [DEBUG] 不明なレベルの行です。
+this line is not a log entry at all
+2024-06-01 12:10:00 [ERROR] データベース接続に失敗しました。
+`
+
+	if err := os.WriteFile(tmpFile, []byte(logContent), 0644); err != nil {
+		t.Fatalf("一時ログファイルの作成に失敗しました: %v", err)
+	}
+
+	// デッドレターを設定した LogProcessor のインスタンスを作成
+	buffer := deadletter.NewBuffer(10)
+	lp := NewLogProcessor()
+	lp.SetDeadLetter(buffer)
+
+	// ProcessFile メソッドを呼び出し
+	stats, err := lp.ProcessFile(tmpFile)
+	if err != nil {
+		t.Fatalf("ProcessFile メソッドの実行に失敗しました: %v", err)
+	}
+
+	t.Logf("取得した統計情報: %+v", stats)
+
+	// 解析できた行は集計される
+	if stats.TotalCount != 2 || stats.Rejected != 2 {
+		t.Errorf("統計情報が期待値と異なります: %+v", stats)
+	}
+
+	// 解析できない行はファイル名、行番号、エラーとともに保持される
+	lines := buffer.Lines(tmpFile)
+	if len(lines) != 2 {
+		t.Fatalf("期待されるデッドレターの行数 2, 実際の行数 %d", len(lines))
+	}
+	if lines[0].Line != 2 || lines[1].Line != 3 || lines[1].Raw != "this line is not a log entry at all" || lines[0].Error == "" {
+		t.Errorf("デッドレターの行が期待値と異なります: %+v", lines)
+	}
+}
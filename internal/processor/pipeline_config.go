@@ -0,0 +1,265 @@
+package processor
+
+/*
+ * errors パッケージはエラーの作成を提供します。
+ * path/filepath パッケージはファイルパスの操作を提供します。
+ * strings パッケージは文字列の操作を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
+	"github.com/Yamituki/go-review-logagg/internal/parser"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// numericField は数値統計を計算するフィールドと集約方法の組です。
+type numericField struct {
+	field   string
+	options aggregator.NumericOptions
+}
+
+// windowSetting はスライディングウィンドウの長さ、時間分解能、現在時刻の決め方の組です。
+type windowSetting struct {
+	length     time.Duration
+	resolution time.Duration
+	source     aggregator.TimeSource
+}
+
+// pipelineConfig はプロセッサが解析したエントリを集約するまでの処理段と、集約器に付加する拡張の設定です。
+type pipelineConfig struct {
+	// パーサーの設定
+	parserConfig parser.StandardParserConfig
+	// ファイルごとのタイムゾーン (キーはファイルパス)
+	fileLocations map[string]*time.Location
+	// ファイルごとの発生源 (キーはファイルパス、ない場合はファイル名から決める)
+	fileSources map[string]string
+	// ファイルごとに作成する処理段の生成関数一覧
+	stages []func() pipeline.Stage
+	// 機密情報の置換に使用する Redactor 一覧 (解析できなかった行にも適用する)
+	redactors []*pipeline.Redactor
+	// エントリにタグを付加して数えるルール (他の処理段より先に適用する)
+	rules *pipeline.RuleSet
+	// 異なり数を推定するフィールド一覧
+	distinctFields []string
+	// 数値統計を計算するフィールド一覧
+	numericFields []numericField
+	// スライディングウィンドウの設定 (nil の場合は計算しない)
+	window *windowSetting
+	// 解析したエントリを転送する集約器一覧
+	sinks []aggregator.Aggregator
+	// 解析できなかった行の書き込み先 (nil の場合は解析のエラーとして扱う)
+	deadLetter deadletter.Sink
+}
+
+// SetParserConfig はログ行の解析に使用するパーサーの設定 (タイムゾーンや追加の書式) を設定します。
+func (pc *pipelineConfig) SetParserConfig(config parser.StandardParserConfig) {
+	pc.parserConfig = config
+}
+
+// SetFileLocation は指定したファイルのタイムゾーンを含まないタイムスタンプを解釈するタイムゾーンを設定します。
+// 異なるタイムゾーンのホストのログを統合する場合に使用します。
+func (pc *pipelineConfig) SetFileLocation(filePath string, location *time.Location) {
+	if pc.fileLocations == nil {
+		pc.fileLocations = make(map[string]*time.Location)
+	}
+	pc.fileLocations[filePath] = location
+}
+
+// SetFileSource は指定したファイルから解析したエントリの発生源を設定します。
+// 設定しない場合の発生源はファイル名から拡張子を除いた名前 (例: /var/log/api.log は api) です。
+func (pc *pipelineConfig) SetFileSource(filePath string, source string) {
+	if pc.fileSources == nil {
+		pc.fileSources = make(map[string]string)
+	}
+	pc.fileSources[filePath] = source
+}
+
+// newParser は指定したファイルの解析に使用するパーサーを作成します。
+func (pc *pipelineConfig) newParser(filePath string) *parser.StandardParser {
+	config := pc.parserConfig
+	if location, ok := pc.fileLocations[filePath]; ok {
+		config.Location = location
+	}
+	config.Source = sourceName(filePath)
+	if source, ok := pc.fileSources[filePath]; ok {
+		config.Source = source
+	}
+	return parser.NewStandardParserWithConfig(config)
+}
+
+// sourceName はファイルパスから拡張子を除いたファイル名を発生源として返します。
+func sourceName(filePath string) string {
+	name := filepath.Base(filePath)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// AddStage は解析したエントリを集約前に通す処理段を追加します。
+// 処理段は状態を持つため、ファイルごとに factory で作成されます。
+func (pc *pipelineConfig) AddStage(factory func() pipeline.Stage) {
+	pc.stages = append(pc.stages, factory)
+}
+
+// AddFilter は条件を満たさないエントリを取り除く処理段を追加します。条件が不正な場合はエラーを返します。
+// 取り除いた件数は統計情報の Filtered に含まれます。
+func (pc *pipelineConfig) AddFilter(config pipeline.FilterConfig) error {
+	filter, err := pipeline.NewFilter(config)
+	if err != nil {
+		return err
+	}
+	pc.AddStage(func() pipeline.Stage { return pipeline.NewFilterStage(filter) })
+
+	return nil
+}
+
+// AddRedaction はメッセージとフィールドの機密情報を置換する処理段を追加します。設定が不正な場合はエラーを返します。
+// 機密情報は集約器、サンプル、転送先、解析できなかった行の書き込み先に渡る前に置換され、置換数は統計情報の Redactions に含まれます。
+func (pc *pipelineConfig) AddRedaction(config pipeline.RedactConfig) error {
+	redactor, err := pipeline.NewRedactor(config)
+	if err != nil {
+		return err
+	}
+	pc.redactors = append(pc.redactors, redactor)
+	pc.AddStage(func() pipeline.Stage { return pipeline.NewRedactStage(redactor) })
+
+	return nil
+}
+
+// AddSampling はエントリを抽出率に従って間引く処理段を追加します。設定が不正な場合はエラーを返します。
+// 残したエントリの件数は抽出率の逆数倍に補正され、統計情報の Sampling に推定値であることが示されます。
+func (pc *pipelineConfig) AddSampling(config pipeline.SampleConfig) error {
+	sampler, err := pipeline.NewSampler(config)
+	if err != nil {
+		return err
+	}
+	pc.AddStage(func() pipeline.Stage { return pipeline.NewSampleStage(sampler) })
+
+	return nil
+}
+
+// SetRules はエントリにタグを付加して件数を数えるルールを設定します。ルールが不正な場合はエラーを返します。
+// ルールは他の処理段より先に適用され、件数は統計情報の Rules に含まれます。
+func (pc *pipelineConfig) SetRules(rules []pipeline.Rule) error {
+	if len(rules) == 0 {
+		pc.rules = nil
+		return nil
+	}
+
+	rs, err := pipeline.NewRuleSet(rules)
+	if err != nil {
+		return err
+	}
+	pc.rules = rs
+
+	return nil
+}
+
+// SetDistinctFields は異なり数を推定するフィールドを設定します。
+// 推定値はワーカー間でスケッチ単位で統合されます。
+func (pc *pipelineConfig) SetDistinctFields(fields ...string) {
+	pc.distinctFields = fields
+}
+
+// AddNumericField は数値統計 (件数、合計、最小値、最大値、平均値、分位点) を計算するフィールドを追加します。
+func (pc *pipelineConfig) AddNumericField(field string, options aggregator.NumericOptions) {
+	pc.numericFields = append(pc.numericFields, numericField{field: field, options: options})
+}
+
+// SetWindow は直近の一定時間のレベル別のログ数、1分あたりのレート、割合を計算するスライディングウィンドウを設定します。
+// 結果は統計情報の Window に含まれます。過去のログの解析では EventTime、リアルタイム監視では WallClock を指定します。
+func (pc *pipelineConfig) SetWindow(length, resolution time.Duration, source aggregator.TimeSource) {
+	pc.window = &windowSetting{length: length, resolution: resolution, source: source}
+}
+
+// AddSink は解析したすべてのエントリを転送する集約器を追加します。
+// 並行プロセッサでは複数のワーカーから呼ばれるため、スレッドセーフな集約器を指定してください。
+func (pc *pipelineConfig) AddSink(sink aggregator.Aggregator) {
+	pc.sinks = append(pc.sinks, sink)
+}
+
+// SetDeadLetter は解析できなかった行の書き込み先を設定します。
+// 設定した場合、解析できなかった行はファイル名、行番号、エラーとともに書き込まれ、処理は中断されません。
+func (pc *pipelineConfig) SetDeadLetter(sink deadletter.Sink) {
+	pc.deadLetter = sink
+}
+
+// reject は解析できなかった行を書き込み先に書き込みます。書き込み先がない場合は解析のエラーを返します。
+// 機密情報の置換を設定している場合は、行とエラー (行の内容を含む場合がある) を置換してから書き込み、
+// 書き込み先がない場合に返すエラーも置換します。
+func (pc *pipelineConfig) reject(filePath string, lineNumber int, line string, parseErr error) error {
+	message := parseErr.Error()
+	for _, redactor := range pc.redactors {
+		line = redactor.Redact(line, nil)
+		message = redactor.Redact(message, nil)
+	}
+
+	if pc.deadLetter == nil {
+		if len(pc.redactors) == 0 {
+			return parseErr
+		}
+		return errors.New(message)
+	}
+
+	return pc.deadLetter.Write(models.RejectedLine{
+		File:       filePath,
+		Line:       lineNumber,
+		Raw:        line,
+		Error:      message,
+		RejectedAt: time.Now(),
+	})
+}
+
+// newAggregator は設定された拡張を付加した集約器を作成します。
+func (pc *pipelineConfig) newAggregator() *aggregator.LogAggregator {
+	ag := aggregator.NewLogAggregator()
+	if pc.rules != nil {
+		ag.Attach(aggregator.NewRuleCounter(pc.rules.Names()...))
+	}
+	for _, field := range pc.distinctFields {
+		ag.Attach(aggregator.NewDistinctCounter(field))
+	}
+	for _, numeric := range pc.numericFields {
+		ag.Attach(aggregator.NewNumericCounter(numeric.field, numeric.options))
+	}
+	if pc.window != nil {
+		ag.Attach(aggregator.NewWindowCounter(pc.window.length, pc.window.resolution, pc.window.source))
+	}
+	for _, sink := range pc.sinks {
+		ag.Attach(sinkExtension{sink: sink})
+	}
+	return ag
+}
+
+// newChain はファイルごとの処理段の連なりを作成します。
+func (pc *pipelineConfig) newChain() *pipeline.Chain {
+	stages := make([]pipeline.Stage, 0, len(pc.stages)+1)
+	if pc.rules != nil {
+		stages = append(stages, pipeline.NewRuleStage(pc.rules))
+	}
+	for _, factory := range pc.stages {
+		stages = append(stages, factory())
+	}
+	return pipeline.NewChain(stages...)
+}
+
+// sinkExtension はエントリを別の集約器へ転送する拡張です。
+type sinkExtension struct {
+	sink aggregator.Aggregator
+}
+
+// Add はエントリを転送先の集約器に追加します。
+func (se sinkExtension) Add(entry models.LogEntry) {
+	se.sink.Add(entry)
+}
+
+// Apply は何もしません。転送先の統計情報は転送先から取得します。
+func (se sinkExtension) Apply(stats *models.Stats) {}
+
+// Reset は何もしません。転送先の状態は転送先の所有者が管理します。
+func (se sinkExtension) Reset() {}
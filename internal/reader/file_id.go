@@ -0,0 +1,22 @@
+package reader
+
+/*
+ * os パッケージはOSの機能（ファイル操作など）を提供します。
+ */
+import "os"
+
+// FileID はファイルを名前によらずに識別するデバイス番号と inode 番号の組です。
+type FileID struct {
+	// デバイス番号
+	Device uint64 `json:"device"`
+	// inode 番号
+	Inode uint64 `json:"inode"`
+}
+
+// IdentifyFile はファイルの情報から FileID を取得します。プラットフォームが inode を提供しない場合は false を返します。
+func IdentifyFile(info os.FileInfo) (FileID, bool) {
+	if info == nil {
+		return FileID{}, false
+	}
+	return fileID(info)
+}
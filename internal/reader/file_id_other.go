@@ -0,0 +1,13 @@
+//go:build !unix
+
+package reader
+
+/*
+ * os パッケージはOSの機能（ファイル操作など）を提供します。
+ */
+import "os"
+
+// fileID は inode 番号を取得できないため、常に false を返します。
+func fileID(info os.FileInfo) (FileID, bool) {
+	return FileID{}, false
+}
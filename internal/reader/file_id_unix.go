@@ -0,0 +1,21 @@
+//go:build unix
+
+package reader
+
+/*
+ * os パッケージはOSの機能（ファイル操作など）を提供します。
+ * syscall パッケージはファイルの inode 番号を提供します。
+ */
+import (
+	"os"
+	"syscall"
+)
+
+// fileID はファイルの情報からデバイス番号と inode 番号を取得します。
+func fileID(info os.FileInfo) (FileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, false
+	}
+	return FileID{Device: uint64(stat.Dev), Inode: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,196 @@
+package reader
+
+/*
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * crypto/sha256 パッケージは SHA-256 ハッシュを提供します。
+ * encoding/hex パッケージは16進数の文字列への変換を提供します。
+ * errors パッケージはエラーの判定を提供します。
+ * io パッケージは基本的な入出力インターフェースを提供します。
+ * io/fs パッケージはファイルシステムのエラーを提供します。
+ * os パッケージはOSの機能（ファイル操作など）を提供します。
+ * path/filepath パッケージはファイルパスの操作を提供します。
+ */
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TailCheckpoint は TailReader の読み込み位置を再開できるように保存した状態を表す構造体です。
+// 保留中の末尾の行はファイルから読み直すため、長さとハッシュのみを保存します。
+type TailCheckpoint struct {
+	// 読み込み中のファイルの識別子 (プラットフォームが inode を提供しない場合は nil)
+	ID *FileID `json:"id,omitempty"`
+	// 読み込み済みのバイト数 (保留中の末尾の行を含む)
+	Offset int64 `json:"offset"`
+	// 読み込み中のファイルで返した行数
+	Lines int `json:"lines"`
+	// 保留中の末尾の行のバイト数
+	PartialLength int `json:"partial_length,omitempty"`
+	// 保留中の末尾の行の SHA-256 ハッシュ (16進数)
+	PartialHash string `json:"partial_hash,omitempty"`
+	// 末尾の行を改行を待たずに確定したかどうか
+	Flushed bool `json:"flushed,omitempty"`
+	// 検出したローテーションと切り詰め
+	Events []models.RotationEvent `json:"events,omitempty"`
+}
+
+// Checkpoint は現在の読み込み位置を保存するための状態を返します。
+func (tr *TailReader) Checkpoint() TailCheckpoint {
+	cp := TailCheckpoint{
+		Offset:        tr.offset,
+		Lines:         tr.lines,
+		PartialLength: len(tr.partial),
+		Flushed:       tr.flushed,
+		Events:        tr.Events(),
+	}
+	if id, ok := IdentifyFile(tr.info); ok {
+		cp.ID = &id
+	}
+	if len(tr.partial) > 0 {
+		cp.PartialHash = hashPartial(tr.partial)
+	}
+
+	return cp
+}
+
+// Restore は保存した状態から読み込みを再開します。
+// 保存後にパスのファイルがローテーションされていた場合は、同じディレクトリから名前が変わった以前のファイルを探して続きを読み込みます。
+// 以前のファイルが見つからない場合や、ファイルが切り詰められた・書き換えられた場合は、ローテーションまたは切り詰めとして記録し、パスのファイルを先頭から読み込みます。
+func (tr *TailReader) Restore(cp TailCheckpoint) error {
+	// 現在の状態を破棄
+	tr.Close()
+	tr.reset()
+	tr.info = nil
+	tr.events = append([]models.RotationEvent(nil), cp.Events...)
+
+	// 保存したファイルを開く
+	file, info, err := tr.locate(cp.ID)
+	if errors.Is(err, fs.ErrNotExist) {
+		// ファイルがまだない場合は作成されてから先頭から読み込む
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	tr.file = file
+	tr.info = info
+
+	// ファイルの識別子が一致しない場合は以前のファイルを読み込めない
+	if id, ok := IdentifyFile(info); cp.ID != nil && ok && id != *cp.ID {
+		tr.offset = cp.Offset
+		tr.record(models.Rotated)
+		tr.reset()
+		return nil
+	}
+
+	// 切り詰められた、または保留中の行が書き換えられた場合は先頭から読み込む
+	partial, err := readPartial(file, cp)
+	if err != nil {
+		return err
+	}
+	if info.Size() < cp.Offset || partial == nil {
+		tr.offset = cp.Offset
+		tr.record(models.Truncated)
+		tr.reset()
+		return nil
+	}
+
+	// 読み込み位置の復元
+	tr.offset = cp.Offset
+	tr.lines = cp.Lines
+	tr.partial = partial
+	tr.flushed = cp.Flushed
+
+	return nil
+}
+
+// locate は保存したファイルを開きます。パスのファイルが別のファイルの場合は、同じディレクトリから識別子が一致するファイルを探します。
+// 見つからない場合はパスのファイルを返します。
+func (tr *TailReader) locate(id *FileID) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(tr.filepath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	var info os.FileInfo
+	if err == nil {
+		if info, err = file.Stat(); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		if current, ok := IdentifyFile(info); id == nil || !ok || current == *id {
+			return file, info, nil
+		}
+	}
+
+	// 名前が変わった以前のファイルを探す
+	if id != nil {
+		dir := filepath.Dir(tr.filepath)
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			entryInfo, err := entry.Info()
+			if err != nil || !entryInfo.Mode().IsRegular() {
+				continue
+			}
+			if current, ok := IdentifyFile(entryInfo); !ok || current != *id {
+				continue
+			}
+			previous, err := os.Open(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				break
+			}
+			previousInfo, err := previous.Stat()
+			if err != nil {
+				previous.Close()
+				break
+			}
+			if file != nil {
+				file.Close()
+			}
+			return previous, previousInfo, nil
+		}
+	}
+
+	if file == nil {
+		return nil, nil, fs.ErrNotExist
+	}
+	return file, info, nil
+}
+
+// readPartial は保存した保留中の末尾の行をファイルから読み直し、ハッシュが一致すればその内容を返します。
+// 保留中の行がない場合は空のスライスを、一致しない場合は nil を返します。
+func readPartial(file *os.File, cp TailCheckpoint) ([]byte, error) {
+	if cp.PartialLength == 0 {
+		return []byte{}, nil
+	}
+	if int64(cp.PartialLength) > cp.Offset {
+		return nil, nil
+	}
+
+	partial := make([]byte, cp.PartialLength)
+	if _, err := file.ReadAt(partial, cp.Offset-int64(cp.PartialLength)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if hashPartial(partial) != cp.PartialHash || bytes.IndexByte(partial, '\n') >= 0 {
+		return nil, nil
+	}
+
+	return partial, nil
+}
+
+// hashPartial は保留中の末尾の行の SHA-256 ハッシュを16進数で返します。
+func hashPartial(partial []byte) string {
+	sum := sha256.Sum256(partial)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,264 @@
+package reader
+
+/*
+ * bufio パッケージはバッファ付きの入出力を提供します。
+ * bytes パッケージはバイトスライスの操作を提供します。
+ * errors パッケージはエラーの判定を提供します。
+ * io パッケージは基本的な入出力インターフェースを提供します。
+ * io/fs パッケージはファイルシステムのエラーを提供します。
+ * os パッケージはOSの機能（ファイル操作など）を提供します。
+ * time パッケージは時間の操作を提供します。
+ */
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// maxRotationEvents は保持するローテーションの記録の最大数です (古いものから破棄)。
+const maxRotationEvents = 100
+
+// TailReader はファイルの読み込み済みの位置を記憶し、追記された行だけを読み込むための構造体です。
+// 読み込み中のファイルを開いたまま保持するため、ローテーションで名前が変更されたファイルも最後まで読み込めます。
+type TailReader struct {
+	// 読み込むファイルのパス
+	filepath string
+	// 読み込み中のファイル (未オープンの場合は nil)
+	file *os.File
+	// 読み込み中のファイルの情報 (同一のファイルかどうかの判定に使用)
+	info os.FileInfo
+	// 読み込み済みのバイト数 (途中までの行を含む)
+	offset int64
+	// 改行で終わっていない末尾の行
+	partial []byte
+	// 末尾の行を改行を待たずに確定したかどうか (直後の改行は読み飛ばす)
+	flushed bool
+	// 読み込み中のファイルで返した行数
+	lines int
+	// 検出したローテーションと切り詰め
+	events []models.RotationEvent
+}
+
+// NewTailReader は指定されたファイルパスをファイルの先頭から読み込む TailReader を初期化します。
+func NewTailReader(filepath string) *TailReader {
+	return &TailReader{filepath: filepath}
+}
+
+// ReadNewLines は前回の読み込み以降に追記された行を順に fn に渡します。
+// fn には読み込み中のファイルでの1から始まる行番号と、末尾の改行 (\n または \r\n) を除いた行が渡されます。
+// 改行で終わっていない末尾の行は書き込みの途中とみなして改行が追記されるまで保留し、
+// ローテーション、切り詰め、または Flush の呼び出しで1行として確定します。
+//
+// パスが別のファイルに置き換えられていた場合は、以前のファイルを最後まで読み込んでから新しいファイルを先頭から読み込みます。
+// ファイルが読み込み済みの位置より小さくなっていた場合は、切り詰められたとみなして先頭から読み込みます。
+// fn がエラーを返した場合は読み込みを中断し、そのエラーを返します。
+func (tr *TailReader) ReadNewLines(fn func(lineNumber int, line string) error) error {
+	// ファイルを開く
+	if tr.file == nil {
+		if err := tr.open(); err != nil {
+			return err
+		}
+	}
+
+	// パスの現在のファイルの情報を取得
+	current, err := os.Stat(tr.filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// 名前が変更されて新しいファイルがまだない場合は、以前のファイルの追記を読み込む
+		return tr.read(fn, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !os.SameFile(tr.info, current):
+		// ローテーション: 以前のファイルを最後まで読み込み、新しいファイルに切り替える
+		if err := tr.read(fn, true); err != nil {
+			return err
+		}
+		tr.record(models.Rotated)
+		tr.Close()
+		if err := tr.open(); err != nil {
+			return err
+		}
+	case current.Size() < tr.offset:
+		// 切り詰め: 保留していた行を確定し、先頭から読み込む
+		if len(tr.partial) > 0 {
+			if err := tr.flush(fn); err != nil {
+				return err
+			}
+		}
+		tr.record(models.Truncated)
+		tr.reset()
+	}
+
+	return tr.read(fn, false)
+}
+
+// Offset は読み込み中のファイルの読み込み済みのバイト数を返します。
+func (tr *TailReader) Offset() int64 {
+	return tr.offset
+}
+
+// Lines は読み込み中のファイルでこれまでに返した行数を返します。
+func (tr *TailReader) Lines() int {
+	return tr.lines
+}
+
+// Info は読み込み中のファイルの情報を返します。まだ開いていない場合は nil を返します。
+// os.SameFile で、別の名前のファイルが読み込み中のファイルと同一かどうかを判定できます。
+func (tr *TailReader) Info() os.FileInfo {
+	return tr.info
+}
+
+// Pending は改行で終わっていない末尾の行を保留しているかどうかを返します。
+func (tr *TailReader) Pending() bool {
+	return len(tr.partial) > 0
+}
+
+// Flush は保留している改行で終わっていない末尾の行を1行として確定し、fn に渡します。保留している行がない場合は何もしません。
+// 以降の追記を読み込まない場合 (監視の停止など) に呼び出します。確定した行の直後の改行は次の読み込みで読み飛ばします。
+func (tr *TailReader) Flush(fn func(lineNumber int, line string) error) error {
+	if len(tr.partial) == 0 {
+		return nil
+	}
+	return tr.flush(fn)
+}
+
+// Events は検出したローテーションと切り詰めを古い順に返します。
+func (tr *TailReader) Events() []models.RotationEvent {
+	return append([]models.RotationEvent(nil), tr.events...)
+}
+
+// Close は読み込み中のファイルを閉じます。次の読み込みではパスのファイルを開き直し、読み込み済みの位置から読み込みます。
+func (tr *TailReader) Close() error {
+	if tr.file == nil {
+		return nil
+	}
+
+	err := tr.file.Close()
+	tr.file = nil
+	return err
+}
+
+// open はパスのファイルを開きます。以前のファイルから切り替えた場合は先頭から読み込みます。
+func (tr *TailReader) open() error {
+	file, err := os.Open(tr.filepath)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	// 以前と別のファイルであれば先頭から読み込む
+	if tr.info != nil && !os.SameFile(tr.info, info) {
+		tr.reset()
+	}
+	tr.file = file
+	tr.info = info
+
+	return nil
+}
+
+// read は読み込み中のファイルの読み込み済みの位置から末尾までの行を fn に渡します。
+// final が true の場合はファイルへの追記がもうないものとして、改行で終わっていない末尾の行も確定します。
+func (tr *TailReader) read(fn func(lineNumber int, line string) error, final bool) error {
+	// 読み込み済みの位置から読み込む
+	if _, err := tr.file.Seek(tr.offset, io.SeekStart); err != nil {
+		return err
+	}
+	br := bufio.NewReader(tr.file)
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		tr.offset += int64(len(chunk))
+
+		// 改行を待たずに確定した行の直後の改行は読み飛ばす
+		if tr.flushed && len(chunk) > 0 {
+			tr.flushed = false
+			if chunk[0] == '\n' {
+				chunk = chunk[1:]
+				if len(chunk) == 0 {
+					continue
+				}
+			}
+		}
+
+		// 行の途中まで (行がバッファより長い場合または末尾の行)
+		if err == bufio.ErrBufferFull || (err == io.EOF && len(chunk) > 0) {
+			tr.partial = append(tr.partial, chunk...)
+			if err == io.EOF {
+				break
+			}
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// 1行が揃った
+		line := append(tr.partial, chunk...)
+		tr.partial = tr.partial[:0]
+		if err := tr.emit(line, fn); err != nil {
+			return err
+		}
+	}
+
+	// 追記が終わった場合は保留していた末尾の行を確定する
+	if final && len(tr.partial) > 0 {
+		return tr.flush(fn)
+	}
+
+	return nil
+}
+
+// flush は保留していた末尾の行を1行として確定し、fn に渡します。
+func (tr *TailReader) flush(fn func(lineNumber int, line string) error) error {
+	line := tr.partial
+	tr.partial = nil
+	tr.flushed = true
+	return tr.emit(line, fn)
+}
+
+// record はローテーションまたは切り詰めを記録します。
+func (tr *TailReader) record(kind models.RotationKind) {
+	tr.events = append(tr.events, models.RotationEvent{
+		File:       tr.filepath,
+		Kind:       kind,
+		Offset:     tr.offset,
+		DetectedAt: time.Now(),
+	})
+
+	// 上限を超えた場合は古いものから破棄
+	if len(tr.events) > maxRotationEvents {
+		tr.events = tr.events[len(tr.events)-maxRotationEvents:]
+	}
+}
+
+// reset は読み込みの位置と保留していた行を破棄し、先頭から読み込むようにします。
+func (tr *TailReader) reset() {
+	tr.offset = 0
+	tr.partial = nil
+	tr.flushed = false
+	tr.lines = 0
+}
+
+// emit は末尾の改行を除いた行を fn に渡します。
+func (tr *TailReader) emit(line []byte, fn func(lineNumber int, line string) error) error {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	tr.lines++
+	return fn(tr.lines, string(line))
+}
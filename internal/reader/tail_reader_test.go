@@ -0,0 +1,364 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// TestTailReader_ReadNewLines は TailReader が追記された行だけを読み込み、改行で終わっていない末尾の行を Flush まで保留することをテストします。
+func TestTailReader_ReadNewLines(t *testing.T) {
+	// テスト用の一時ファイルを作成
+	tmpFile := filepath.Join(t.TempDir(), "tail.log")
+	if err := os.WriteFile(tmpFile, []byte("行1\r\n行2\n行3の前半"), 0644); err != nil {
+		t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+	}
+
+	tr := NewTailReader(tmpFile)
+
+	// 読み込んだ行を収集する
+	var lines []string
+	var numbers []int
+	collect := func(lineNumber int, line string) error {
+		numbers = append(numbers, lineNumber)
+		lines = append(lines, line)
+		return nil
+	}
+
+	// 1回目: 改行で終わる行のみ
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"行1", "行2"}) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+
+	// 行の続きを追記
+	appendFile(t, tmpFile, "と後半\n行4")
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"行1", "行2", "行3の前半と後半"}) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+
+	// 書き込みが止まっていても末尾の行は確定しない
+	for range 3 {
+		if err := tr.ReadNewLines(collect); err != nil {
+			t.Fatalf("ReadNewLines に失敗しました: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(lines, []string{"行1", "行2", "行3の前半と後半"}) || !tr.Pending() {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+
+	// Flush で末尾の行を確定する
+	if err := tr.Flush(collect); err != nil {
+		t.Fatalf("Flush に失敗しました: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"行1", "行2", "行3の前半と後半", "行4"}) || tr.Pending() {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+
+	// 確定した行の直後の改行は空行として扱わない
+	appendFile(t, tmpFile, "\n行5\n")
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+	expected := []string{"行1", "行2", "行3の前半と後半", "行4", "行5"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+	if !reflect.DeepEqual(numbers, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("行番号が期待値と異なります: %v", numbers)
+	}
+
+	// 読み込み済みの位置はファイルの末尾
+	info, err := os.Stat(tmpFile)
+	if err != nil {
+		t.Fatalf("ファイルの情報の取得に失敗しました: %v", err)
+	}
+	if tr.Offset() != info.Size() {
+		t.Errorf("読み込み済みの位置が期待値と異なります: 期待値=%d, 実際=%d", info.Size(), tr.Offset())
+	}
+}
+
+// TestTailReader_ReadNewLines_LongLine は TailReader がバッファより長い行を1行として読み込むことをテストします。
+func TestTailReader_ReadNewLines_LongLine(t *testing.T) {
+	// バッファ (4096バイト) より長い行
+	long := make([]byte, 10000)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "long.log")
+	if err := os.WriteFile(tmpFile, append(long, '\n'), 0644); err != nil {
+		t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+	}
+
+	var lines []string
+	err := NewTailReader(tmpFile).ReadNewLines(func(lineNumber int, line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+
+	if len(lines) != 1 || lines[0] != string(long) {
+		t.Errorf("長い行が1行として読み込まれていません: %d 行", len(lines))
+	}
+}
+
+// TestTailReader_ReadNewLines_Rotation は TailReader がローテーションされたファイルを読み終えてから新しいファイルを先頭から読み込むことをテストします。
+func TestTailReader_ReadNewLines_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	tmpFile := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(tmpFile, []byte("行1\n"), 0644); err != nil {
+		t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+	}
+
+	tr := NewTailReader(tmpFile)
+	defer tr.Close()
+
+	var lines []string
+	collect := func(lineNumber int, line string) error {
+		lines = append(lines, line)
+		return nil
+	}
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+
+	// ローテーションの直前に追記され、名前が変更される
+	appendFile(t, tmpFile, "行2\n行3")
+	if err := os.Rename(tmpFile, tmpFile+".1"); err != nil {
+		t.Fatalf("ファイル名の変更に失敗しました: %v", err)
+	}
+
+	// 新しいファイルがまだない間も、以前のファイルの追記を読み込む
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"行1", "行2"}) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+
+	// 新しいファイルが作成される
+	appendFile(t, tmpFile+".1", "の続き\n")
+	if err := os.WriteFile(tmpFile, []byte("新1\n"), 0644); err != nil {
+		t.Fatalf("新しいログファイルの作成に失敗しました: %v", err)
+	}
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+
+	// 以前のファイルを読み終えてから新しいファイルを読み込む
+	expected := []string{"行1", "行2", "行3の続き", "新1"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+	if tr.Lines() != 1 || tr.Offset() != int64(len("新1\n")) {
+		t.Errorf("新しいファイルの読み込み位置が期待値と異なります: 行数=%d, 位置=%d", tr.Lines(), tr.Offset())
+	}
+
+	// ローテーションが記録される
+	events := tr.Events()
+	if len(events) != 1 || events[0].Kind != models.Rotated || events[0].Offset != int64(len("行1\n行2\n行3の続き\n")) {
+		t.Errorf("ローテーションの記録が期待値と異なります: %+v", events)
+	}
+}
+
+// TestTailReader_ReadNewLines_Truncation は TailReader が切り詰められたファイルを先頭から読み込み直すことをテストします。
+func TestTailReader_ReadNewLines_Truncation(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(tmpFile, []byte("行1\n行2\n"), 0644); err != nil {
+		t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+	}
+
+	tr := NewTailReader(tmpFile)
+	defer tr.Close()
+
+	var lines []string
+	collect := func(lineNumber int, line string) error {
+		lines = append(lines, line)
+		return nil
+	}
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+
+	// copytruncate で切り詰められた後に書き込まれる
+	if err := os.Truncate(tmpFile, 0); err != nil {
+		t.Fatalf("ファイルの切り詰めに失敗しました: %v", err)
+	}
+	appendFile(t, tmpFile, "行3\n")
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+
+	if !reflect.DeepEqual(lines, []string{"行1", "行2", "行3"}) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+
+	// 切り詰めが記録される
+	events := tr.Events()
+	if len(events) != 1 || events[0].Kind != models.Truncated || events[0].Offset != int64(len("行1\n行2\n")) {
+		t.Errorf("切り詰めの記録が期待値と異なります: %+v", events)
+	}
+}
+
+// appendFile はファイルに文字列を追記します。
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("ファイルを開けませんでした: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("ファイルへの追記に失敗しました: %v", err)
+	}
+}
+
+// TestTailReader_Restore は保存した状態から保留中の末尾の行を含めて読み込みを再開できることをテストします。
+func TestTailReader_Restore(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(tmpFile, []byte("行1\n行2の前半"), 0644); err != nil {
+		t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+	}
+
+	var lines []string
+	var numbers []int
+	collect := func(lineNumber int, line string) error {
+		numbers = append(numbers, lineNumber)
+		lines = append(lines, line)
+		return nil
+	}
+
+	// 保留中の行がある状態で保存
+	tr := NewTailReader(tmpFile)
+	if err := tr.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+	cp := tr.Checkpoint()
+	tr.Close()
+	if cp.PartialLength != len("行2の前半") || cp.PartialHash == "" {
+		t.Fatalf("保留中の行が保存されていません: %+v", cp)
+	}
+
+	// 停止中に追記され、別の TailReader で再開する
+	appendFile(t, tmpFile, "と後半\n行3\n")
+	restored := NewTailReader(tmpFile)
+	defer restored.Close()
+	if err := restored.Restore(cp); err != nil {
+		t.Fatalf("Restore に失敗しました: %v", err)
+	}
+	if err := restored.ReadNewLines(collect); err != nil {
+		t.Fatalf("ReadNewLines に失敗しました: %v", err)
+	}
+
+	// 重複も欠落もなく続きから読み込む
+	if !reflect.DeepEqual(lines, []string{"行1", "行2の前半と後半", "行3"}) {
+		t.Fatalf("読み込んだ行が期待値と異なります: %q", lines)
+	}
+	if !reflect.DeepEqual(numbers, []int{1, 2, 3}) {
+		t.Errorf("行番号が期待値と異なります: %v", numbers)
+	}
+	if len(restored.Events()) != 0 {
+		t.Errorf("ローテーションが記録されています: %+v", restored.Events())
+	}
+}
+
+// TestTailReader_Restore_Changed は停止中にファイルが切り詰められた・ローテーションされた場合の再開をテストします。
+func TestTailReader_Restore_Changed(t *testing.T) {
+	// 保存した状態を作成する
+	checkpointFor := func(t *testing.T, path string) TailCheckpoint {
+		t.Helper()
+		tr := NewTailReader(path)
+		defer tr.Close()
+		if err := tr.ReadNewLines(func(int, string) error { return nil }); err != nil {
+			t.Fatalf("ReadNewLines に失敗しました: %v", err)
+		}
+		return tr.Checkpoint()
+	}
+
+	t.Run("切り詰め", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "app.log")
+		if err := os.WriteFile(tmpFile, []byte("行1\n行2\n"), 0644); err != nil {
+			t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+		}
+		cp := checkpointFor(t, tmpFile)
+
+		// 停止中に切り詰められて書き込まれる
+		if err := os.WriteFile(tmpFile, []byte("新1\n"), 0644); err != nil {
+			t.Fatalf("ファイルの書き込みに失敗しました: %v", err)
+		}
+
+		tr := NewTailReader(tmpFile)
+		defer tr.Close()
+		if err := tr.Restore(cp); err != nil {
+			t.Fatalf("Restore に失敗しました: %v", err)
+		}
+		var lines []string
+		if err := tr.ReadNewLines(func(_ int, line string) error {
+			lines = append(lines, line)
+			return nil
+		}); err != nil {
+			t.Fatalf("ReadNewLines に失敗しました: %v", err)
+		}
+
+		if !reflect.DeepEqual(lines, []string{"新1"}) {
+			t.Errorf("読み込んだ行が期待値と異なります: %q", lines)
+		}
+		if events := tr.Events(); len(events) != 1 || events[0].Kind != models.Truncated {
+			t.Errorf("切り詰めの記録が期待値と異なります: %+v", events)
+		}
+	})
+
+	t.Run("ローテーション", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "app.log")
+		if err := os.WriteFile(tmpFile, []byte("行1\n"), 0644); err != nil {
+			t.Fatalf("テスト用ログファイルの作成に失敗しました: %v", err)
+		}
+		cp := checkpointFor(t, tmpFile)
+		if cp.ID == nil {
+			t.Skip("このプラットフォームでは inode を取得できません")
+		}
+
+		// 停止中に追記されてからローテーションされる
+		appendFile(t, tmpFile, "行2\n")
+		if err := os.Rename(tmpFile, tmpFile+".1"); err != nil {
+			t.Fatalf("ファイル名の変更に失敗しました: %v", err)
+		}
+		if err := os.WriteFile(tmpFile, []byte("新1\n"), 0644); err != nil {
+			t.Fatalf("ファイルの書き込みに失敗しました: %v", err)
+		}
+
+		tr := NewTailReader(tmpFile)
+		defer tr.Close()
+		if err := tr.Restore(cp); err != nil {
+			t.Fatalf("Restore に失敗しました: %v", err)
+		}
+		var lines []string
+		if err := tr.ReadNewLines(func(_ int, line string) error {
+			lines = append(lines, line)
+			return nil
+		}); err != nil {
+			t.Fatalf("ReadNewLines に失敗しました: %v", err)
+		}
+
+		// 以前のファイルの続きを読んでから新しいファイルを読み込む
+		if !reflect.DeepEqual(lines, []string{"行2", "新1"}) {
+			t.Errorf("読み込んだ行が期待値と異なります: %q", lines)
+		}
+		if events := tr.Events(); len(events) != 1 || events[0].Kind != models.Rotated {
+			t.Errorf("ローテーションの記録が期待値と異なります: %+v", events)
+		}
+	})
+}
@@ -4,18 +4,55 @@ package server
  * encoding/json パッケージは JSON エンコードとデコードを提供します
  * fmt パッケージはフォーマットされたI/Oを提供します
  * net/http パッケージは HTTP クライアントとサーバーの実装を提供します
+ * strconv パッケージは文字列と基本データ型の変換を提供します
+ * time パッケージは時間の操作を提供します
  */
 import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/Yamituki/go-review-logagg/internal/processor"
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
 
 // jsonRequest は JSON リクエストの共通構造を表します。
 type jsonRequest struct {
 	Filepath string `json:"filepath"`
+	// 処理段と集約の指定
+	pipelineRequest
+}
+
+// anomaliesRequest は異常検知リクエストの構造を表します。
+type anomaliesRequest struct {
+	jsonRequest
+	// 時間バケットの長さ (例: "1m")
+	Bucket string `json:"bucket,omitempty"`
+	// 異常とみなす z スコアの閾値
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// tracesRequest はリクエスト相関リクエストの構造を表します。
+type tracesRequest struct {
+	jsonRequest
+	// 複数のファイルをまとめて相関させる場合のファイルパス一覧
+	Filepaths []string `json:"filepaths,omitempty"`
+	// リクエストを識別するフィールド名 (既定は "request_id")
+	Field string `json:"field,omitempty"`
+}
+
+// traceWorkers はリクエスト相関でファイルを並行して読み込むワーカー数の上限です。
+const traceWorkers = 4
+
+// analyzeResponse はログ集約のレスポンスを表します。
+type analyzeResponse struct {
+	// 統計情報
+	models.Stats
+	// このリクエストで解析できなかった行 (dead_letter を指定した場合のみ、上限を超えた分は古い順に破棄する)
+	DeadLetters []models.RejectedLine `json:"dead_letters,omitempty"`
 }
 
 // jsonResponse は JSON レスポンスの共通構造を表します。
@@ -67,8 +104,15 @@ func handleAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ログファイルの解析処理
-	ps := processor.NewLogProcessor()
+	// ログファイルの解析処理 (解析できなかった行はこのリクエストのレスポンスにのみ含める)
+	if req.DeadLetter {
+		req.deadLetters = deadletter.NewBuffer(deadLetterCapacity)
+	}
+	ps, err := req.newProcessor()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"%s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
 	stats, err := ps.ProcessFile(req.Filepath)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(`{"status":"error","data":"ログファイルの解析に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
@@ -80,12 +124,214 @@ func handleAnalyze(w http.ResponseWriter, r *http.Request) {
 
 	// レスポンスボディを JSON 形式で返します。
 	resp.Status = "ok"
-	resp.Data = stats
+	data := analyzeResponse{Stats: stats}
+	if req.deadLetters != nil {
+		data.DeadLetters = req.deadLetters.Lines("")
+	}
+	resp.Data = data
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"レスポンスの生成に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonResp)
+}
+
+// handleTemplates はログテンプレート一覧のハンドラーです。
+// クエリパラメータ id を指定した場合は、そのテンプレートのみを返します。
+func handleTemplates(w http.ResponseWriter, r *http.Request) {
+	// 戻り値の型は jsonResponse を使用します。
+	w.Header().Set("Content-Type", "application/json")
+
+	// 終了時にボディを閉じます。
+	defer r.Body.Close()
+
+	var req jsonRequest
+	var resp jsonResponse
+
+	// リクエストの解析
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"リクエストの解析に失敗しました: %s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// テンプレートIDの解析
+	id := 0
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		var err error
+		id, err = strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"テンプレートIDが不正です: %s"}`, idStr), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// ログファイルのテンプレート抽出
+	miner := aggregator.NewTemplateMiner()
+	ps, err := req.newProcessor()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"%s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err := ps.Aggregate(req.Filepath, miner); err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"ログファイルの解析に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// レスポンスデータの決定
+	resp.Status = "ok"
+	if id != 0 {
+		template, ok := miner.Template(id)
+		if !ok {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"テンプレートが見つかりません: %d"}`, id), http.StatusNotFound)
+			return
+		}
+		resp.Data = template
+	} else {
+		resp.Data = miner.Templates()
+	}
+
+	// レスポンスボディを JSON 形式で返します。
 	jsonResp, err := json.Marshal(resp)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(`{"status":"error","data":"レスポンスの生成に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
 		return
 	}
 
+	// 処理結果の状態を返します。
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// handleAnomalies はエラー急増などの異常検知のハンドラーです。
+func handleAnomalies(w http.ResponseWriter, r *http.Request) {
+	// 戻り値の型は jsonResponse を使用します。
+	w.Header().Set("Content-Type", "application/json")
+
+	// 終了時にボディを閉じます。
+	defer r.Body.Close()
+
+	var req anomaliesRequest
+	var resp jsonResponse
+
+	// リクエストの解析
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"リクエストの解析に失敗しました: %s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// 異常検知の設定
+	config := aggregator.DefaultAnomalyConfig()
+	if req.Bucket != "" {
+		bucket, err := time.ParseDuration(req.Bucket)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"時間バケットの指定が不正です: %s"}`, req.Bucket), http.StatusBadRequest)
+			return
+		}
+		config.BucketSize = bucket
+	}
+	if req.Threshold > 0 {
+		config.Threshold = req.Threshold
+	}
+
+	// ログファイルの異常検知
+	detector := aggregator.NewAnomalyDetector(config)
+	ps, err := req.newProcessor()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"%s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err := ps.Aggregate(req.Filepath, detector); err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"ログファイルの解析に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	detector.Flush()
+
+	// レスポンスボディを JSON 形式で返します。
+	resp.Status = "ok"
+	resp.Data = detector.Anomalies()
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"レスポンスの生成に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// 処理結果の状態を返します。
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// handleTraces はリクエストIDやトレースIDによる相関のハンドラーです。
+// クエリパラメータ id を指定した場合は、そのリクエストのタイムスタンプ順のログ一覧を返します。
+func handleTraces(w http.ResponseWriter, r *http.Request) {
+	// 戻り値の型は jsonResponse を使用します。
+	w.Header().Set("Content-Type", "application/json")
+
+	// 終了時にボディを閉じます。
+	defer r.Body.Close()
+
+	var req tracesRequest
+	var resp jsonResponse
+
+	// リクエストの解析
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"リクエストの解析に失敗しました: %s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// 対象ファイルの決定
+	paths := req.Filepaths
+	if req.Filepath != "" {
+		paths = append([]string{req.Filepath}, paths...)
+	}
+	if len(paths) == 0 {
+		http.Error(w, `{"status":"error","data":"ファイルパスが指定されていません"}`, http.StatusBadRequest)
+		return
+	}
+
+	// ファイルをまたいだリクエストの相関
+	config := aggregator.DefaultCorrelatorConfig()
+	if req.Field != "" {
+		config.Field = req.Field
+	}
+	correlator := aggregator.NewCorrelator(config)
+
+	cp, err := req.newConcurrentProcessor(min(len(paths), traceWorkers))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"%s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	cp.AddSink(correlator)
+	if _, err := cp.ProcessFiles(paths); err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"ログファイルの解析に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// レスポンスデータの決定
+	resp.Status = "ok"
+	if id := r.URL.Query().Get("id"); id != "" {
+		trace, ok := correlator.Trace(id)
+		if !ok {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"リクエストが見つかりません: %s"}`, id), http.StatusNotFound)
+			return
+		}
+		resp.Data = trace
+	} else {
+		resp.Data = correlator.Traces()
+	}
+
+	// レスポンスボディを JSON 形式で返します。
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"レスポンスの生成に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// 処理結果の状態を返します。
+	w.WriteHeader(http.StatusOK)
 	w.Write(jsonResp)
 }
@@ -3,11 +3,13 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Yamituki/go-review-logagg/pkg/models"
 )
@@ -229,3 +231,513 @@ func TestHandleAnalyze_FileNotFound(t *testing.T) {
 		t.Errorf("期待されるエラーメッセージ %s, 実際のエラーメッセージ %s", expectedErrorMessage, errorMessage)
 	}
 }
+
+// TestHandleTemplates_Success は handleTemplates ハンドラーの成功ケースのテストを行います。
+func TestHandleTemplates_Success(t *testing.T) {
+	// 一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	logFileContent := `2024-10-01 12:00:00 [ERROR] request 101 failed after 3 retries
+2024-10-01 12:05:00 [ERROR] request 102 failed after 5 retries
+2024-10-01 12:10:00 [INFO] アプリケーションが起動しました
+`
+
+	if err := os.WriteFile(logFilePath, []byte(logFileContent), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON, err := json.Marshal(jsonRequest{Filepath: logFilePath})
+	if err != nil {
+		t.Fatalf("リクエストボディの生成に失敗しました: %s", err.Error())
+	}
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/templates", bytes.NewBuffer(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleTemplates(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディの解析
+	var resp struct {
+		Status string               `json:"status"`
+		Data   []models.LogTemplate `json:"data"`
+	}
+	if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+	}
+
+	if len(resp.Data) != 2 {
+		t.Fatalf("期待されるテンプレート数 2, 実際のテンプレート数 %d", len(resp.Data))
+	}
+
+	expectedTemplate := "request <*> failed after <*> retries"
+	if resp.Data[0].Template != expectedTemplate || resp.Data[0].Count != 2 {
+		t.Errorf("期待されるテンプレート %s (2件), 実際のテンプレート %s (%d件)", expectedTemplate, resp.Data[0].Template, resp.Data[0].Count)
+	}
+
+	if len(resp.Data[0].Samples) != 2 {
+		t.Errorf("期待されるサンプル数 2, 実際のサンプル数 %d", len(resp.Data[0].Samples))
+	}
+}
+
+// TestHandleTemplates_NotFound は handleTemplates ハンドラーで存在しないテンプレートIDを指定した場合のテストを行います。
+func TestHandleTemplates_NotFound(t *testing.T) {
+	// 一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	if err := os.WriteFile(logFilePath, []byte("2024-10-01 12:00:00 [INFO] started\n"), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON, err := json.Marshal(jsonRequest{Filepath: logFilePath})
+	if err != nil {
+		t.Fatalf("リクエストボディの生成に失敗しました: %s", err.Error())
+	}
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/templates?id=99", bytes.NewBuffer(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleTemplates(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusNotFound {
+		t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusNotFound, testRec.Code)
+	}
+}
+
+// TestHandleAnalyze_Numeric は handleAnalyze ハンドラーの数値統計のテストを行います。
+func TestHandleAnalyze_Numeric(t *testing.T) {
+	// 一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	logFileContent := `2024-10-01 12:00:00 [INFO] GET /api host=web-1 duration_ms=100
+2024-10-01 12:00:30 [INFO] GET /api host=web-1 duration_ms=300
+2024-10-01 12:01:00 [WARN] GET /api host=web-2 duration_ms=900
+`
+
+	if err := os.WriteFile(logFilePath, []byte(logFileContent), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON := fmt.Sprintf(`{"filepath": %q, "numeric": [{"field": "duration_ms", "group_by": "host", "bucket": "1m"}]}`, logFilePath)
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleAnalyze(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディの解析
+	var resp struct {
+		Status string       `json:"status"`
+		Data   models.Stats `json:"data"`
+	}
+	if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+	}
+
+	report := resp.Data.Numeric["duration_ms"]
+	if report.Overall.Count != 3 || report.Overall.Max != 900 || report.Overall.Sum != 1300 {
+		t.Errorf("全体の数値統計が期待値と異なります: %+v", report.Overall)
+	}
+	if report.Groups["web-1"].Count != 2 {
+		t.Errorf("web-1 の件数が期待値と異なります: %d", report.Groups["web-1"].Count)
+	}
+	if len(report.Buckets) != 2 {
+		t.Errorf("期待される時間バケット数 2, 実際の時間バケット数 %d", len(report.Buckets))
+	}
+}
+
+// TestHandleAnalyze_InvalidBucket は handleAnalyze ハンドラーで不正な時間バケットを指定した場合のテストを行います。
+func TestHandleAnalyze_InvalidBucket(t *testing.T) {
+	reqJSON := `{"filepath": "sample.log", "numeric": [{"field": "duration_ms", "bucket": "soon"}]}`
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleAnalyze(testRec, testReq)
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusBadRequest {
+		t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusBadRequest, testRec.Code)
+	}
+}
+
+// TestHandleAnomalies_Success は handleAnomalies ハンドラーの成功ケースのテストを行います。
+func TestHandleAnomalies_Success(t *testing.T) {
+	// 毎分 ERROR 1件の後に ERROR が急増するログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+
+	var content bytes.Buffer
+	for minute := 0; minute < 10; minute++ {
+		fmt.Fprintf(&content, "2024-10-01 12:%02d:00 [ERROR] payment failed code=500\n", minute)
+	}
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&content, "2024-10-01 12:10:%02d [ERROR] payment failed code=500\n", i)
+	}
+
+	if err := os.WriteFile(logFilePath, content.Bytes(), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON := fmt.Sprintf(`{"filepath": %q, "bucket": "1m"}`, logFilePath)
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/anomalies", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleAnomalies(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディの解析
+	var resp struct {
+		Status string           `json:"status"`
+		Data   []models.Anomaly `json:"data"`
+	}
+	if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+	}
+
+	found := false
+	for _, anomaly := range resp.Data {
+		if anomaly.Level == "ERROR" && anomaly.Count == 20 && anomaly.Direction == "spike" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("ERROR の急増が検知されていません: %+v", resp.Data)
+	}
+}
+
+// TestHandleTraces_Timeline は handleTraces ハンドラーで複数ファイルのログを ID ごとのタイムラインにまとめるテストを行います。
+func TestHandleTraces_Timeline(t *testing.T) {
+	// 2つのサービスのログファイルを作成
+	tmpDir := t.TempDir()
+	apiLogPath := tmpDir + "/api.log"
+	dbLogPath := tmpDir + "/db.log"
+	apiLog := `2024-10-01 12:00:00 [INFO] request start request_id=r1
+2024-10-01 12:00:03 [INFO] request done request_id=r1
+2024-10-01 12:00:05 [INFO] request start request_id=r2
+`
+	dbLog := `2024-10-01 12:00:01 [WARN] slow query request_id=r1
+2024-10-01 12:00:06 [ERROR] query failed request_id=r2
+`
+
+	if err := os.WriteFile(apiLogPath, []byte(apiLog), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+	if err := os.WriteFile(dbLogPath, []byte(dbLog), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON := fmt.Sprintf(`{"filepaths": [%q, %q]}`, apiLogPath, dbLogPath)
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/traces?id=r1", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleTraces(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディの解析
+	var resp struct {
+		Status string       `json:"status"`
+		Data   models.Trace `json:"data"`
+	}
+	if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+	}
+
+	trace := resp.Data
+	if trace.Count != 3 || trace.WorstLevel != "WARN" || trace.Duration != 3*time.Second {
+		t.Errorf("リクエスト r1 の集計が期待値と異なります: %+v", trace)
+	}
+	expected := []string{"request start request_id=r1", "slow query request_id=r1", "request done request_id=r1"}
+	if len(trace.Entries) != len(expected) {
+		t.Fatalf("期待されるログ数 %d, 実際のログ数 %d", len(expected), len(trace.Entries))
+	}
+	for i, message := range expected {
+		if trace.Entries[i].Message != message {
+			t.Errorf("%d 番目のログは %q であるべきですが、実際の値は %q です", i, message, trace.Entries[i].Message)
+		}
+	}
+}
+
+// TestHandleAnalyze_Filter は handleAnalyze ハンドラーで絞り込みの条件を指定した場合のテストを行います。
+func TestHandleAnalyze_Filter(t *testing.T) {
+	// 一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	logFileContent := `2024-10-01 12:00:00 [INFO] GET /api status=200
+2024-10-01 12:30:00 [ERROR] GET /api status=503
+2024-10-01 13:00:00 [WARN] GET /api status=404
+2024-10-01 14:00:00 [ERROR] GET /api status=500
+`
+
+	if err := os.WriteFile(logFilePath, []byte(logFileContent), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON := fmt.Sprintf(`{"filepath": %q, "filter": {"level": "WARN", "until": "2024-10-01 14:00:00", "where": ["status>=500"]}}`, logFilePath)
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleAnalyze(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディの解析
+	var resp struct {
+		Status string       `json:"status"`
+		Data   models.Stats `json:"data"`
+	}
+	if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+	}
+
+	// 12:30 の ERROR のみが残る
+	if resp.Data.TotalCount != 1 || resp.Data.ErrorCount != 1 {
+		t.Errorf("絞り込み後の統計情報が期待値と異なります: %+v", resp.Data)
+	}
+}
+
+// TestHandleTemplates_Redact は handleTemplates ハンドラーで機密情報がサンプルに含まれないことのテストを行います。
+func TestHandleTemplates_Redact(t *testing.T) {
+	// 一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	logFileContent := `2024-10-01 12:00:00 [INFO] password reset for alice@example.com from 203.0.113.7
+2024-10-01 12:00:01 [INFO] password reset for bob@example.com from 203.0.113.8
+`
+
+	if err := os.WriteFile(logFilePath, []byte(logFileContent), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON := fmt.Sprintf(`{"filepath": %q, "redact": {}}`, logFilePath)
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/templates", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleTemplates(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディに機密情報が含まれない
+	body := testRec.Body.String()
+	for _, secret := range []string{"alice@example.com", "bob@example.com", "203.0.113.7"} {
+		if bytes.Contains([]byte(body), []byte(secret)) {
+			t.Errorf("レスポンスに機密情報 %q が含まれています", secret)
+		}
+	}
+	if !bytes.Contains([]byte(body), []byte("password reset for [REDACTED:email] from [REDACTED:ipv4]")) {
+		t.Errorf("置換後のテンプレートが含まれていません")
+	}
+}
+
+// TestHandleAnalyze_RedactParseError は handleAnalyze ハンドラーで解析のエラーに含まれる行の機密情報が置換されるテストを行います。
+func TestHandleAnalyze_RedactParseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"区切りのない行", "2024-10-01 12:00:00 login alice@example.com Bearer abcdef0123456789 card 4111111111111111\n"},
+		{"タイムスタンプが不正な行", "alice@example.com Bearer abcdef0123456789 card 4111111111111111 [INFO] login\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logFilePath := t.TempDir() + "/test.log"
+			if err := os.WriteFile(logFilePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+			}
+
+			reqJSON := fmt.Sprintf(`{"filepath": %q, "redact": {}}`, logFilePath)
+			testReq := httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(reqJSON))
+			testRec := httptest.NewRecorder()
+			handleAnalyze(testRec, testReq)
+
+			t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+			// デッドレターを指定していないため解析のエラーになる
+			if testRec.Code != http.StatusInternalServerError {
+				t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusInternalServerError, testRec.Code)
+			}
+
+			// エラーに機密情報が含まれない
+			body := testRec.Body.String()
+			for _, secret := range []string{"alice@example.com", "abcdef0123456789", "4111111111111111"} {
+				if bytes.Contains([]byte(body), []byte(secret)) {
+					t.Errorf("レスポンスに機密情報 %q が含まれています", secret)
+				}
+			}
+		})
+	}
+}
+
+// TestHandleAnalyze_DeadLetters は handleAnalyze ハンドラーで解析できなかった行が機密情報を置換してリクエストごとに返されるテストを行います。
+func TestHandleAnalyze_DeadLetters(t *testing.T) {
+	// 解析できない行を含む一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	logFileContent := `2024-10-01 12:00:00 [INFO] started
+2024-10-01 12:00:01 login failed for alice@example.com
+`
+
+	if err := os.WriteFile(logFilePath, []byte(logFileContent), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	// 同じリクエストを2回送信しても、それぞれのレスポンスにはそのリクエストの行のみが含まれる
+	reqJSON := fmt.Sprintf(`{"filepath": %q, "dead_letter": true, "redact": {"detectors": ["email"]}}`, logFilePath)
+	for i := 0; i < 2; i++ {
+		testReq := httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(reqJSON))
+		testRec := httptest.NewRecorder()
+		handleAnalyze(testRec, testReq)
+
+		t.Logf("ステータスコード: %d", testRec.Code)
+		t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+		// ステータスコードの検証
+		if testRec.Code != http.StatusOK {
+			t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+		}
+
+		// 機密情報はレスポンスに含まれない
+		if bytes.Contains(testRec.Body.Bytes(), []byte("alice@example.com")) {
+			t.Errorf("レスポンスに機密情報が含まれています")
+		}
+
+		// レスポンスボディの解析
+		var resp struct {
+			Status string `json:"status"`
+			Data   struct {
+				Rejected    int                   `json:"rejected"`
+				DeadLetters []models.RejectedLine `json:"dead_letters"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+		}
+
+		lines := resp.Data.DeadLetters
+		if resp.Data.Rejected != 1 || len(lines) != 1 || lines[0].Line != 2 || lines[0].Raw != "2024-10-01 12:00:01 login failed for [REDACTED:email]" {
+			t.Errorf("解析できなかった行が期待値と異なります: %+v", resp.Data)
+		}
+	}
+}
+
+// TestHandleAnalyze_Window は handleAnalyze ハンドラーでスライディングウィンドウを指定した場合のテストを行います。
+func TestHandleAnalyze_Window(t *testing.T) {
+	// 一時的なログファイルを作成
+	tmpDir := t.TempDir()
+	logFilePath := tmpDir + "/test.log"
+	logFileContent := `2024-10-01 12:00:00 [INFO] アプリケーションが起動しました
+2024-10-01 12:08:00 [INFO] リクエストを処理しました
+2024-10-01 12:09:00 [ERROR] データベース接続に失敗しました
+`
+
+	if err := os.WriteFile(logFilePath, []byte(logFileContent), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	reqJSON := fmt.Sprintf(`{"filepath": %q, "window": {"length": "5m", "resolution": "1m"}}`, logFilePath)
+
+	// リクエストの作成
+	testReq := httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(reqJSON))
+	testRec := httptest.NewRecorder()
+
+	// ハンドラーの呼び出し
+	handleAnalyze(testRec, testReq)
+
+	t.Logf("ステータスコード: %d", testRec.Code)
+	t.Logf("レスポンスボディ: %s", testRec.Body.String())
+
+	// ステータスコードの検証
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, testRec.Code)
+	}
+
+	// レスポンスボディの解析
+	var resp struct {
+		Status string       `json:"status"`
+		Data   models.Stats `json:"data"`
+	}
+	if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("レスポンスボディの解析に失敗しました: %s", err.Error())
+	}
+
+	// 全体は3件、直近5分 (12:05〜12:10) は2件
+	window := resp.Data.Window
+	if resp.Data.TotalCount != 3 || window == nil {
+		t.Fatalf("統計情報が期待値と異なります: %+v", resp.Data)
+	}
+	if window.TotalCount != 2 || window.LevelCounts["ERROR"] != 1 || window.Ratios["ERROR"] != 0.5 {
+		t.Errorf("ウィンドウの統計情報が期待値と異なります: %+v", window)
+	}
+
+	// 不正なウィンドウの指定
+	testReq = httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(fmt.Sprintf(`{"filepath": %q, "window": {"length": "5m", "clock": "moon"}}`, logFilePath)))
+	testRec = httptest.NewRecorder()
+	handleAnalyze(testRec, testReq)
+	if testRec.Code != http.StatusBadRequest {
+		t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusBadRequest, testRec.Code)
+	}
+}
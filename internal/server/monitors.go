@@ -0,0 +1,787 @@
+package server
+
+/*
+ * crypto/rand パッケージは暗号論的に安全な乱数を提供します
+ * encoding/hex パッケージは16進数の文字列への変換を提供します
+ * encoding/json パッケージは JSON エンコードとデコードを提供します
+ * errors パッケージはエラーの判定を提供します
+ * fmt パッケージはフォーマットされたI/Oを提供します
+ * io/fs パッケージはファイルシステムのエラーを提供します
+ * net/http パッケージは HTTP サーバーの実装を提供します
+ * os パッケージはファイル操作を提供します
+ * path/filepath パッケージはファイルパスの操作を提供します
+ * slices パッケージはスライスの操作を提供します
+ * sort パッケージはスライスのソートを提供します
+ * sync パッケージは基本的な同期プリミティブを提供します
+ * time パッケージは時間の操作を提供します
+ */
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/alert"
+	"github.com/Yamituki/go-review-logagg/internal/checkpoint"
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
+	"github.com/Yamituki/go-review-logagg/internal/monitor"
+	"github.com/Yamituki/go-review-logagg/internal/processor"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+const (
+	// defaultMonitorInterval は監視間隔の既定値です。
+	defaultMonitorInterval = time.Second
+	// monitorsFile はモニターの定義を保存するファイル名です。
+	monitorsFile = "monitors.json"
+	// maskedSecret はレスポンスで秘密の値の代わりに返す文字列です。
+	maskedSecret = "********"
+)
+
+// monitorRequest はモニターの作成リクエストの構造を表します。Path と Patterns のいずれか一方を指定します。
+type monitorRequest struct {
+	// 監視するファイルのパス
+	Path string `json:"path,omitempty"`
+	// 監視するファイルのパターン (filepath.Glob の形式) またはディレクトリ
+	Patterns []string `json:"patterns,omitempty"`
+	// 監視間隔 (例: "1s"、既定は1秒)
+	Interval string `json:"interval,omitempty"`
+	// アラートのルールと通知先
+	Alerts *alert.RuleConfig `json:"alerts,omitempty"`
+	// アラートの設定ファイルのパス (モニターの作成時とサーバーの起動時に読み込む)
+	AlertsFile string `json:"alerts_file,omitempty"`
+	// パーサー、絞り込み、処理段と集約の指定
+	pipelineRequest
+}
+
+// newAlertEngine はリクエストのアラートの設定で Engine を作成します。アラートを指定しない場合は nil を返します。
+// 設定が不正な場合はエラーを返します。
+func (mr monitorRequest) newAlertEngine() (*alert.Engine, error) {
+	switch {
+	case mr.Alerts != nil && mr.AlertsFile != "":
+		return nil, fmt.Errorf("alerts と alerts_file は同時に指定できません")
+	case mr.Alerts != nil:
+		return mr.Alerts.NewEngine()
+	case mr.AlertsFile != "":
+		config, err := alert.LoadConfigFile(mr.AlertsFile)
+		if err != nil {
+			return nil, err
+		}
+		return config.NewEngine()
+	default:
+		return nil, nil
+	}
+}
+
+// masked はアラートの通知先の SMTP のパスワードと Webhook のヘッダーの値を伏せた設定を返します。
+func (mr monitorRequest) masked() monitorRequest {
+	if mr.Alerts == nil || len(mr.Alerts.Channels) == 0 {
+		return mr
+	}
+
+	config := *mr.Alerts
+	config.Channels = make(map[string]alert.ChannelConfig, len(mr.Alerts.Channels))
+	for name, channel := range mr.Alerts.Channels {
+		if channel.SMTP != nil && channel.SMTP.Password != "" {
+			smtp := *channel.SMTP
+			smtp.Password = maskedSecret
+			channel.SMTP = &smtp
+		}
+		if channel.Webhook != nil && len(channel.Webhook.Headers) > 0 {
+			webhook := *channel.Webhook
+			webhook.Headers = make(map[string]string, len(channel.Webhook.Headers))
+			for key := range channel.Webhook.Headers {
+				webhook.Headers[key] = maskedSecret
+			}
+			channel.Webhook = &webhook
+		}
+		config.Channels[name] = channel
+	}
+	mr.Alerts = &config
+	return mr
+}
+
+// newMonitor はリクエストの設定を反映したモニターを作成します。checkpointPath が空でない場合は状態ファイルを設定します。
+// 設定が不正な場合はエラーを返します。
+func (mr monitorRequest) newMonitor(checkpointPath string) (monitor.Monitor, error) {
+	// 監視間隔
+	interval := defaultMonitorInterval
+	if mr.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(mr.Interval); err != nil || interval <= 0 {
+			return nil, fmt.Errorf("監視間隔の指定が不正です: %s", mr.Interval)
+		}
+	}
+
+	// 監視では実際の時計を既定とするスライディングウィンドウ
+	if mr.Window != nil && mr.Window.Clock == "" {
+		window := *mr.Window
+		window.Clock = "wall"
+		mr.Window = &window
+	}
+
+	switch {
+	case mr.Path != "" && len(mr.Patterns) == 0:
+		// 1つのファイルの監視
+		fm := monitor.NewFileMonitor(mr.Path, interval)
+		if err := mr.configure(fm.Processor()); err != nil {
+			return nil, err
+		}
+		if checkpointPath != "" {
+			fm.SetCheckpointFile(checkpointPath)
+		}
+		return fm, nil
+	case mr.Path == "" && len(mr.Patterns) > 0:
+		// 複数ファイルの監視 (設定は新しく追跡するファイルごとに反映するため、先に検証する)
+		if err := mr.configure(processor.NewIncrementalProcessor("")); err != nil {
+			return nil, err
+		}
+		config := monitor.DefaultMultiFileConfig()
+		config.Patterns = mr.Patterns
+		config.Interval = interval
+		mm := monitor.NewMultiFileMonitor(config)
+		mm.ConfigureProcessor(func(ip *processor.IncrementalProcessor) {
+			mr.configure(ip)
+		})
+		if checkpointPath != "" {
+			mm.SetCheckpointFile(checkpointPath)
+		}
+		return mm, nil
+	default:
+		return nil, fmt.Errorf("path と patterns のいずれか一方を指定してください")
+	}
+}
+
+// monitorActions は /monitors/{id}/{action} で指定できる操作の一覧です (空は /monitors/{id})。
+var monitorActions = []string{"", "pause", "resume", "deadletters", "alerts", "silences"}
+
+// monitorRecord は保存するモニターの定義を表します。
+type monitorRecord struct {
+	// モニターの ID
+	ID string `json:"id"`
+	// 作成した時刻
+	CreatedAt time.Time `json:"created_at"`
+	// 一時停止中かどうか
+	Paused bool `json:"paused,omitempty"`
+	// 作成リクエストの設定
+	Config monitorRequest `json:"config"`
+}
+
+// monitorResponse はモニターの状態のレスポンスを表します。
+type monitorResponse struct {
+	// モニターの ID
+	ID string `json:"id"`
+	// 状態 ("running" または "paused")
+	State string `json:"state"`
+	// 作成した時刻
+	CreatedAt time.Time `json:"created_at"`
+	// 作成リクエストの設定
+	Config monitorRequest `json:"config"`
+	// 現在の統計情報
+	Stats models.Stats `json:"stats"`
+	// ファイルごとの統計情報 (複数ファイルのモニターの詳細のみ)
+	Files map[string]models.Stats `json:"files,omitempty"`
+	// 監視を開始できなかった理由
+	Error string `json:"error,omitempty"`
+}
+
+// managedMonitor は API で管理する1つのモニターです。
+type managedMonitor struct {
+	// 保存する定義
+	monitorRecord
+	// 監視中のモニター (一時停止中は nil)
+	monitor monitor.Monitor
+	// 一時停止した時点の統計情報
+	stats models.Stats
+	// 監視を開始できなかった理由
+	err error
+	// 解析できなかった直近の行 (一時停止しても保持する)
+	deadLetters *deadletter.Buffer
+	// アラートの評価 (アラートを指定しない場合は nil、一時停止しても状態と通知の抑止を保持する)
+	alerts *alert.Engine
+}
+
+// alertsResponse はモニターのアラートの状態のレスポンスを表します。
+type alertsResponse struct {
+	// ルールごとの現在の評価結果 (定義順)
+	Alerts []models.Alert `json:"alerts"`
+	// 終了していない通知を抑止する期間
+	Silences []alert.Silence `json:"silences"`
+}
+
+// silenceRequest は通知を抑止する期間の追加リクエストの構造を表します。End と Duration のいずれか一方を指定します。
+type silenceRequest struct {
+	// 対象のルール名 (空の場合はすべてのルール)
+	Rule string `json:"rule,omitempty"`
+	// 開始時刻 (既定は現在時刻)
+	Start time.Time `json:"start,omitzero"`
+	// 終了時刻
+	End time.Time `json:"end,omitzero"`
+	// 開始時刻からの長さ (例: "2h")
+	Duration string `json:"duration,omitempty"`
+}
+
+// silence はリクエストを検証して通知を抑止する期間を返します。
+func (sr silenceRequest) silence(now time.Time) (alert.Silence, error) {
+	silence := alert.Silence{Rule: sr.Rule, Start: sr.Start, End: sr.End}
+	if silence.Start.IsZero() {
+		silence.Start = now
+	}
+
+	switch {
+	case sr.Duration != "" && !sr.End.IsZero():
+		return silence, fmt.Errorf("end と duration は同時に指定できません")
+	case sr.Duration != "":
+		duration, err := time.ParseDuration(sr.Duration)
+		if err != nil || duration <= 0 {
+			return silence, fmt.Errorf("抑止する長さの指定が不正です: %s", sr.Duration)
+		}
+		silence.End = silence.Start.Add(duration)
+	case sr.End.IsZero():
+		return silence, fmt.Errorf("end と duration のいずれか一方を指定してください")
+	}
+	if !silence.End.After(silence.Start) || !silence.End.After(now) {
+		return silence, fmt.Errorf("抑止する期間の終了時刻が不正です: %s", silence.End.Format(time.RFC3339))
+	}
+
+	return silence, nil
+}
+
+// deadLettersResponse はモニターで解析できなかった行のレスポンスを表します。
+type deadLettersResponse struct {
+	// 破棄したものを含め、これまでに解析できなかった行数
+	Total int `json:"total"`
+	// 保持している直近の行 (機密情報の置換を設定している場合は置換済み、古い順)
+	Lines []models.RejectedLine `json:"lines"`
+}
+
+// monitorManager は API で作成したモニターを管理する構造体です。
+// 状態ディレクトリを設定した場合は、モニターの定義と読み込み位置を保存し、再起動後に監視を再開します。
+type monitorManager struct {
+	// モニター一覧 (キーは ID)
+	monitors map[string]*managedMonitor
+	// 状態ディレクトリ (未設定の場合は定義を保存しない)
+	stateDir string
+	// 状態ディレクトリが未設定の場合に読み込み位置を保存する一時ディレクトリ
+	tempDir string
+	// 並行アクセスを保護するミューテックス
+	mutex sync.Mutex
+}
+
+// newMonitorManager は新しい monitorManager を作成します。
+func newMonitorManager() *monitorManager {
+	return &monitorManager{monitors: make(map[string]*managedMonitor)}
+}
+
+// load は状態ディレクトリからモニターの定義を読み込み、一時停止していないモニターの監視を再開します。
+// 監視を再開できなかったモニターは一時停止として扱い、理由を記録します。
+func (mgr *monitorManager) load(stateDir string) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("状態ディレクトリを作成できませんでした: %v", err)
+	}
+	mgr.stateDir = stateDir
+
+	// 定義の読み込み
+	data, err := os.ReadFile(filepath.Join(stateDir, monitorsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("モニターの定義を読み込めませんでした: %v", err)
+	}
+	var records []monitorRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("モニターの定義の形式が不正です: %v", err)
+	}
+
+	// 監視の再開
+	for _, record := range records {
+		mm := &managedMonitor{monitorRecord: record}
+		mgr.monitors[record.ID] = mm
+		if record.Paused {
+			mm.stats = mgr.savedStats(record.ID)
+			mm.alerts, mm.err = record.Config.newAlertEngine()
+			continue
+		}
+		if err := mgr.start(mm); err != nil {
+			mm.Paused = true
+			mm.err = err
+			mm.stats = mgr.savedStats(record.ID)
+		}
+	}
+
+	return nil
+}
+
+// create は新しいモニターを作成して監視を開始します。
+func (mgr *monitorManager) create(req monitorRequest) (monitorResponse, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	id, err := newMonitorID()
+	if err != nil {
+		return monitorResponse{}, err
+	}
+	mm := &managedMonitor{monitorRecord: monitorRecord{ID: id, CreatedAt: time.Now(), Config: req}}
+	if err := mgr.start(mm); err != nil {
+		return monitorResponse{}, err
+	}
+	mgr.monitors[id] = mm
+
+	if err := mgr.save(); err != nil {
+		return monitorResponse{}, err
+	}
+	return mm.response(false), nil
+}
+
+// list はすべてのモニターの状態を作成順に返します。
+func (mgr *monitorManager) list() []monitorResponse {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	monitors := make([]*managedMonitor, 0, len(mgr.monitors))
+	for _, mm := range mgr.monitors {
+		monitors = append(monitors, mm)
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		if !monitors[i].CreatedAt.Equal(monitors[j].CreatedAt) {
+			return monitors[i].CreatedAt.Before(monitors[j].CreatedAt)
+		}
+		return monitors[i].ID < monitors[j].ID
+	})
+
+	responses := make([]monitorResponse, 0, len(monitors))
+	for _, mm := range monitors {
+		responses = append(responses, mm.response(false))
+	}
+	return responses
+}
+
+// get はモニターの詳細な状態を返します。モニターが存在しない場合は false を返します。
+func (mgr *monitorManager) get(id string) (monitorResponse, bool) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return monitorResponse{}, false
+	}
+	return mm.response(true), true
+}
+
+// pause はモニターの監視を停止します。読み込み位置と統計情報は保持し、再開すると続きから監視します。
+func (mgr *monitorManager) pause(id string) (monitorResponse, bool, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return monitorResponse{}, false, nil
+	}
+	if !mm.Paused {
+		mm.stop()
+		mm.Paused = true
+		if err := mgr.save(); err != nil {
+			return monitorResponse{}, true, err
+		}
+	}
+	return mm.response(false), true, nil
+}
+
+// resume は一時停止したモニターの監視を再開します。
+func (mgr *monitorManager) resume(id string) (monitorResponse, bool, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return monitorResponse{}, false, nil
+	}
+	if mm.Paused {
+		if err := mgr.start(mm); err != nil {
+			mm.err = err
+			return monitorResponse{}, true, err
+		}
+		mm.Paused = false
+		if err := mgr.save(); err != nil {
+			return monitorResponse{}, true, err
+		}
+	}
+	return mm.response(false), true, nil
+}
+
+// deadLetterLines はモニターで解析できなかった行を返します。file を指定した場合はそのファイルの行のみを返します。
+// reset が true の場合は返した後に保持している行と件数をリセットします。モニターが存在しない場合は false を返します。
+func (mgr *monitorManager) deadLetterLines(id, file string, reset bool) (deadLettersResponse, bool) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return deadLettersResponse{}, false
+	}
+	resp := deadLettersResponse{Lines: []models.RejectedLine{}}
+	if mm.deadLetters != nil {
+		resp.Total = mm.deadLetters.Total()
+		if lines := mm.deadLetters.Lines(file); lines != nil {
+			resp.Lines = lines
+		}
+		if reset {
+			mm.deadLetters.Reset()
+		}
+	}
+	return resp, true
+}
+
+// alertStates はモニターのアラートの状態を返します。モニターが存在しない場合は false を返します。
+func (mgr *monitorManager) alertStates(id string) (alertsResponse, bool) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return alertsResponse{}, false
+	}
+	return mm.alertsResponse(), true
+}
+
+// silence はモニターのアラートの通知を抑止する期間を追加します。モニターが存在しない場合は false を返します。
+// 抑止する期間は保存しないため、サーバーの再起動で失われます。
+func (mgr *monitorManager) silence(id string, req silenceRequest) (alertsResponse, bool, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return alertsResponse{}, false, nil
+	}
+	if mm.alerts == nil {
+		return alertsResponse{}, true, fmt.Errorf("モニターにアラートが設定されていません")
+	}
+
+	// 対象のルールの検証
+	if req.Rule != "" && !slices.ContainsFunc(mm.alerts.Alerts(), func(a models.Alert) bool { return a.Rule == req.Rule }) {
+		return alertsResponse{}, true, fmt.Errorf("アラートのルールがありません: %s", req.Rule)
+	}
+	silence, err := req.silence(time.Now())
+	if err != nil {
+		return alertsResponse{}, true, err
+	}
+	mm.alerts.Silence(silence)
+
+	return mm.alertsResponse(), true, nil
+}
+
+// remove はモニターの監視を停止し、定義と読み込み位置を削除します。
+func (mgr *monitorManager) remove(id string) (bool, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	mm, ok := mgr.monitors[id]
+	if !ok {
+		return false, nil
+	}
+	mm.stop()
+	delete(mgr.monitors, id)
+	if path, err := mgr.checkpointPath(id); err == nil {
+		os.Remove(path)
+	}
+
+	return true, mgr.save()
+}
+
+// close はすべてのモニターの監視を停止します。定義は変更しないため、次回の起動で監視を再開します。
+func (mgr *monitorManager) close() {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+
+	for _, mm := range mgr.monitors {
+		mm.stop()
+	}
+	if mgr.tempDir != "" {
+		os.RemoveAll(mgr.tempDir)
+		mgr.tempDir = ""
+	}
+}
+
+// start はモニターを作成して監視を開始します。一時停止前の読み込み位置があれば続きから監視します。
+func (mgr *monitorManager) start(mm *managedMonitor) error {
+	path, err := mgr.checkpointPath(mm.ID)
+	if err != nil {
+		return err
+	}
+	if mm.deadLetters == nil {
+		mm.deadLetters = deadletter.NewBuffer(deadLetterCapacity)
+	}
+	if mm.alerts == nil {
+		if mm.alerts, err = mm.Config.newAlertEngine(); err != nil {
+			return err
+		}
+	}
+	config := mm.Config
+	config.deadLetters = mm.deadLetters
+	m, err := config.newMonitor(path)
+	if err != nil {
+		return err
+	}
+
+	// 最初に集約したエントリから評価するため、監視の開始前に購読する
+	if mm.alerts != nil {
+		if err := mm.alerts.Start(m); err != nil {
+			return err
+		}
+	}
+	if err := m.Start(); err != nil {
+		if mm.alerts != nil {
+			mm.alerts.Stop()
+		}
+		return err
+	}
+	mm.monitor = m
+	mm.err = nil
+
+	return nil
+}
+
+// save はモニターの定義を状態ディレクトリに保存します。状態ディレクトリが未設定の場合は何もしません。
+func (mgr *monitorManager) save() error {
+	if mgr.stateDir == "" {
+		return nil
+	}
+
+	records := make([]monitorRecord, 0, len(mgr.monitors))
+	for _, mm := range mgr.monitors {
+		records = append(records, mm.monitorRecord)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("モニターの定義の変換に失敗しました: %v", err)
+	}
+	return checkpoint.WriteFile(filepath.Join(mgr.stateDir, monitorsFile), data)
+}
+
+// checkpointPath はモニターの読み込み位置を保存する状態ファイルのパスを返します。
+// 状態ディレクトリが未設定の場合も、一時停止から続きを監視できるように一時ディレクトリに保存します。
+func (mgr *monitorManager) checkpointPath(id string) (string, error) {
+	dir := mgr.stateDir
+	if dir == "" {
+		if mgr.tempDir == "" {
+			tempDir, err := os.MkdirTemp("", "logagg-monitors-*")
+			if err != nil {
+				return "", fmt.Errorf("読み込み位置の保存先を作成できませんでした: %v", err)
+			}
+			mgr.tempDir = tempDir
+		}
+		dir = mgr.tempDir
+	}
+	return filepath.Join(dir, "monitor-"+id+".json"), nil
+}
+
+// savedStats は状態ファイルに保存された統計情報を統合して返します。
+func (mgr *monitorManager) savedStats(id string) models.Stats {
+	path, err := mgr.checkpointPath(id)
+	if err != nil {
+		return models.Stats{}
+	}
+	state, err := checkpoint.Load(path)
+	if err != nil {
+		return models.Stats{}
+	}
+
+	var stats models.Stats
+	for _, file := range state.Files {
+		stats = aggregator.MergeStats(stats, file.Stats)
+	}
+	return stats
+}
+
+// stop はモニターの監視を停止し、その時点の統計情報を保持します。
+func (mm *managedMonitor) stop() {
+	if mm.monitor == nil {
+		return
+	}
+	if mm.alerts != nil {
+		mm.alerts.Stop()
+	}
+	mm.monitor.Stop()
+	mm.stats, _ = mm.monitor.GetStats()
+	mm.monitor = nil
+}
+
+// response はモニターの状態のレスポンスを作成します。detail が true の場合は複数ファイルのモニターのファイルごとの統計情報を含めます。
+func (mm *managedMonitor) response(detail bool) monitorResponse {
+	resp := monitorResponse{
+		ID:        mm.ID,
+		State:     "running",
+		CreatedAt: mm.CreatedAt,
+		Config:    mm.Config.masked(),
+		Stats:     mm.stats,
+	}
+	if mm.err != nil {
+		resp.Error = mm.err.Error()
+	}
+	if mm.monitor == nil {
+		resp.State = "paused"
+		return resp
+	}
+
+	resp.Stats, _ = mm.monitor.GetStats()
+	if mfm, ok := mm.monitor.(*monitor.MultiFileMonitor); ok && detail {
+		resp.Files = mfm.FileStats()
+	}
+	return resp
+}
+
+// alertsResponse はアラートの状態のレスポンスを作成します。アラートを指定しない場合は空の一覧を返します。
+func (mm *managedMonitor) alertsResponse() alertsResponse {
+	resp := alertsResponse{Alerts: []models.Alert{}, Silences: []alert.Silence{}}
+	if mm.alerts != nil {
+		resp.Alerts = mm.alerts.Alerts()
+		resp.Silences = mm.alerts.Silences()
+	}
+	return resp
+}
+
+// newMonitorID は新しいモニターの ID を作成します。
+func newMonitorID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("モニターの ID の作成に失敗しました: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// handleMonitors はモニター一覧のハンドラーです。
+// GET ではすべてのモニターの状態を返し、POST ではリクエストの設定でモニターを作成して監視を開始します。
+func (mgr *monitorManager) handleMonitors(w http.ResponseWriter, r *http.Request) {
+	// 戻り値の型は jsonResponse を使用します。
+	w.Header().Set("Content-Type", "application/json")
+
+	var resp jsonResponse
+	status := http.StatusOK
+
+	// メソッドごとの処理
+	switch r.Method {
+	case http.MethodGet:
+		resp.Data = mgr.list()
+	case http.MethodPost:
+		defer r.Body.Close()
+
+		// リクエストの解析
+		var req monitorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"リクエストの解析に失敗しました: %s"}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		// モニターの作成
+		created, err := mgr.create(req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"モニターの作成に失敗しました: %s"}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		resp.Data = created
+		status = http.StatusCreated
+	default:
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"許可されていないメソッドです: %s"}`, r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// レスポンスボディを JSON 形式で返します。
+	resp.Status = "ok"
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"レスポンスの生成に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// 処理結果の状態を返します。
+	w.WriteHeader(status)
+	w.Write(jsonResp)
+}
+
+// handleMonitor は1つのモニターのハンドラーです。
+// GET /monitors/{id} では詳細な状態を返し、DELETE では監視を停止して削除します。
+// POST /monitors/{id}/pause と POST /monitors/{id}/resume では監視を一時停止・再開します。
+// GET /monitors/{id}/deadletters では解析できなかった直近の行を返し (file クエリでファイルを指定できる)、
+// DELETE では返した後に保持している行を消去します (パーサーの設定を直した後などに使用します)。
+// GET /monitors/{id}/alerts ではアラートのルールごとの状態と通知を抑止する期間を返し、
+// POST /monitors/{id}/silences では通知を抑止する期間を追加します。
+func (mgr *monitorManager) handleMonitor(w http.ResponseWriter, r *http.Request) {
+	// 戻り値の型は jsonResponse を使用します。
+	w.Header().Set("Content-Type", "application/json")
+
+	var resp jsonResponse
+	id := r.PathValue("id")
+	found := true
+	var err error
+	errStatus := http.StatusInternalServerError
+
+	// 操作ごとの処理
+	switch action := r.PathValue("action"); {
+	case action == "" && r.Method == http.MethodGet:
+		resp.Data, found = mgr.get(id)
+	case action == "" && r.Method == http.MethodDelete:
+		found, err = mgr.remove(id)
+	case action == "pause" && r.Method == http.MethodPost:
+		resp.Data, found, err = mgr.pause(id)
+	case action == "resume" && r.Method == http.MethodPost:
+		resp.Data, found, err = mgr.resume(id)
+	case action == "deadletters" && (r.Method == http.MethodGet || r.Method == http.MethodDelete):
+		resp.Data, found = mgr.deadLetterLines(id, r.URL.Query().Get("file"), r.Method == http.MethodDelete)
+	case action == "alerts" && r.Method == http.MethodGet:
+		resp.Data, found = mgr.alertStates(id)
+	case action == "silences" && r.Method == http.MethodPost:
+		defer r.Body.Close()
+
+		// リクエストの解析
+		var req silenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf(`{"status":"error","data":"リクエストの解析に失敗しました: %s"}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		resp.Data, found, err = mgr.silence(id, req)
+		errStatus = http.StatusBadRequest
+	case !slices.Contains(monitorActions, action):
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"不明な操作です: %s"}`, action), http.StatusNotFound)
+		return
+	default:
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"許可されていないメソッドです: %s"}`, r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"モニターが見つかりません: %s"}`, id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"%s"}`, err.Error()), errStatus)
+		return
+	}
+
+	// レスポンスボディを JSON 形式で返します。
+	resp.Status = "ok"
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"status":"error","data":"レスポンスの生成に失敗しました: %s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// 処理結果の状態を返します。
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
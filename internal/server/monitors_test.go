@@ -0,0 +1,389 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/alert"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/pkg/models"
+)
+
+// monitorTestLog は監視のテストで使用するログです。
+const monitorTestLog = `2024-10-01 12:00:00 [INFO] アプリケーションが起動しました
+2024-10-01 12:05:00 [ERROR] データベース接続に失敗しました
+`
+
+// newMonitorMux はモニターの管理のハンドラーを登録した ServeMux を作成します。
+func newMonitorMux(mgr *monitorManager) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/monitors", mgr.handleMonitors)
+	mux.HandleFunc("/monitors/{id}", mgr.handleMonitor)
+	mux.HandleFunc("/monitors/{id}/{action}", mgr.handleMonitor)
+	return mux
+}
+
+// doMonitorRequest はリクエストを送信し、ステータスコードとレスポンスの data を返します。
+func doMonitorRequest(t *testing.T, mux *http.ServeMux, method, target string, body any, data any) int {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatalf("リクエストの作成に失敗しました: %s", err.Error())
+		}
+	}
+	testReq := httptest.NewRequest(method, target, &reqBody)
+	testRec := httptest.NewRecorder()
+	mux.ServeHTTP(testRec, testReq)
+
+	if data != nil && testRec.Code < 300 {
+		resp := jsonResponse{Data: data}
+		if err := json.Unmarshal(testRec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("レスポンスボディの解析に失敗しました: %s: %s", err.Error(), testRec.Body.String())
+		}
+	}
+	return testRec.Code
+}
+
+// waitMonitorCount はモニターの総ログ数が期待値になるまで待ちます。
+func waitMonitorCount(t *testing.T, mux *http.ServeMux, id string, expected int) monitorResponse {
+	t.Helper()
+
+	var resp monitorResponse
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if code := doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+id, nil, &resp); code != http.StatusOK {
+			t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+		}
+		if resp.Stats.TotalCount == expected {
+			return resp
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("総ログ数が期待値になりません: 期待値 %d, 実際 %d", expected, resp.Stats.TotalCount)
+	return resp
+}
+
+// appendLog はログファイルに行を追記します。
+func appendLog(t *testing.T, path, line string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("ログファイルを開けませんでした: %s", err.Error())
+	}
+	defer file.Close()
+	if _, err := file.WriteString(line); err != nil {
+		t.Fatalf("ログファイルへの追記に失敗しました: %s", err.Error())
+	}
+}
+
+// TestHandleMonitors_Lifecycle はモニターの作成、一覧、一時停止、再開、削除のテストを行います。
+func TestHandleMonitors_Lifecycle(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFilePath, []byte(monitorTestLog), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	mgr := newMonitorManager()
+	defer mgr.close()
+	mux := newMonitorMux(mgr)
+
+	// モニターの作成
+	var created monitorResponse
+	req := monitorRequest{Path: logFilePath, Interval: "10ms", pipelineRequest: pipelineRequest{Window: &windowRequest{Length: "1m"}}}
+	code := doMonitorRequest(t, mux, http.MethodPost, "/monitors", req, &created)
+	if code != http.StatusCreated {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusCreated, code)
+	}
+	if created.ID == "" || created.State != "running" {
+		t.Fatalf("作成したモニターの状態が期待値と異なります: %+v", created)
+	}
+
+	// 監視のウィンドウは実際の時計で数える
+	if resp := waitMonitorCount(t, mux, created.ID, 2); resp.Stats.Window == nil || resp.Stats.Window.TotalCount != 2 {
+		t.Errorf("ウィンドウの統計情報が期待値と異なります: %+v", resp.Stats.Window)
+	}
+
+	// 一覧
+	var list []monitorResponse
+	doMonitorRequest(t, mux, http.MethodGet, "/monitors", nil, &list)
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Fatalf("モニター一覧が期待値と異なります: %+v", list)
+	}
+
+	// 一時停止中に追記した行は再開後に続きから読み込む
+	var paused monitorResponse
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+created.ID+"/pause", nil, &paused); code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+	}
+	if paused.State != "paused" || paused.Stats.TotalCount != 2 {
+		t.Errorf("一時停止したモニターの状態が期待値と異なります: %+v", paused)
+	}
+	appendLog(t, logFilePath, "2024-10-01 12:10:00 [WARN] メモリ使用量が高くなっています\n")
+
+	var resumed monitorResponse
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+created.ID+"/resume", nil, &resumed); code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+	}
+	if resumed.State != "running" {
+		t.Errorf("再開したモニターの状態が期待値と異なります: %+v", resumed)
+	}
+	resp := waitMonitorCount(t, mux, created.ID, 3)
+	if resp.Stats.WarnCount != 1 {
+		t.Errorf("WARN の数が期待値と異なります: %+v", resp.Stats)
+	}
+
+	// 削除
+	if code := doMonitorRequest(t, mux, http.MethodDelete, "/monitors/"+created.ID, nil, nil); code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+	}
+	if code := doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID, nil, nil); code != http.StatusNotFound {
+		t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusNotFound, code)
+	}
+}
+
+// TestHandleMonitors_Invalid は不正なリクエストのテストを行います。
+func TestHandleMonitors_Invalid(t *testing.T) {
+	mgr := newMonitorManager()
+	defer mgr.close()
+	mux := newMonitorMux(mgr)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     any
+		expected int
+	}{
+		{"path と patterns の両方", http.MethodPost, "/monitors", monitorRequest{Path: "a.log", Patterns: []string{"*.log"}}, http.StatusBadRequest},
+		{"不正な監視間隔", http.MethodPost, "/monitors", monitorRequest{Path: "a.log", Interval: "-1s"}, http.StatusBadRequest},
+		{"バケット数が多すぎるウィンドウ", http.MethodPost, "/monitors", monitorRequest{Path: "a.log", pipelineRequest: pipelineRequest{Window: &windowRequest{Length: "2540400h", Resolution: "1ns"}}}, http.StatusBadRequest},
+		{"存在しないモニター", http.MethodGet, "/monitors/unknown", nil, http.StatusNotFound},
+		{"存在しないモニターの一時停止", http.MethodPost, "/monitors/unknown/pause", nil, http.StatusNotFound},
+		{"不明な操作", http.MethodPost, "/monitors/unknown/restart", nil, http.StatusNotFound},
+		{"許可されていないメソッド", http.MethodPut, "/monitors", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := doMonitorRequest(t, mux, tt.method, tt.target, tt.body, nil); code != tt.expected {
+				t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+// TestMonitorManager_Persistence は再起動後にモニターの監視を続きから再開するテストを行います。
+func TestMonitorManager_Persistence(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateDir := filepath.Join(tmpDir, "state")
+	logFilePath := filepath.Join(tmpDir, "app.log")
+	if err := os.WriteFile(logFilePath, []byte(monitorTestLog), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	// 1回目の起動
+	mgr := newMonitorManager()
+	if err := mgr.load(stateDir); err != nil {
+		t.Fatalf("状態ディレクトリの読み込みに失敗しました: %s", err.Error())
+	}
+	mux := newMonitorMux(mgr)
+	var running, paused monitorResponse
+	doMonitorRequest(t, mux, http.MethodPost, "/monitors", monitorRequest{Path: logFilePath, Interval: "10ms"}, &running)
+	doMonitorRequest(t, mux, http.MethodPost, "/monitors", monitorRequest{Path: logFilePath, Interval: "10ms"}, &paused)
+	waitMonitorCount(t, mux, running.ID, 2)
+	waitMonitorCount(t, mux, paused.ID, 2)
+	doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+paused.ID+"/pause", nil, nil)
+	mgr.close()
+
+	// 停止中に追記した行だけを再起動後に読み込む
+	appendLog(t, logFilePath, "2024-10-01 12:10:00 [WARN] メモリ使用量が高くなっています\n")
+
+	// 2回目の起動
+	mgr = newMonitorManager()
+	defer mgr.close()
+	if err := mgr.load(stateDir); err != nil {
+		t.Fatalf("状態ディレクトリの読み込みに失敗しました: %s", err.Error())
+	}
+	mux = newMonitorMux(mgr)
+
+	var list []monitorResponse
+	doMonitorRequest(t, mux, http.MethodGet, "/monitors", nil, &list)
+	if len(list) != 2 {
+		t.Fatalf("モニター一覧が期待値と異なります: %+v", list)
+	}
+	waitMonitorCount(t, mux, running.ID, 3)
+
+	resp := waitMonitorCount(t, mux, paused.ID, 2)
+	if resp.State != "paused" {
+		t.Errorf("一時停止したモニターの状態が引き継がれていません: %+v", resp)
+	}
+}
+
+// TestHandleMonitors_Health は監視対象のファイルがない場合にモニターの状態で確認できるテストを行います。
+func TestHandleMonitors_Health(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "missing.log")
+
+	mgr := newMonitorManager()
+	defer mgr.close()
+	mux := newMonitorMux(mgr)
+
+	var created monitorResponse
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors", monitorRequest{Path: logFilePath, Interval: "10ms"}, &created); code != http.StatusCreated {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusCreated, code)
+	}
+
+	var resp monitorResponse
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID, nil, &resp)
+		if health := resp.Stats.Health; health != nil && health.State == models.HealthWaiting && health.ConsecutiveFailures > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("監視の状態が期待値になりません: %+v", resp.Stats.Health)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestHandleMonitors_DeadLetters はモニターで解析できなかった行を機密情報を置換して参照し、消去できるテストを行います。
+func TestHandleMonitors_DeadLetters(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFilePath, []byte("壊れた行 alice@example.com\n"+monitorTestLog), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	mgr := newMonitorManager()
+	defer mgr.close()
+	mux := newMonitorMux(mgr)
+
+	var created monitorResponse
+	req := monitorRequest{Path: logFilePath, Interval: "10ms", pipelineRequest: pipelineRequest{Redact: &pipeline.RedactConfig{}}}
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors", req, &created); code != http.StatusCreated {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusCreated, code)
+	}
+	if resp := waitMonitorCount(t, mux, created.ID, 2); resp.Stats.Rejected != 1 {
+		t.Errorf("解析できなかった行数が期待値と異なります: %d", resp.Stats.Rejected)
+	}
+
+	// 解析できなかった行は置換して保持する
+	var dead deadLettersResponse
+	if code := doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID+"/deadletters", nil, &dead); code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+	}
+	if dead.Total != 1 || len(dead.Lines) != 1 || dead.Lines[0].Line != 1 || dead.Lines[0].Raw != "壊れた行 [REDACTED:email]" {
+		t.Fatalf("解析できなかった行が期待値と異なります: %+v", dead)
+	}
+
+	// ファイルで絞り込む
+	if doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID+"/deadletters?file=other.log", nil, &dead); len(dead.Lines) != 0 {
+		t.Errorf("他のファイルの行が返されました: %+v", dead.Lines)
+	}
+
+	// 消去すると空になる
+	if code := doMonitorRequest(t, mux, http.MethodDelete, "/monitors/"+created.ID+"/deadletters", nil, nil); code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+	}
+	doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID+"/deadletters", nil, &dead)
+	if dead.Total != 0 || len(dead.Lines) != 0 {
+		t.Errorf("消去後の解析できなかった行が期待値と異なります: %+v", dead)
+	}
+
+	// 存在しないモニター
+	if code := doMonitorRequest(t, mux, http.MethodGet, "/monitors/unknown/deadletters", nil, nil); code != http.StatusNotFound {
+		t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusNotFound, code)
+	}
+}
+
+// TestHandleMonitors_Alerts はモニターに設定したアラートが通知され、状態の参照と通知の抑止ができることをテストします。
+func TestHandleMonitors_Alerts(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFilePath, []byte(monitorTestLog), 0644); err != nil {
+		t.Fatalf("一時的なログファイルの作成に失敗しました: %s", err.Error())
+	}
+
+	// 通知を受け取る Webhook
+	notified := make(chan models.Alert, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a models.Alert
+		json.NewDecoder(r.Body).Decode(&a)
+		notified <- a
+	}))
+	defer webhook.Close()
+
+	mgr := newMonitorManager()
+	defer mgr.close()
+	mux := newMonitorMux(mgr)
+
+	// 不正なアラートの設定ではモニターを作成しない
+	invalid := monitorRequest{Path: logFilePath, Alerts: &alert.RuleConfig{Rules: []alert.Rule{{Rule: pipeline.Rule{Name: "db"}, Window: "x"}}}}
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors", invalid, nil); code != http.StatusBadRequest {
+		t.Errorf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusBadRequest, code)
+	}
+	if len(mgr.list()) != 0 {
+		t.Fatal("不正なアラートの設定でモニターが作成されました")
+	}
+
+	// モニターの作成
+	req := monitorRequest{
+		Path:     logFilePath,
+		Interval: "10ms",
+		Alerts: &alert.RuleConfig{
+			Rules: []alert.Rule{{Rule: pipeline.Rule{Name: "db", Level: "ERROR", Message: "データベース"}}},
+			Channels: map[string]alert.ChannelConfig{
+				"hook": {Webhook: &alert.WebhookConfig{URL: webhook.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}},
+			},
+		},
+	}
+	var created monitorResponse
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors", req, &created); code != http.StatusCreated {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusCreated, code)
+	}
+	if got := created.Config.Alerts.Channels["hook"].Webhook.Headers["Authorization"]; got != maskedSecret {
+		t.Errorf("レスポンスの Webhook のヘッダーが伏せられていません: %s", got)
+	}
+
+	// 既存のログでルールが発火して通知される
+	select {
+	case a := <-notified:
+		if a.Rule != "db" || a.State != models.AlertFiring {
+			t.Errorf("通知されたアラートが期待値と異なります: %+v", a)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("アラートが通知されませんでした")
+	}
+	var alerts alertsResponse
+	doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID+"/alerts", nil, &alerts)
+	if len(alerts.Alerts) != 1 || alerts.Alerts[0].State != models.AlertFiring {
+		t.Errorf("アラートの状態が期待値と異なります: %+v", alerts)
+	}
+
+	// 通知の抑止
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+created.ID+"/silences", silenceRequest{Rule: "unknown", Duration: "1h"}, nil); code != http.StatusBadRequest {
+		t.Errorf("存在しないルールの抑止: 期待されるステータスコード %d, 実際のステータスコード %d", http.StatusBadRequest, code)
+	}
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+created.ID+"/silences", silenceRequest{Rule: "db"}, nil); code != http.StatusBadRequest {
+		t.Errorf("期間のない抑止: 期待されるステータスコード %d, 実際のステータスコード %d", http.StatusBadRequest, code)
+	}
+	if code := doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+created.ID+"/silences", silenceRequest{Rule: "db", Duration: "1h"}, &alerts); code != http.StatusOK {
+		t.Fatalf("期待されるステータスコード %d, 実際のステータスコード %d", http.StatusOK, code)
+	}
+	if len(alerts.Silences) != 1 || alerts.Silences[0].Rule != "db" {
+		t.Errorf("通知を抑止する期間が期待値と異なります: %+v", alerts.Silences)
+	}
+
+	// 一時停止しても抑止する期間を保持する
+	doMonitorRequest(t, mux, http.MethodPost, "/monitors/"+created.ID+"/pause", nil, nil)
+	doMonitorRequest(t, mux, http.MethodGet, "/monitors/"+created.ID+"/alerts", nil, &alerts)
+	if len(alerts.Silences) != 1 {
+		t.Errorf("一時停止で通知を抑止する期間が失われました: %+v", alerts.Silences)
+	}
+}
@@ -0,0 +1,255 @@
+package server
+
+/*
+ * fmt パッケージはフォーマットされたI/Oを提供します
+ * time パッケージは時間の操作を提供します
+ */
+import (
+	"fmt"
+	"time"
+
+	"github.com/Yamituki/go-review-logagg/internal/aggregator"
+	"github.com/Yamituki/go-review-logagg/internal/deadletter"
+	"github.com/Yamituki/go-review-logagg/internal/parser"
+	"github.com/Yamituki/go-review-logagg/internal/pipeline"
+	"github.com/Yamituki/go-review-logagg/internal/processor"
+)
+
+// pipelineRequest はリクエストで指定できる処理段と集約の設定を表します。
+type pipelineRequest struct {
+	// タイムゾーンを含まないタイムスタンプを解釈するタイムゾーン (例: "Asia/Tokyo", "+09:00"、既定は UTC)
+	Timezone string `json:"timezone,omitempty"`
+	// ファイルごとのタイムゾーン (キーはファイルパス)
+	FileTimezones map[string]string `json:"file_timezones,omitempty"`
+	// ファイルごとの発生源 (キーはファイルパス、既定はファイル名から拡張子を除いた名前)
+	FileSources map[string]string `json:"file_sources,omitempty"`
+	// 既定の書式で解析できない場合に試すタイムスタンプの書式一覧 (Go の時刻の書式)
+	Layouts []string `json:"layouts,omitempty"`
+	// 解析できなかった行を件数 (rejected) に数えて処理を続けるかどうか
+	// (/analyze では機密情報を置換した行の内容をレスポンスの dead_letters に含める。
+	// モニターは指定によらず処理を続け、直近の行を /monitors/{id}/deadletters で返す)
+	DeadLetter bool `json:"dead_letter,omitempty"`
+	// タグ付けと件数の集計を行うルール一覧
+	Rules []pipeline.Rule `json:"rules,omitempty"`
+	// ルールの設定ファイルのパス (リクエストごとに読み込むため、編集は次のリクエストから反映される)
+	RulesFile string `json:"rules_file,omitempty"`
+	// 絞り込みの条件
+	Filter *pipeline.FilterOptions `json:"filter,omitempty"`
+	// 機密情報の置換の設定
+	Redact *pipeline.RedactConfig `json:"redact,omitempty"`
+	// サンプリングの設定
+	Sample *pipeline.SampleConfig `json:"sample,omitempty"`
+	// 重複抑制の設定
+	Dedup *dedupRequest `json:"dedup,omitempty"`
+	// 異なり数を推定するフィールド一覧
+	Distinct []string `json:"distinct,omitempty"`
+	// 数値統計を計算するフィールド一覧
+	Numeric []numericRequest `json:"numeric,omitempty"`
+	// スライディングウィンドウの設定
+	Window *windowRequest `json:"window,omitempty"`
+
+	// 解析できなかった行の保持先 (レスポンスに含めるリクエストとモニターのみ、nil の場合は件数のみを数える)
+	deadLetters *deadletter.Buffer
+}
+
+// windowRequest はスライディングウィンドウの設定を表します。
+type windowRequest struct {
+	// ウィンドウの長さ (例: "5m")
+	Length string `json:"length"`
+	// バケットの時間分解能 (例: "10s"、既定は1秒)
+	Resolution string `json:"resolution,omitempty"`
+	// 現在時刻の決め方 ("event" はエントリの最新のタイムスタンプ、"wall" は実際の時計。既定は解析では "event"、監視では "wall")
+	Clock string `json:"clock,omitempty"`
+}
+
+// dedupRequest は重複抑制の設定を表します。
+type dedupRequest struct {
+	// 重複とみなす基準 ("message" または "template")
+	By string `json:"by,omitempty"`
+	// 直前の重複からまとめる時間 (例: "10s")
+	Window string `json:"window,omitempty"`
+	// 連続する重複のみをまとめるかどうか
+	Consecutive bool `json:"consecutive,omitempty"`
+	// まとめている途中で保持するグループ数の上限 (省略時は 10000)
+	MaxGroups int `json:"max_groups,omitempty"`
+}
+
+// numericRequest は数値統計の計算方法を表します。
+type numericRequest struct {
+	// 対象のフィールド名
+	Field string `json:"field"`
+	// グループ化に使用するフィールド名
+	GroupBy string `json:"group_by,omitempty"`
+	// 時間バケットの長さ (例: "1m")
+	Bucket string `json:"bucket,omitempty"`
+}
+
+// pipelineSetter は処理段と集約の設定を受け付けるプロセッサです。
+type pipelineSetter interface {
+	SetParserConfig(config parser.StandardParserConfig)
+	SetFileLocation(filePath string, location *time.Location)
+	SetFileSource(filePath string, source string)
+	SetDeadLetter(sink deadletter.Sink)
+	AddFilter(config pipeline.FilterConfig) error
+	AddRedaction(config pipeline.RedactConfig) error
+	AddSampling(config pipeline.SampleConfig) error
+	SetRules(rules []pipeline.Rule) error
+	AddStage(factory func() pipeline.Stage)
+	SetDistinctFields(fields ...string)
+	AddNumericField(field string, options aggregator.NumericOptions)
+	SetWindow(length, resolution time.Duration, source aggregator.TimeSource)
+}
+
+// newProcessor はリクエストの設定を反映した LogProcessor を作成します。設定が不正な場合はエラーを返します。
+func (pr pipelineRequest) newProcessor() (*processor.LogProcessor, error) {
+	ps := processor.NewLogProcessor()
+	if err := pr.configure(ps); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// newConcurrentProcessor はリクエストの設定を反映した ConcurrentProcessor を作成します。設定が不正な場合はエラーを返します。
+func (pr pipelineRequest) newConcurrentProcessor(workers int) (*processor.ConcurrentProcessor, error) {
+	cp := processor.NewConcurrentProcessor(workers)
+	if err := pr.configure(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// configure はリクエストの設定をプロセッサに反映します。
+func (pr pipelineRequest) configure(ps pipelineSetter) error {
+	// パーサーの設定
+	parserConfig := parser.StandardParserConfig{Layouts: pr.Layouts}
+	if pr.Timezone != "" {
+		location, err := parser.ParseLocation(pr.Timezone)
+		if err != nil {
+			return err
+		}
+		parserConfig.Location = location
+	}
+	ps.SetParserConfig(parserConfig)
+	for path, timezone := range pr.FileTimezones {
+		location, err := parser.ParseLocation(timezone)
+		if err != nil {
+			return err
+		}
+		ps.SetFileLocation(path, location)
+	}
+	for path, source := range pr.FileSources {
+		ps.SetFileSource(path, source)
+	}
+
+	// デッドレターの設定 (保持先がある場合は常に書き込む)
+	switch {
+	case pr.deadLetters != nil:
+		ps.SetDeadLetter(pr.deadLetters)
+	case pr.DeadLetter:
+		ps.SetDeadLetter(deadletter.Discard)
+	}
+
+	// ルールの設定 (設定ファイルのルールの後にリクエストのルールを追加)
+	rules := pr.Rules
+	if pr.RulesFile != "" {
+		fileRules, err := pipeline.LoadRuleFile(pr.RulesFile)
+		if err != nil {
+			return err
+		}
+		rules = append(fileRules, rules...)
+	}
+	if err := ps.SetRules(rules); err != nil {
+		return err
+	}
+
+	// 絞り込みの設定 (重複抑制より先に適用)
+	if pr.Filter != nil {
+		config, err := pr.Filter.Config()
+		if err != nil {
+			return err
+		}
+		if err := ps.AddFilter(config); err != nil {
+			return err
+		}
+	}
+
+	// 機密情報の置換の設定 (集約やサンプルの保持より先に適用)
+	if pr.Redact != nil {
+		if err := ps.AddRedaction(*pr.Redact); err != nil {
+			return err
+		}
+	}
+
+	// サンプリングの設定
+	if pr.Sample != nil {
+		if err := ps.AddSampling(*pr.Sample); err != nil {
+			return err
+		}
+	}
+
+	// 重複抑制の設定
+	if pr.Dedup != nil {
+		config := pipeline.DedupConfig{Consecutive: pr.Dedup.Consecutive, MaxGroups: pr.Dedup.MaxGroups}
+		switch pr.Dedup.By {
+		case "", "message":
+			config.By = pipeline.DedupByMessage
+		case "template":
+			config.By = pipeline.DedupByTemplate
+		default:
+			return fmt.Errorf("重複の基準の指定が不正です: %s", pr.Dedup.By)
+		}
+		if pr.Dedup.Window != "" {
+			window, err := time.ParseDuration(pr.Dedup.Window)
+			if err != nil {
+				return fmt.Errorf("重複をまとめる時間の指定が不正です: %s", pr.Dedup.Window)
+			}
+			config.Window = window
+		}
+		ps.AddStage(func() pipeline.Stage { return pipeline.NewDedupStage(config) })
+	}
+
+	// 異なり数の設定
+	ps.SetDistinctFields(pr.Distinct...)
+
+	// 数値統計の設定
+	for _, numeric := range pr.Numeric {
+		options := aggregator.NumericOptions{GroupBy: numeric.GroupBy}
+		if numeric.Bucket != "" {
+			bucket, err := time.ParseDuration(numeric.Bucket)
+			if err != nil {
+				return fmt.Errorf("時間バケットの指定が不正です: %s", numeric.Bucket)
+			}
+			options.BucketSize = bucket
+		}
+		ps.AddNumericField(numeric.Field, options)
+	}
+
+	// スライディングウィンドウの設定
+	if pr.Window != nil {
+		length, err := time.ParseDuration(pr.Window.Length)
+		if err != nil || length <= 0 {
+			return fmt.Errorf("ウィンドウの長さの指定が不正です: %s", pr.Window.Length)
+		}
+		resolution := time.Second
+		if pr.Window.Resolution != "" {
+			if resolution, err = time.ParseDuration(pr.Window.Resolution); err != nil || resolution <= 0 {
+				return fmt.Errorf("ウィンドウの時間分解能の指定が不正です: %s", pr.Window.Resolution)
+			}
+		}
+		if err := aggregator.CheckWindow(length, resolution); err != nil {
+			return err
+		}
+		var source aggregator.TimeSource
+		switch pr.Window.Clock {
+		case "", "event":
+			source = aggregator.EventTime
+		case "wall":
+			source = aggregator.WallClock
+		default:
+			return fmt.Errorf("ウィンドウの現在時刻の指定が不正です: %s", pr.Window.Clock)
+		}
+		ps.SetWindow(length, resolution, source)
+	}
+
+	return nil
+}
@@ -6,10 +6,15 @@ import (
 	"github.com/Yamituki/go-review-logagg/internal/processor"
 )
 
+// deadLetterCapacity は1つのリクエストのレスポンス、または1つのモニターで保持する解析できなかった行の最大数です。
+const deadLetterCapacity = 1000
+
 // Server はログ集約サーバーを表します。
 type Server struct {
 	port      string
 	processor *processor.LogProcessor
+	// API で作成したモニター
+	monitors *monitorManager
 }
 
 // NewServer は新しい Server インスタンスを作成します。
@@ -17,9 +22,21 @@ func NewServer(port string) *Server {
 	return &Server{
 		port:      port,
 		processor: processor.NewLogProcessor(),
+		monitors:  newMonitorManager(),
 	}
 }
 
+// SetStateDir は API で作成したモニターの定義と読み込み位置を保存するディレクトリを設定し、
+// 保存されているモニターの監視を再開します。Start の前に呼び出してください。
+func (s *Server) SetStateDir(dir string) error {
+	return s.monitors.load(dir)
+}
+
+// Close はすべてのモニターの監視を停止し、読み込み位置を保存します。
+func (s *Server) Close() {
+	s.monitors.close()
+}
+
 // Start はサーバーを起動します。
 func (s *Server) Start() error {
 	return http.ListenAndServe(s.port, nil)
@@ -32,4 +49,18 @@ func (s *Server) SetupRoutes() {
 
 	// ログ集約のエンドポイント
 	http.HandleFunc("/analyze", handleAnalyze)
+
+	// ログテンプレート一覧のエンドポイント
+	http.HandleFunc("/templates", handleTemplates)
+
+	// 異常検知のエンドポイント
+	http.HandleFunc("/anomalies", handleAnomalies)
+
+	// リクエスト相関のエンドポイント
+	http.HandleFunc("/traces", handleTraces)
+
+	// モニターの管理のエンドポイント
+	http.HandleFunc("/monitors", s.monitors.handleMonitors)
+	http.HandleFunc("/monitors/{id}", s.monitors.handleMonitor)
+	http.HandleFunc("/monitors/{id}/{action}", s.monitors.handleMonitor)
 }
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// AlertState はアラートの状態です。
+type AlertState string
+
+const (
+	// AlertInactive は条件を満たしていない状態を表します。
+	AlertInactive AlertState = "inactive"
+	// AlertPending は条件を満たしているが、継続時間に達していない状態を表します。
+	AlertPending AlertState = "pending"
+	// AlertFiring は条件を継続時間以上満たし、発火している状態を表します。
+	AlertFiring AlertState = "firing"
+	// AlertResolved は発火していたアラートの条件を満たさなくなったことを表します (通知でのみ使用)。
+	AlertResolved AlertState = "resolved"
+)
+
+// Alert はアラートのルールの評価結果を表す構造体です。
+type Alert struct {
+	// ルール名
+	Rule string `json:"rule"`
+	// 状態
+	State AlertState `json:"state"`
+	// 評価結果の説明
+	Summary string `json:"summary"`
+	// 評価した時間内に一致したログ数
+	Count int `json:"count"`
+	// 最後に一致したログのファイルパス
+	File string `json:"file,omitempty"`
+	// 最後に一致したログのメッセージ
+	Sample string `json:"sample,omitempty"`
+	// 条件を満たし始めた時刻
+	ActiveSince time.Time `json:"active_since,omitzero"`
+	// 発火した時刻
+	FiredAt time.Time `json:"fired_at,omitzero"`
+	// 解決した時刻
+	ResolvedAt time.Time `json:"resolved_at,omitzero"`
+	// 通知を抑止しているかどうか
+	Silenced bool `json:"silenced,omitempty"`
+	// 最後の通知の失敗
+	Error string `json:"error,omitempty"`
+	// 監視中に受信が遅れて評価の対象にできなかったイベント数 (0 より大きい場合は Count が実際より少ない可能性がある)
+	Dropped uint64 `json:"dropped,omitempty"`
+}
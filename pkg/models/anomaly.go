@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Anomaly は通常と異なる件数が観測された時間バケットを表す構造体です。
+type Anomaly struct {
+	// 時間バケットの開始時刻
+	BucketStart time.Time `json:"bucket_start"`
+	// 時間バケットの長さ
+	BucketSize time.Duration `json:"bucket_size"`
+	// 系列の名前 (例: "level:ERROR", "template:3")
+	Series string `json:"series"`
+	// 対象のレベル (レベルの系列の場合)
+	Level string `json:"level,omitempty"`
+	// 対象のテンプレートID (テンプレートの系列の場合)
+	TemplateID int `json:"template_id,omitempty"`
+	// 対象のテンプレート文字列 (テンプレートの系列の場合)
+	Template string `json:"template,omitempty"`
+	// 観測された件数
+	Count int `json:"count"`
+	// 過去の傾向から期待される件数
+	Expected float64 `json:"expected"`
+	// 標準偏差で正規化した乖離 (z スコア)
+	ZScore float64 `json:"z_score"`
+	// 乖離の方向 ("spike" または "drop")
+	Direction string `json:"direction"`
+}
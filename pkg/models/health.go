@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// HealthState は監視の状態です。
+type HealthState string
+
+const (
+	// HealthOK は監視対象のファイルを正常に読み込めていることを表します。
+	HealthOK HealthState = "ok"
+	// HealthWaiting は監視対象のファイルが存在せず、作成されるのを待っていることを表します。
+	HealthWaiting HealthState = "waiting_for_file"
+	// HealthFailing は監視対象のファイルの読み込みに失敗し続けていることを表します。
+	HealthFailing HealthState = "failing"
+)
+
+// MonitorHealth は監視の状態を表す構造体です。
+type MonitorHealth struct {
+	// 状態
+	State HealthState `json:"state"`
+	// 最後のエラー (回復後も保持する)
+	LastError string `json:"last_error,omitempty"`
+	// 最後のエラーの時刻
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+	// 連続して失敗した回数 (成功すると0に戻る)
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// 最後に正常に読み込んだ時刻
+	LastReadAt time.Time `json:"last_read_at,omitzero"`
+	// 失敗した場合に次に再確認する時刻
+	NextRetryAt time.Time `json:"next_retry_at,omitzero"`
+}
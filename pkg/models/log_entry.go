@@ -15,4 +15,69 @@ type LogEntry struct {
 	Message string `json:"message"`
 	// Logの発生源 (例: サービス名やホスト名)
 	Source string `json:"source"`
+	// メッセージ中の key=value 形式のフィールド
+	Fields map[string]string `json:"fields,omitempty"`
+	// 一致したルールのタグ
+	Tags []string `json:"tags,omitempty"`
+	// 重複をまとめた場合の繰り返し回数 (0 または 1 は単独のエントリ)
+	Repeat int `json:"repeat,omitempty"`
+	// 重複をまとめた場合の最後のタイムスタンプ
+	EndTimestamp time.Time `json:"end_timestamp,omitzero"`
+}
+
+// Count はエントリが表すログの件数を返します。重複をまとめたエントリは繰り返し回数を返します。
+func (e LogEntry) Count() int {
+	if e.Repeat > 1 {
+		return e.Repeat
+	}
+	return 1
+}
+
+// LastTimestamp はエントリが表すログの最後のタイムスタンプを返します。
+func (e LogEntry) LastTimestamp() time.Time {
+	if e.EndTimestamp.After(e.Timestamp) {
+		return e.EndTimestamp
+	}
+	return e.Timestamp
+}
+
+// Field は指定した名前のフィールドの値を返します。
+// level, message, source はそれぞれ構造体のフィールドを、それ以外は Fields を参照します。
+func (e LogEntry) Field(name string) (string, bool) {
+	switch name {
+	case "level":
+		return e.Level, e.Level != ""
+	case "message":
+		return e.Message, e.Message != ""
+	case "source":
+		return e.Source, e.Source != ""
+	}
+
+	value, ok := e.Fields[name]
+	return value, ok
+}
+
+// HasTag はエントリが指定したタグを持つかどうかを返します。
+func (e LogEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// LevelSeverity はレベルの重大度 (ERROR > WARN > INFO) を返します。未知のレベルは INFO より低く、空のレベルは最も低い重大度とみなします。
+func LevelSeverity(level string) int {
+	switch level {
+	case "ERROR":
+		return 3
+	case "WARN":
+		return 2
+	case "INFO":
+		return 1
+	case "":
+		return -1
+	}
+	return 0
 }
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// NumericSummary は数値フィールドの統計量を表す構造体です。
+type NumericSummary struct {
+	// 値の件数
+	Count uint64 `json:"count"`
+	// 合計
+	Sum float64 `json:"sum"`
+	// 最小値
+	Min float64 `json:"min"`
+	// 最大値
+	Max float64 `json:"max"`
+	// 平均値
+	Mean float64 `json:"mean"`
+	// 50パーセンタイル
+	P50 float64 `json:"p50"`
+	// 90パーセンタイル
+	P90 float64 `json:"p90"`
+	// 95パーセンタイル
+	P95 float64 `json:"p95"`
+	// 99パーセンタイル
+	P99 float64 `json:"p99"`
+	// 統合用の分位点スケッチの状態
+	Sketch *SketchState `json:"-"`
+}
+
+// SketchState は統合用の分位点スケッチ (DDSketch) の内部状態です。
+type SketchState struct {
+	// 相対精度
+	RelativeAccuracy float64
+	// 0 の件数
+	ZeroCount uint64
+	// 正の値のビン (キーはビン番号)
+	Positive map[int]uint64
+	// 負の値のビン (キーは絶対値のビン番号)
+	Negative map[int]uint64
+}
+
+// NumericBucket は時間バケットごとの数値フィールドの統計量を表す構造体です。
+type NumericBucket struct {
+	// バケットの開始時刻
+	Start time.Time `json:"start"`
+	// バケット内の統計量
+	Summary NumericSummary `json:"summary"`
+}
+
+// NumericReport は数値フィールドの全体、グループ別、時間バケット別の統計量を表す構造体です。
+type NumericReport struct {
+	// 全体の統計量
+	Overall NumericSummary `json:"overall"`
+	// グループ化に使用したフィールド名
+	GroupBy string `json:"group_by,omitempty"`
+	// グループ別の統計量
+	Groups map[string]NumericSummary `json:"groups,omitempty"`
+	// 時間バケットの長さ
+	BucketSize time.Duration `json:"bucket_size,omitempty"`
+	// 時間バケット別の統計量 (開始時刻順)
+	Buckets []NumericBucket `json:"buckets,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RejectedLine は解析できなかったログ行を表す構造体です。
+type RejectedLine struct {
+	// ログファイルのパス
+	File string `json:"file"`
+	// 行番号 (1 始まり)
+	Line int `json:"line"`
+	// 元の行
+	Raw string `json:"raw"`
+	// 解析のエラー
+	Error string `json:"error"`
+	// 解析に失敗した時刻
+	RejectedAt time.Time `json:"rejected_at"`
+}
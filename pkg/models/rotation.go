@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RotationKind は監視中のファイルで検出した変化の種類です。
+type RotationKind string
+
+const (
+	// Rotated はファイルが別のファイルに置き換えられた (名前の変更後に再作成された) ことを表します。
+	Rotated RotationKind = "rotated"
+	// Truncated はファイルが切り詰められた (copytruncate など) ことを表します。
+	Truncated RotationKind = "truncated"
+)
+
+// RotationEvent は監視中のファイルのローテーションまたは切り詰めを表す構造体です。
+type RotationEvent struct {
+	// ログファイルのパス
+	File string `json:"file"`
+	// 変化の種類
+	Kind RotationKind `json:"kind"`
+	// 以前のファイルから読み込んだバイト数
+	Offset int64 `json:"offset"`
+	// 検出した時刻
+	DetectedAt time.Time `json:"detected_at"`
+}
@@ -16,4 +16,48 @@ type Stats struct {
 	FirstTimestamp time.Time `json:"first_timestamp"`
 	// 最後のログ時刻
 	LastTimestamp time.Time `json:"last_timestamp"`
+	// 解析できずデッドレターに書き込んだ行数
+	Rejected int `json:"rejected,omitempty"`
+	// 絞り込みの条件を満たさず取り除いたログ数
+	Filtered int `json:"filtered,omitempty"`
+	// フィールドごとの異なり数の推定値
+	Distinct map[string]DistinctEstimate `json:"distinct,omitempty"`
+	// 数値フィールドごとの統計量
+	Numeric map[string]NumericReport `json:"numeric,omitempty"`
+	// ルールごとに一致したログ数
+	Rules map[string]int `json:"rules,omitempty"`
+	// 検出器ごとの機密情報の置換数
+	Redactions map[string]int `json:"redactions,omitempty"`
+	// サンプリングした場合の抽出の情報 (設定されている場合、件数は推定値)
+	Sampling *SamplingInfo `json:"sampling,omitempty"`
+	// 監視中に検出したローテーションと切り詰め
+	Rotations []RotationEvent `json:"rotations,omitempty"`
+	// 直近の一定時間のレベル別のログ数、レート、割合 (ウィンドウを設定した場合のみ)
+	Window *WindowStats `json:"window,omitempty"`
+	// 監視の状態 (監視中の統計情報のみ)
+	Health *MonitorHealth `json:"health,omitempty"`
+}
+
+// SamplingInfo は統計情報がサンプリングによる推定値であることを表す構造体です。
+type SamplingInfo struct {
+	// 件数が推定値かどうか
+	Estimated bool `json:"estimated"`
+	// 入力されたログ数
+	Observed int `json:"observed"`
+	// 集約に使用したログ数 (件数の補正前)
+	Kept int `json:"kept"`
+}
+
+// DistinctEstimate はフィールドの異なり数の推定値を表す構造体です。
+type DistinctEstimate struct {
+	// 推定値
+	Estimate uint64 `json:"estimate"`
+	// 相対標準誤差
+	StandardError float64 `json:"standard_error"`
+	// 95%信頼区間の下限
+	Lower uint64 `json:"lower"`
+	// 95%信頼区間の上限
+	Upper uint64 `json:"upper"`
+	// 統合用の HyperLogLog レジスタ
+	Registers []uint8 `json:"-"`
 }
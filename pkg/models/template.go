@@ -0,0 +1,15 @@
+package models
+
+// LogTemplate はメッセージのクラスタリングによって得られたログテンプレートを表す構造体です。
+type LogTemplate struct {
+	// テンプレートの識別子
+	ID int `json:"id"`
+	// ワイルドカード (<*>) を含むテンプレート文字列
+	Template string `json:"template"`
+	// テンプレートに一致したログ数
+	Count int `json:"count"`
+	// レベル別のログ数
+	LevelCounts map[string]int `json:"level_counts"`
+	// テンプレートに一致したログのサンプル
+	Samples []LogEntry `json:"samples"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Trace は同じリクエストIDやトレースIDを持つログの集まりを表す構造体です。
+type Trace struct {
+	// リクエストIDまたはトレースID
+	ID string `json:"id"`
+	// 最初のタイムスタンプ
+	Start time.Time `json:"start"`
+	// 最後のタイムスタンプ
+	End time.Time `json:"end"`
+	// 最初から最後までの経過時間
+	Duration time.Duration `json:"duration"`
+	// ログ数
+	Count int `json:"count"`
+	// 最も重大なレベル (ERROR > WARN > INFO)
+	WorstLevel string `json:"worst_level"`
+	// レベル別のログ数
+	LevelCounts map[string]int `json:"level_counts"`
+	// ログの発生源一覧
+	Sources []string `json:"sources,omitempty"`
+	// 保持しているログの件数を超えて破棄したログ数
+	Dropped int `json:"dropped,omitempty"`
+	// タイムスタンプ順のログ一覧 (個別に取得した場合のみ)
+	Entries []LogEntry `json:"entries,omitempty"`
+}
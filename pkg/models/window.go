@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// WindowStats はスライディングウィンドウ内の統計情報を表す構造体です。
+type WindowStats struct {
+	// ウィンドウの長さ
+	Window time.Duration `json:"window"`
+	// ウィンドウの開始時刻
+	Start time.Time `json:"start"`
+	// ウィンドウの終了時刻
+	End time.Time `json:"end"`
+	// ウィンドウ内の総ログ数
+	TotalCount int `json:"total_count"`
+	// ウィンドウ内のレベル別ログ数
+	LevelCounts map[string]int `json:"level_counts"`
+	// レベル別の1分あたりのログ数
+	RatesPerMinute map[string]float64 `json:"rates_per_minute"`
+	// レベル別の総ログ数に対する割合 (0.0〜1.0)
+	Ratios map[string]float64 `json:"ratios"`
+}